@@ -0,0 +1,8 @@
+// Package asyncio provides files with Read and Write timeouts.
+package asyncio
+
+import "errors"
+
+// ErrTimeout is returned by Read and Write when the
+// timeout expires or the deadline is reached.
+var ErrTimeout = errors.New("asyncio timeout")
@@ -0,0 +1,142 @@
+package asyncio
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+// File represents an open file descriptor that supports timeouts.
+//
+// Read and Write operations uses the timeout and deadlines.
+//
+// Many functions, such as Fd, Stat, Close are supported
+// directly through os.File.
+type File struct {
+	*os.File
+
+	timeout time.Duration
+
+	// deadlines set by the user
+	mu       sync.Mutex
+	rdl, wdl time.Time
+}
+
+func Open(name string) (*File, error) {
+	f, err := os.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &File{File: f}, nil
+}
+
+// SetTimeout sets the timeout for Read and Write operations.
+func (f *File) SetTimeout(x time.Duration) {
+	f.timeout = x
+}
+
+// SetDeadline sets the read and write deadlines.
+func (f *File) SetDeadline(t time.Time) error {
+	if err := f.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return f.SetWriteDeadline(t)
+}
+
+// SetReadDeadline sets the deadline for pending and future Read calls.
+// A zero value for t means Read will not time out,
+// except because of the timeout set with SetTimeout.
+func (f *File) SetReadDeadline(t time.Time) error {
+	f.mu.Lock()
+	f.rdl = t
+	f.mu.Unlock()
+	return f.File.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for pending and future Write calls.
+// A zero value for t means Write will not time out,
+// except because of the timeout set with SetTimeout.
+func (f *File) SetWriteDeadline(t time.Time) error {
+	f.mu.Lock()
+	f.wdl = t
+	f.mu.Unlock()
+	return f.File.SetWriteDeadline(t)
+}
+
+func (f *File) Read(p []byte) (n int, err error) {
+	return f.ReadContext(context.Background(), p)
+}
+
+// ReadContext reads from f like Read. If ctx is done
+// before the read completes, the read is cancelled and ctx.Err() is returned.
+func (f *File) ReadContext(ctx context.Context, p []byte) (n int, err error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	f.mu.Lock()
+	t := f.deadline(f.rdl)
+	f.mu.Unlock()
+	if err := f.File.SetReadDeadline(t); err != nil {
+		return 0, err
+	}
+	stop := context.AfterFunc(ctx, func() {
+		f.File.SetReadDeadline(aLongTimeAgo)
+	})
+	n, err = f.File.Read(p)
+	stop()
+	return n, f.ioErr(ctx, err)
+}
+
+func (f *File) Write(p []byte) (n int, err error) {
+	return f.WriteContext(context.Background(), p)
+}
+
+// WriteContext writes to f like Write. If ctx is done
+// before the write completes, the write is cancelled and ctx.Err() is returned.
+func (f *File) WriteContext(ctx context.Context, p []byte) (n int, err error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	f.mu.Lock()
+	t := f.deadline(f.wdl)
+	f.mu.Unlock()
+	if err := f.File.SetWriteDeadline(t); err != nil {
+		return 0, err
+	}
+	stop := context.AfterFunc(ctx, func() {
+		f.File.SetWriteDeadline(aLongTimeAgo)
+	})
+	n, err = f.File.Write(p)
+	stop()
+	return n, f.ioErr(ctx, err)
+}
+
+// aLongTimeAgo is a deadline in the past used for cancellation.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// deadline returns the earlier of the user deadline t
+// and the deadline from the timeout.
+func (f *File) deadline(t time.Time) time.Time {
+	if f.timeout == 0 {
+		return t
+	}
+	x := time.Now().Add(f.timeout)
+	if t.IsZero() || x.Before(t) {
+		return x
+	}
+	return t
+}
+
+func (f *File) ioErr(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if os.IsTimeout(err) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return ErrTimeout
+	}
+	return err
+}
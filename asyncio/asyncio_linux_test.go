@@ -0,0 +1,58 @@
+package asyncio
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func pipe(t *testing.T) (*File, *os.File) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		r.Close()
+		w.Close()
+	})
+	return &File{File: r}, w
+}
+
+func TestReadDeadline(t *testing.T) {
+	f, w := pipe(t)
+
+	f.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	buf := make([]byte, 4)
+	if _, err := f.Read(buf); err != ErrTimeout {
+		t.Fatalf("got %v, want ErrTimeout", err)
+	}
+
+	f.SetReadDeadline(time.Time{})
+	w.Write([]byte("ds4"))
+	if n, err := f.Read(buf); err != nil || string(buf[:n]) != "ds4" {
+		t.Fatalf("got %q, %v", buf[:n], err)
+	}
+
+	f.SetTimeout(10 * time.Millisecond)
+	if _, err := f.Read(buf); err != ErrTimeout {
+		t.Fatalf("got %v, want ErrTimeout", err)
+	}
+}
+
+func TestReadContext(t *testing.T) {
+	f, _ := pipe(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	buf := make([]byte, 4)
+	if _, err := f.ReadContext(ctx, buf); err != context.Canceled {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := f.ReadContext(ctx, buf); err != context.DeadlineExceeded {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -1,18 +1,16 @@
 package asyncio
 
 import (
-	"errors"
+	"context"
 	"os"
 	"sync"
 	"syscall"
 	"time"
 )
 
-var ErrTimeout = errors.New("asyncio timeout")
-
 // File represents an open file descriptor that supports timeouts.
 //
-// Read and Write operations uses the timeout.
+// Read and Write operations uses the timeout and deadlines.
 //
 // Many functions, such as Fd, Stat, Close are supported
 // directly through os.File.
@@ -23,11 +21,13 @@ type File struct {
 
 	timeout uint32 // milliseconds
 
-	rl sync.Mutex
-	ro *syscall.Overlapped
+	rl  sync.Mutex
+	ro  *syscall.Overlapped
+	rdl deadline
 
-	wl sync.Mutex
-	wo *syscall.Overlapped
+	wl  sync.Mutex
+	wo  *syscall.Overlapped
+	wdl deadline
 }
 
 func Open(name string) (*File, error) {
@@ -73,11 +73,43 @@ func (f *File) SetTimeout(x time.Duration) {
 	f.timeout = t
 }
 
+// SetDeadline sets the read and write deadlines.
+func (f *File) SetDeadline(t time.Time) error {
+	f.rdl.set(t)
+	f.wdl.set(t)
+	return nil
+}
+
+// SetReadDeadline sets the deadline for pending and future Read calls.
+// A zero value for t means Read will not time out,
+// except because of the timeout set with SetTimeout.
+func (f *File) SetReadDeadline(t time.Time) error {
+	f.rdl.set(t)
+	return nil
+}
+
+// SetWriteDeadline sets the deadline for pending and future Write calls.
+// A zero value for t means Write will not time out,
+// except because of the timeout set with SetTimeout.
+func (f *File) SetWriteDeadline(t time.Time) error {
+	f.wdl.set(t)
+	return nil
+}
+
 func (f *File) Read(p []byte) (n int, err error) {
+	return f.ReadContext(context.Background(), p)
+}
+
+// ReadContext reads from f like Read. If ctx is done
+// before the read completes, the read is cancelled and ctx.Err() is returned.
+func (f *File) ReadContext(ctx context.Context, p []byte) (n int, err error) {
 	// https://support.microsoft.com/hu-hu/kb/156932
 	f.rl.Lock()
 	defer f.rl.Unlock()
 
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
 	if err := resetEvent(f.ro.HEvent); err != nil {
 		return 0, err
 	}
@@ -91,13 +123,22 @@ func (f *File) Read(p []byte) (n int, err error) {
 		return int(nread), err
 	}
 	// i/o pending
-	return f.overlappedResult(f.ro)
+	return f.overlappedResult(ctx, f.ro, &f.rdl)
 }
 
 func (f *File) Write(p []byte) (n int, err error) {
+	return f.WriteContext(context.Background(), p)
+}
+
+// WriteContext writes to f like Write. If ctx is done
+// before the write completes, the write is cancelled and ctx.Err() is returned.
+func (f *File) WriteContext(ctx context.Context, p []byte) (n int, err error) {
 	f.wl.Lock()
 	defer f.wl.Unlock()
 
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
 	if err := resetEvent(f.wo.HEvent); err != nil {
 		return 0, err
 	}
@@ -111,28 +152,47 @@ func (f *File) Write(p []byte) (n int, err error) {
 		return int(nwritten), err
 	}
 	// i/o pending
-	return f.overlappedResult(f.wo)
+	return f.overlappedResult(ctx, f.wo, &f.wdl)
 }
 
-func (f *File) overlappedResult(o *syscall.Overlapped) (n int, err error) {
+func (f *File) overlappedResult(ctx context.Context, o *syscall.Overlapped, dl *deadline) (n int, err error) {
 	// https://blogs.msdn.microsoft.com/oldnewthing/20110202-00/?p=11613/
-	var done uint32
-	if f.timeout != 0 {
+	cancel := func() { syscall.CancelIoEx(f.handle, o) }
+
+	timedOut := !dl.start(cancel)
+	if timedOut {
+		cancel()
+	}
+	stop := context.AfterFunc(ctx, cancel)
+
+	if !timedOut && f.timeout != 0 {
 		evt, err := syscall.WaitForSingleObject(o.HEvent, f.timeout)
 		if err != nil {
+			stop()
+			dl.done()
 			return 0, err
 		}
 		if evt == syscall.WAIT_TIMEOUT {
-			if err := syscall.CancelIo(f.handle); err != nil {
-				// fatal?
-				return 0, err
-			}
-			syscall.WaitForSingleObject(o.HEvent, syscall.INFINITE)
-			return 0, ErrTimeout
+			timedOut = true
+			cancel()
 		}
 	}
 
-	if err := getOverlappedResult(f.handle, o, &done, true); err != nil {
+	var done uint32
+	err = getOverlappedResult(f.handle, o, &done, true)
+	stop()
+	if dl.done() {
+		timedOut = true
+	}
+	if err == syscall.ERROR_OPERATION_ABORTED {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		if timedOut {
+			return 0, ErrTimeout
+		}
+	}
+	if err != nil {
 		return 0, err
 	}
 	return int(done), nil
@@ -0,0 +1,77 @@
+package asyncio
+
+import (
+	"sync"
+	"time"
+)
+
+// deadline holds a read or write deadline, and cancels
+// the pending operation when the deadline is reached.
+// It is used on platforms without deadline support in os.File.
+type deadline struct {
+	mu sync.Mutex
+	t  time.Time
+
+	timer *time.Timer
+
+	// pending operation
+	gen     int
+	cancel  func()
+	expired bool
+}
+
+// set sets the deadline to t. The zero value means no deadline.
+// It affects the pending operation, if any.
+func (d *deadline) set(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.t = t
+	d.arm()
+}
+
+// start registers cancel for the operation about to start.
+// It reports false if the deadline has already passed.
+func (d *deadline) start(cancel func()) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.t.IsZero() && !time.Now().Before(d.t) {
+		return false
+	}
+	d.gen++
+	d.cancel, d.expired = cancel, false
+	d.arm()
+	return true
+}
+
+// done unregisters the operation registered with start,
+// and reports if the operation was cancelled because of the deadline.
+func (d *deadline) done() (expired bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	d.cancel = nil
+	return d.expired
+}
+
+// arm starts the timer for the pending operation. d.mu must be held.
+func (d *deadline) arm() {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	if d.cancel == nil || d.t.IsZero() {
+		return
+	}
+	gen := d.gen
+	d.timer = time.AfterFunc(time.Until(d.t), func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if d.gen == gen && d.cancel != nil {
+			d.expired = true
+			d.cancel()
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package asyncio
+
+import (
+	"testing"
+	"time"
+)
+
+// wait waits for c, and reports whether it was signalled within d.
+func wait(c <-chan struct{}, d time.Duration) bool {
+	select {
+	case <-c:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
+
+func TestDeadlinePassed(t *testing.T) {
+	var d deadline
+	d.set(time.Now().Add(-time.Second))
+	if d.start(func() { t.Error("cancel called") }) {
+		t.Fatal("start succeeded after the deadline")
+	}
+
+	d.set(time.Time{})
+	if !d.start(func() { t.Error("cancel called") }) {
+		t.Fatal("start failed without deadline")
+	}
+	if d.done() {
+		t.Error("expired without deadline")
+	}
+}
+
+func TestDeadlineExpires(t *testing.T) {
+	var d deadline
+	cancelled := make(chan struct{})
+	if !d.start(func() { close(cancelled) }) {
+		t.Fatal("start failed")
+	}
+
+	// setting the deadline affects the pending operation
+	d.set(time.Now().Add(10 * time.Millisecond))
+	if !wait(cancelled, 5*time.Second) {
+		t.Fatal("operation not cancelled")
+	}
+	if !d.done() {
+		t.Error("done did not report expiry")
+	}
+}
+
+func TestDeadlineDone(t *testing.T) {
+	var d deadline
+	d.set(time.Now().Add(20 * time.Millisecond))
+	cancelled := make(chan struct{})
+	if !d.start(func() { close(cancelled) }) {
+		t.Fatal("start failed")
+	}
+	if d.done() {
+		t.Error("expired early")
+	}
+	if wait(cancelled, 50*time.Millisecond) {
+		t.Error("cancel called after done")
+	}
+}
+
+func TestDeadlineCleared(t *testing.T) {
+	var d deadline
+	d.set(time.Now().Add(20 * time.Millisecond))
+	cancelled := make(chan struct{})
+	if !d.start(func() { close(cancelled) }) {
+		t.Fatal("start failed")
+	}
+	d.set(time.Time{})
+	if wait(cancelled, 50*time.Millisecond) {
+		t.Error("cancel called after the deadline was cleared")
+	}
+	if d.done() {
+		t.Error("expired after the deadline was cleared")
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tajtiattila/hid/ds4"
+	"github.com/tajtiattila/hid/ds4/ds4util"
+)
+
+var errCalibrationAborted = errors.New("calibration aborted")
+
+// defaultRegistry returns the default registry path.
+func defaultRegistry() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "ds4", "registry.json")
+}
+
+// Calibrate runs the guided calibration of d using states from c,
+// and stores the result in reg for the serial number sno,
+// creating the directory of the registry file if needed.
+// Calibration is aborted when stop is closed.
+func Calibrate(d *ds4.Device, c <-chan ds4.State, stop <-chan struct{}, reg *ds4util.Registry, sno string) error {
+	old := d.Calibration()
+	d.SetCalibration(ds4.Calibration{})
+
+	cb := ds4util.NewCalibrator()
+
+	fmt.Println("Put the controller on a flat surface, and don't touch it.")
+	for !cb.Done() {
+		s, err := recvState(c, stop)
+		if err != nil {
+			d.SetCalibration(old)
+			return err
+		}
+		rest := cb.Add(&s)
+		msg := "moving"
+		if rest {
+			msg = "at rest"
+		}
+		fmt.Printf("\r[%-20s] %s", strings.Repeat("#", int(cb.Progress()*20)), msg)
+		os.Stdout.Write([]byte{27, '[', 'K'})
+	}
+	fmt.Println()
+
+	fmt.Println("Move both sticks around their full range a few times, then press Cross.")
+	var pressed bool
+	for {
+		s, err := recvState(c, stop)
+		if err != nil {
+			d.SetCalibration(old)
+			return err
+		}
+		cb.Add(&s)
+		cross := s.Button&ds4.Cross != 0
+		if pressed && !cross {
+			break
+		}
+		pressed = cross
+	}
+
+	cal := cb.Calibration()
+	fmt.Printf("Stick offsets: L(%+d %+d) R(%+d %+d)\n", cal.LX, cal.LY, cal.RX, cal.RY)
+	fmt.Printf("Stick ranges: min %v max %v\n", cal.Min, cal.Max)
+	fmt.Printf("Gyro bias: %+d %+d %+d\n", cal.XGyro, cal.YGyro, cal.ZGyro)
+	d.SetCalibration(cal)
+
+	if reg == nil {
+		return nil
+	}
+	st, _ := reg.Lookup(sno)
+	st.Calibration = cal
+	reg.Set(sno, st)
+	if err := os.MkdirAll(filepath.Dir(registry), 0777); err != nil {
+		return err
+	}
+	return reg.Save()
+}
+
+func recvState(c <-chan ds4.State, stop <-chan struct{}) (ds4.State, error) {
+	select {
+	case s, ok := <-c:
+		if !ok {
+			return s, errors.New("controller disconnected")
+		}
+		return s, nil
+	case <-stop:
+		return ds4.State{}, errCalibrationAborted
+	}
+}
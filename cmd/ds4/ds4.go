@@ -5,7 +5,6 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"math"
 	"os"
 	"time"
 
@@ -19,8 +18,19 @@ var (
 	touch    bool
 	verbose  bool
 
+	calibrate bool
+	registry  string
+
 	alpha  float64
 	movavg time.Duration
+
+	euroMinCutoff float64
+	euroBeta      float64
+	kalmanQ       float64
+	kalmanR       float64
+
+	gyroSpace string
+	gyroSens  float64
 )
 
 func main() {
@@ -29,6 +39,14 @@ func main() {
 	flag.BoolVar(&verbose, "v", false, "Verbose output")
 	flag.Float64Var(&alpha, "alpha", 1, "Gyro low pass filter alpha")
 	flag.DurationVar(&movavg, "movavg", 0, "Moving average duration")
+	flag.Float64Var(&euroMinCutoff, "euro-mincutoff", 0, "Gyro 1€ filter minimum cutoff frequency in Hz, 0 disables")
+	flag.Float64Var(&euroBeta, "euro-beta", 0.01, "Gyro 1€ filter speed coefficient")
+	flag.Float64Var(&kalmanQ, "kalman-q", 1e6, "Gyro Kalman filter process noise")
+	flag.Float64Var(&kalmanR, "kalman-r", 0, "Gyro Kalman filter measurement noise, 0 disables")
+	flag.StringVar(&gyroSpace, "gyro-space", "player", "Gyro aim space: local, world or player")
+	flag.Float64Var(&gyroSens, "gyro-sens", 1, "Gyro aim sensitivity")
+	flag.BoolVar(&calibrate, "calibrate", false, "Calibrate sticks and gyro")
+	flag.StringVar(&registry, "registry", defaultRegistry(), "Controller registry file")
 	flag.Parse()
 
 	var dlist []*hid.DeviceInfo
@@ -69,6 +87,22 @@ func main() {
 	}
 	defer d.Close()
 
+	if info, err := d.Info(); err == nil {
+		fmt.Println("firmware:", info)
+	} else {
+		log.Println("firmware info:", err)
+	}
+
+	var reg *ds4util.Registry
+	if registry != "" {
+		reg, err = ds4util.OpenRegistry(registry)
+		if err != nil {
+			log.Println("registry:", err)
+		} else if st, ok := reg.Lookup(di.Attr.SerialNo); ok {
+			d.SetCalibration(st.Calibration)
+		}
+	}
+
 	d.SetColor(ds4.Color{0xff, 0x88, 0x00})
 	//d.SetFlashColor(ds4.Color{255, 0, 0}, time.Second, time.Second)
 
@@ -84,11 +118,7 @@ func main() {
 
 	var s ds4.State
 	if err := s.Decode(ibuf); err == nil {
-		var charging string
-		if s.Battery&0xf0 != 0 {
-			charging = " (charging)"
-		}
-		fmt.Printf("Battery: %v%%%s\n", 10*(s.Battery&0xf), charging)
+		fmt.Printf("Battery: %v\n", s.Battery)
 	}
 
 	ch := make(chan struct{})
@@ -98,77 +128,85 @@ func main() {
 		close(ch)
 	}()
 
-	var f func(p []byte, s *ds4.State)
+	var f func(s *ds4.State)
 	if touch {
 		tt := NewTouchTest()
-		f = func(p []byte, s *ds4.State) {
-			tt.Run(s)
-		}
+		f = tt.Run
 	} else {
 		f = InputTest
+		aim = ds4util.NewGyroAim()
+		aim.Sensitivity = gyroSens
+		aim.Ratchet = ds4.L1
+		switch gyroSpace {
+		case "local":
+			aim.Space = ds4util.LocalSpace
+		case "world":
+			aim.Space = ds4util.WorldSpace
+		case "player":
+			aim.Space = ds4util.PlayerSpace
+		default:
+			log.Println("invalid gyro space:", gyroSpace)
+			return
+		}
+		fmt.Println("Hold L1 to ratchet gyro aim")
+		var v []ds4util.VecFilter
 		if alpha != 1 {
-			filter = ds4util.NewAlphaFilter(3, alpha)
+			v = append(v, &ds4util.LowPass{Alpha: alpha})
 		}
 		if movavg != 0 {
-			n := int(movavg/time.Millisecond) * 3
-			a := ds4util.NewMovAvg(3, n, movavg)
-			if filter != ds4util.Input {
-				filter = ds4util.Combine(filter, a)
-			} else {
-				filter = a
-			}
+			v = append(v, &ds4util.MovingAverage{D: movavg})
+		}
+		if euroMinCutoff > 0 {
+			v = append(v, &ds4util.OneEuro{MinCutoff: euroMinCutoff, Beta: euroBeta})
+		}
+		if kalmanR > 0 {
+			v = append(v, &ds4util.Kalman{ProcessNoise: kalmanQ, MeasurementNoise: kalmanR})
+		}
+		if len(v) != 0 {
+			aim.Smoothing = ds4util.CombineVec(v...)
 		}
 	}
 
+	sub := d.Subscribe(16, ds4.Block)
+	defer sub.Close()
+
+	if calibrate {
+		if err := Calibrate(d, sub.C, ch, reg, di.Attr.SerialNo); err != nil {
+			log.Println("calibration:", err)
+		}
+		return
+	}
+
 	for {
 		select {
 		case <-ch:
 			fmt.Println()
 			err := d.DisconnectRadio()
-			if err != nil {
+			if err != nil && err != hid.ErrNotBluetooth {
 				log.Println(err)
 			}
 			return
-		default:
-		}
-
-		_, err := d.Read(ibuf)
-		if err != nil {
-			log.Println(err)
-			return
-		}
-
-		if err := s.Decode(ibuf); err != nil {
-			continue
+		case s, ok := <-sub.C:
+			if !ok {
+				log.Println(sub.Err())
+				return
+			}
+			f(&s)
 		}
-
-		f(ibuf, &s)
 	}
 }
 
-var filter ds4util.Filter = ds4util.Input
+var (
+	aim        *ds4util.GyroAim
+	aimX, aimY float64
+)
 
-func InputTest(ibuf []byte, s *ds4.State) {
+func InputTest(s *ds4.State) {
 	fmt.Print("\r")
-	const m = 100000
-	v := filter.Filter([]int{
-		int(s.XGyro) * m,
-		int(s.YGyro) * m,
-		int(s.ZGyro) * m,
-	})
-	x, y, z := float64(v[0]), float64(v[1]), float64(v[2])
-	r, p := ds4.GyroRollPitch(x, y, z)
-	mag := math.Sqrt(x*x + y*y + z*z)
-	fmt.Printf("%5.2f %5.2f %5.2f %4.0f %4.0f", x/mag, y/mag, z/mag, r, p)
-	/*
-
-		gr, gp, ok := s.GyroRollPitch()
-		ls := "ok  "
-		if !ok {
-			ls = "lock"
-		}
-		fmt.Printf(" GX(%5.1f %5.1f %s)", gr, gp, ls)
-	*/
+	dx, dy := aim.Update(s)
+	aimX += dx
+	aimY += dy
+	fmt.Printf("%+7.2f %+7.2f aim(%+9.1f %+9.1f)", dx, dy, aimX, aimY)
 
 	//dumpbytes(ibuf, 40)
 
@@ -0,0 +1,94 @@
+package ds4
+
+import (
+	"strconv"
+	"time"
+)
+
+// Battery is the battery state byte of an input report.
+//
+// Bits 0-3 hold the battery level, and bit 4 is set when a cable is
+// connected. The level ranges from 0 to 10 without a cable.
+// With a cable it ranges from 0 to 11, where 11 means fully charged,
+// and higher values indicate that the battery is not charging.
+type Battery byte
+
+const (
+	batteryLevelMask = 0x0f
+	batteryCable     = 0x10
+
+	batteryFull = 11
+)
+
+// Cable reports if a USB cable is connected.
+func (b Battery) Cable() bool { return b&batteryCable != 0 }
+
+// Level returns the raw battery level.
+func (b Battery) Level() int { return int(b & batteryLevelMask) }
+
+// Charging reports if the battery is being charged.
+func (b Battery) Charging() bool { return b.Cable() && b.Level() < batteryFull }
+
+// Full reports if the battery is fully charged.
+func (b Battery) Full() bool { return b.Cable() && b.Level() == batteryFull }
+
+// Percent returns the charge percentage of the battery,
+// using the same mapping with and without a cable as hid-sony.
+func (b Battery) Percent() int {
+	l := b.Level()
+	if l >= 10 {
+		return 100
+	}
+	return l*10 + 5
+}
+
+// String returns the charge percentage,
+// followed by '+' if the battery is charging.
+func (b Battery) String() string {
+	switch {
+	case b.Full():
+		return "full"
+	case b.Charging():
+		return strconv.Itoa(b.Percent()) + "%+"
+	}
+	return strconv.Itoa(b.Percent()) + "%"
+}
+
+// BatteryFilter smooths battery readings that flicker between levels.
+//
+// A new reading is accepted once it has been read continuously for Hold.
+// Cable changes are accepted immediately.
+type BatteryFilter struct {
+	Hold time.Duration
+
+	init  bool
+	cur   Battery
+	next  Battery
+	since time.Time
+}
+
+// Update adds the reading b at time t. It returns the filtered
+// battery state, and whether it has changed.
+// The first reading is accepted as is, but reported unchanged.
+func (f *BatteryFilter) Update(b Battery, t time.Time) (Battery, bool) {
+	switch {
+	case !f.init:
+		f.init = true
+		f.cur, f.next = b, b
+		return b, false
+	case b == f.cur:
+		f.next = b
+		return b, false
+	case b.Cable() != f.cur.Cable():
+		f.cur, f.next = b, b
+		return b, true
+	}
+	if b != f.next {
+		f.next, f.since = b, t
+	}
+	if t.Sub(f.since) >= f.Hold {
+		f.cur = b
+		return b, true
+	}
+	return f.cur, false
+}
@@ -0,0 +1,62 @@
+package ds4
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBattery(t *testing.T) {
+	tests := []struct {
+		b        Battery
+		pct      int
+		charging bool
+		full     bool
+		str      string
+	}{
+		{0x00, 5, false, false, "5%"},
+		{0x05, 55, false, false, "55%"},
+		{0x09, 95, false, false, "95%"},
+		{0x0a, 100, false, false, "100%"},
+		{0x0f, 100, false, false, "100%"},
+		{0x10, 5, true, false, "5%+"},
+		{0x15, 55, true, false, "55%+"},
+		{0x1a, 100, true, false, "100%+"},
+		{0x1b, 100, false, true, "full"},
+		{0x1e, 100, false, false, "100%"},
+	}
+	for _, tt := range tests {
+		b := tt.b
+		if b.Percent() != tt.pct || b.Charging() != tt.charging || b.Full() != tt.full || b.String() != tt.str {
+			t.Errorf("%#02x: got %d%% charging=%v full=%v %q",
+				byte(b), b.Percent(), b.Charging(), b.Full(), b.String())
+		}
+	}
+}
+
+func TestBatteryFilter(t *testing.T) {
+	f := BatteryFilter{Hold: time.Second}
+	t0 := time.Unix(1000, 0)
+	at := func(ms int) time.Time { return t0.Add(time.Duration(ms) * time.Millisecond) }
+
+	steps := []struct {
+		ms      int
+		in, out Battery
+		changed bool
+	}{
+		{0, 0x05, 0x05, false},
+		{100, 0x04, 0x05, false}, // flicker
+		{200, 0x05, 0x05, false},
+		{300, 0x04, 0x05, false},
+		{1200, 0x04, 0x05, false},
+		{1300, 0x04, 0x04, true}, // stable for Hold
+		{1400, 0x14, 0x14, true}, // cable change
+		{1500, 0x04, 0x04, true},
+	}
+	for _, s := range steps {
+		b, changed := f.Update(s.in, at(s.ms))
+		if b != s.out || changed != s.changed {
+			t.Errorf("%dms: Update(%#02x) = %#02x, %v; want %#02x, %v",
+				s.ms, byte(s.in), byte(b), changed, byte(s.out), s.changed)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package ds4
+
+import "encoding/json"
+
+// Calibration holds corrections applied to decoded input states.
+type Calibration struct {
+	// stick centre offsets
+	LX int `json:"lx,omitempty"`
+	LY int `json:"ly,omitempty"`
+	RX int `json:"rx,omitempty"`
+	RY int `json:"ry,omitempty"`
+
+	// Min and Max are the raw stick values at full deflection
+	// in the order LX, LY, RX, RY. Axes with zero Max
+	// are not scaled, only offset.
+	// They are omitted from JSON when all values are zero.
+	Min [4]byte `json:"min"`
+	Max [4]byte `json:"max"`
+
+	// gyroscope bias
+	XGyro int `json:"xgyro,omitempty"`
+	YGyro int `json:"ygyro,omitempty"`
+	ZGyro int `json:"zgyro,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (c Calibration) MarshalJSON() ([]byte, error) {
+	type calibration Calibration
+	v := struct {
+		calibration
+		Min *[4]byte `json:"min,omitempty"`
+		Max *[4]byte `json:"max,omitempty"`
+	}{calibration: calibration(c)}
+	if c.Min != ([4]byte{}) {
+		v.Min = &c.Min
+	}
+	if c.Max != ([4]byte{}) {
+		v.Max = &c.Max
+	}
+	return json.Marshal(v)
+}
+
+// SetCalibration sets the calibration applied to states read from d.
+func (d *Device) SetCalibration(c Calibration) {
+	d.stmu.Lock()
+	defer d.stmu.Unlock()
+	d.cal = c
+}
+
+// Calibration returns the calibration applied to states read from d.
+func (d *Device) Calibration() Calibration {
+	d.stmu.Lock()
+	defer d.stmu.Unlock()
+	return d.cal
+}
+
+// apply applies the corrections in c to s.
+func (c *Calibration) apply(s *State) {
+	if *c == (Calibration{}) {
+		return
+	}
+	s.LX = c.axis(0, s.LX, c.LX)
+	s.LY = c.axis(1, s.LY, c.LY)
+	s.RX = c.axis(2, s.RX, c.RX)
+	s.RY = c.axis(3, s.RY, c.RY)
+	s.XGyro = subInt16(s.XGyro, c.XGyro)
+	s.YGyro = subInt16(s.YGyro, c.YGyro)
+	s.ZGyro = subInt16(s.ZGyro, c.ZGyro)
+}
+
+// axis returns the corrected value of the raw stick axis i
+// with centre offset d.
+func (c *Calibration) axis(i int, v byte, d int) byte {
+	lo, hi := int(c.Min[i]), int(c.Max[i])
+	ctr := 128 + d
+	if hi == 0 || lo >= ctr || hi <= ctr {
+		return subByte(v, d)
+	}
+	x := int(v)
+	if x >= ctr {
+		return clampByte(128 + (x-ctr)*127/(hi-ctr))
+	}
+	return clampByte(128 - (ctr-x)*128/(ctr-lo))
+}
+
+func subByte(v byte, d int) byte {
+	return clampByte(int(v) - d)
+}
+
+func clampByte(x int) byte {
+	switch {
+	case x < 0:
+		return 0
+	case x > 255:
+		return 255
+	}
+	return byte(x)
+}
+
+func subInt16(v int16, d int) int16 {
+	x := int(v) - d
+	switch {
+	case x < -32768:
+		return -32768
+	case x > 32767:
+		return 32767
+	}
+	return int16(x)
+}
@@ -0,0 +1,66 @@
+package ds4
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCalibrationApply(t *testing.T) {
+	c := Calibration{LX: 5, LY: -5, RX: 200, XGyro: 100, YGyro: -40000}
+	s := State{LX: 130, LY: 254, RX: 100, RY: 128, XGyro: 50, YGyro: 1}
+	c.apply(&s)
+	want := State{LX: 125, LY: 255, RX: 0, RY: 128, XGyro: -50, YGyro: 32767}
+	if s != want {
+		t.Errorf("got %+v, want %+v", s, want)
+	}
+}
+
+func TestColorText(t *testing.T) {
+	c := Color{R: 0x12, G: 0xab, B: 0x00}
+	p, _ := c.MarshalText()
+	if string(p) != "#12ab00" {
+		t.Fatalf("got %s", p)
+	}
+	var x Color
+	if err := x.UnmarshalText(p); err != nil || x != c {
+		t.Fatalf("got %v, %v", x, err)
+	}
+	for _, s := range []string{"12ab00", "#12ab0", "#12ab000", "#12ag00"} {
+		if err := x.UnmarshalText([]byte(s)); err == nil {
+			t.Errorf("%q accepted", s)
+		}
+	}
+}
+
+func TestCalibrationRange(t *testing.T) {
+	c := Calibration{LX: 4, Min: [4]byte{20}, Max: [4]byte{230}}
+	for _, tt := range []struct{ in, out byte }{
+		{132, 128}, {230, 255}, {255, 255}, {20, 0}, {76, 64}, {181, 191},
+	} {
+		s := State{LX: tt.in}
+		c.apply(&s)
+		if s.LX != tt.out {
+			t.Errorf("%d: got %d, want %d", tt.in, s.LX, tt.out)
+		}
+	}
+}
+
+func TestCalibrationJSON(t *testing.T) {
+	for _, tt := range []struct {
+		c    Calibration
+		want string
+	}{
+		{Calibration{}, `{}`},
+		{Calibration{LX: 2, XGyro: -3}, `{"lx":2,"xgyro":-3}`},
+		{Calibration{Min: [4]byte{10}, Max: [4]byte{250}}, `{"min":[10,0,0,0],"max":[250,0,0,0]}`},
+	} {
+		p, err := json.Marshal(tt.c)
+		if err != nil || string(p) != tt.want {
+			t.Errorf("got %s, %v, want %s", p, err, tt.want)
+		}
+		var c Calibration
+		if err := json.Unmarshal(p, &c); err != nil || c != tt.c {
+			t.Errorf("%s decoded to %+v, %v", p, c, err)
+		}
+	}
+}
@@ -1,19 +1,66 @@
 package ds4
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
 	"time"
 
 	"github.com/tajtiattila/hid"
 )
 
+// Conn is the connection to the HID device used by Device.
+// It is implemented by *hid.Device, and can be replaced
+// by other implementations using NewDevice, for example in tests.
+type Conn interface {
+	io.ReadWriteCloser
+
+	Name() string
+	DeviceInfo() (*hid.DeviceInfo, error)
+
+	ReadContext(ctx context.Context, p []byte) (int, error)
+	WriteContext(ctx context.Context, p []byte) (int, error)
+	SetTimeout(time.Duration)
+	SetDeadline(t time.Time) error
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+
+	SetOutputReport(p []byte) error
+	GetFeatureReport(p []byte) error
+	SetFeatureReport(p []byte) error
+
+	DisconnectRadio() error
+}
+
 type Device struct {
-	*hid.Device
+	// HID is the HID device of d.
+	// It is nil if d was created by NewDevice
+	// with a connection other than *hid.Device.
+	HID *hid.Device
+
+	conn Conn
 
 	// bluetooth
 	bt bool
 
 	ibuf []byte
+
+	// protects input statistics and calibration
+	stmu sync.Mutex
+	st   stats
+	cal  Calibration
+
+	// protects obuf and out
+	omu  sync.Mutex
 	obuf []byte
+	out  Output
+
+	// subscriptions
+	submu     sync.Mutex
+	subs      []*Subscription
+	subcancel context.CancelFunc
+	subdone   chan struct{}
 }
 
 type Error struct {
@@ -23,7 +70,14 @@ type Error struct {
 	Err error
 }
 
-func (e *Error) Error() string { return e.Msg + ": " + e.Err.Error() }
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Msg
+	}
+	return e.Msg + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error { return e.Err }
 
 const (
 	BT_OUTPUT_REPORT_LENGTH = 78
@@ -35,34 +89,103 @@ func Open(name string) (*Device, error) {
 	if err != nil {
 		return nil, &Error{"ds4.Open", err}
 	}
-	di, err := d.DeviceInfo()
+	return NewDevice(d)
+}
+
+// NewDevice returns a Device using the HID connection c.
+// The connection is closed if NewDevice fails.
+func NewDevice(c Conn) (*Device, error) {
+	di, err := c.DeviceInfo()
 	if err != nil {
-		d.Close()
+		c.Close()
 		return nil, &Error{"ds4.DeviceInfo", err}
 	}
+	hd, _ := c.(*hid.Device)
 	x := &Device{
-		Device: d,
+		HID:  hd,
+		conn: c,
 
 		bt:   di.Caps.InputLen > 64,
 		ibuf: make([]byte, di.Caps.InputLen),
 		obuf: make([]byte, di.Caps.OutputLen),
+		st:   newStats(),
 	}
 	if err = x.SetOutput(&Output{}); err != nil {
-		d.Close()
+		c.Close()
 		return nil, &Error{"ds4.SetOutput", err}
 	}
 	return x, nil
 }
 
+// The methods below forward to the connection of d,
+// so they work with any Conn passed to NewDevice.
+// Methods specific to the HID device are available using HID.
+
+func (d *Device) Read(p []byte) (int, error)  { return d.conn.Read(p) }
+func (d *Device) Write(p []byte) (int, error) { return d.conn.Write(p) }
+func (d *Device) Close() error                { return d.conn.Close() }
+
+func (d *Device) Name() string                         { return d.conn.Name() }
+func (d *Device) DeviceInfo() (*hid.DeviceInfo, error) { return d.conn.DeviceInfo() }
+
+func (d *Device) ReadContext(ctx context.Context, p []byte) (int, error) {
+	return d.conn.ReadContext(ctx, p)
+}
+
+func (d *Device) WriteContext(ctx context.Context, p []byte) (int, error) {
+	return d.conn.WriteContext(ctx, p)
+}
+
+func (d *Device) SetTimeout(x time.Duration)         { d.conn.SetTimeout(x) }
+func (d *Device) SetDeadline(t time.Time) error      { return d.conn.SetDeadline(t) }
+func (d *Device) SetReadDeadline(t time.Time) error  { return d.conn.SetReadDeadline(t) }
+func (d *Device) SetWriteDeadline(t time.Time) error { return d.conn.SetWriteDeadline(t) }
+
+func (d *Device) SetOutputReport(p []byte) error  { return d.conn.SetOutputReport(p) }
+func (d *Device) GetFeatureReport(p []byte) error { return d.conn.GetFeatureReport(p) }
+func (d *Device) SetFeatureReport(p []byte) error { return d.conn.SetFeatureReport(p) }
+func (d *Device) DisconnectRadio() error          { return d.conn.DisconnectRadio() }
+
 // Bluetooth reports whether d uses bluetooth.
 func (d *Device) Bluetooth() bool { return d.bt }
 
+// ReadState reads the next input report into s.
+//
+// Errors can be tested using errors.Is with the sentinel errors
+// of package hid, such as hid.ErrDeviceGone, hid.ErrTimeout,
+// or hid.ErrShortReport when a corrupt report was received.
 func (d *Device) ReadState(s *State) error {
-	_, err := d.Device.Read(d.ibuf)
+	n, err := d.conn.Read(d.ibuf)
 	if err != nil {
 		return err
 	}
-	return s.Decode(d.ibuf)
+	return d.decode(d.ibuf[:n], s)
+}
+
+// ReadStateContext reads the next input report into s like ReadState.
+// If ctx is done before a report arrives, the read is cancelled
+// and the returned error wraps ctx.Err().
+func (d *Device) ReadStateContext(ctx context.Context, s *State) error {
+	n, err := d.conn.ReadContext(ctx, d.ibuf)
+	if err != nil {
+		return err
+	}
+	return d.decode(d.ibuf[:n], s)
+}
+
+// decode decodes the report p just read into s,
+// and updates the statistics of d.
+func (d *Device) decode(p []byte, s *State) error {
+	now := time.Now()
+	err := s.Decode(p)
+	crcok := !d.bt || checkCRC(p)
+	d.stmu.Lock()
+	d.st.record(now, s, err, crcok)
+	if err == nil {
+		d.cal.apply(s)
+	}
+	d.stmu.Unlock()
+	return err
 }
 
 func (d *Device) SetColor(c Color) error {
@@ -73,7 +196,11 @@ func (d *Device) SetFlashColor(c Color, on, off time.Duration) error {
 	return d.SetOutput(&Output{Led: c, On: on, Off: off})
 }
 
+// SetOutput sets the rumble motors and the light bar.
 func (d *Device) SetOutput(o *Output) (err error) {
+	d.omu.Lock()
+	defer d.omu.Unlock()
+	d.out = *o
 	if d.bt {
 		d.obuf[0] = 0x11
 		d.obuf[1] = 0x80
@@ -103,6 +230,13 @@ func (d *Device) SetOutput(o *Output) (err error) {
 	return err
 }
 
+// Output returns the output last set using SetOutput.
+func (d *Device) Output() Output {
+	d.omu.Lock()
+	defer d.omu.Unlock()
+	return d.out
+}
+
 type Output struct {
 	// Rumble motors
 	Light, Heavy byte
@@ -118,6 +252,28 @@ type Color struct {
 	R, G, B byte
 }
 
+// String returns c in the form #rrggbb.
+func (c Color) String() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// MarshalText implements encoding.TextMarshaler using the form #rrggbb.
+func (c Color) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+// It accepts colours in the form #rrggbb.
+func (c *Color) UnmarshalText(p []byte) error {
+	var x Color
+	n, err := fmt.Sscanf(string(p), "#%02x%02x%02x", &x.R, &x.G, &x.B)
+	if err != nil || n != 3 || len(p) != 7 {
+		return fmt.Errorf("ds4: invalid color %q", p)
+	}
+	*c = x
+	return nil
+}
+
 func dur(d time.Duration) byte {
 	if d <= 0 {
 		return 0
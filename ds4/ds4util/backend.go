@@ -0,0 +1,28 @@
+package ds4util
+
+import (
+	"github.com/tajtiattila/hid"
+	"github.com/tajtiattila/hid/ds4"
+)
+
+// Backend finds and opens DS4 controllers for DeviceManager.
+type Backend interface {
+	// Devices lists the available controllers.
+	Devices() ([]*hid.DeviceInfo, error)
+
+	// Open opens the controller di.
+	Open(di *hid.DeviceInfo) (*ds4.Device, error)
+}
+
+// HIDBackend is the Backend using package hid.
+var HIDBackend Backend = hidBackend{}
+
+type hidBackend struct{}
+
+func (hidBackend) Devices() ([]*hid.DeviceInfo, error) {
+	return hid.VendorDevices(0x54C, 0x5C4) // DualShock 4
+}
+
+func (hidBackend) Open(di *hid.DeviceInfo) (*ds4.Device, error) {
+	return ds4.Open(di.Name)
+}
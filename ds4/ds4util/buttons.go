@@ -0,0 +1,32 @@
+package ds4util
+
+import "github.com/tajtiattila/hid/ds4"
+
+// D-pad direction bits used by Buttons
+// in addition to the button bits of ds4.State.
+const (
+	DpadUp    = 1 << 24
+	DpadRight = 1 << 25
+	DpadDown  = 1 << 26
+	DpadLeft  = 1 << 27
+
+	// DpadMask holds all direction bits.
+	DpadMask = DpadUp | DpadRight | DpadDown | DpadLeft
+)
+
+// dpadBits maps the D-pad values of ds4.State to direction bits.
+var dpadBits = [8]uint32{
+	DpadUp, DpadUp | DpadRight, DpadRight, DpadDown | DpadRight,
+	DpadDown, DpadDown | DpadLeft, DpadLeft, DpadUp | DpadLeft,
+}
+
+// Buttons returns the button bits b of ds4.State
+// with the D-pad value replaced by direction bits.
+func Buttons(b uint32) uint32 {
+	d := b & ds4.Dpad
+	b &^= ds4.Dpad
+	if d&ds4.DpadOff == 0 {
+		b |= dpadBits[d&ds4.DpadDir]
+	}
+	return b
+}
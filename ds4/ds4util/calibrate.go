@@ -0,0 +1,216 @@
+package ds4util
+
+import (
+	"math"
+
+	"github.com/tajtiattila/hid/ds4"
+)
+
+// Defaults for rest detection and calibration.
+const (
+	DefaultRestWindow = 100 // states
+	DefaultRestStdDev = 250 // raw accelerometer units
+
+	defaultCalibrationSamples = 250
+	defaultDriftAlpha         = 0.01
+
+	// minimum stick travel from centre for range calibration
+	minStickRange = 64
+)
+
+// restDetector detects when the controller is at rest
+// from the variance of the accelerometer.
+type restDetector struct {
+	acc [][3]float64
+	pos int
+	n   int
+}
+
+// add adds the accelerometer vector of s, and reports whether
+// the standard deviation of the last window vectors is below maxStd.
+func (r *restDetector) add(s *ds4.State, window int, maxStd float64) bool {
+	if window <= 0 {
+		window = DefaultRestWindow
+	}
+	if maxStd <= 0 {
+		maxStd = DefaultRestStdDev
+	}
+	if len(r.acc) != window {
+		r.acc, r.pos, r.n = make([][3]float64, window), 0, 0
+	}
+	r.acc[r.pos] = [3]float64{float64(s.XAcc), float64(s.YAcc), float64(s.ZAcc)}
+	r.pos = (r.pos + 1) % window
+	if r.n < window {
+		r.n++
+		return false
+	}
+
+	var mean [3]float64
+	for _, a := range r.acc {
+		for i := range mean {
+			mean[i] += a[i]
+		}
+	}
+	for i := range mean {
+		mean[i] /= float64(window)
+	}
+	var v float64
+	for _, a := range r.acc {
+		for i := range mean {
+			d := a[i] - mean[i]
+			v += d * d
+		}
+	}
+	return math.Sqrt(v/float64(window)) < maxStd
+}
+
+// Calibrator measures the stick centres and ranges and the gyroscope bias
+// from raw input states.
+//
+// Stick centres and gyroscope bias are measured while the controller rests,
+// detected using the accelerometer. Stick ranges are measured from
+// the extreme positions seen.
+//
+// The states must be read without calibration,
+// see ds4.Device.SetCalibration.
+type Calibrator struct {
+	// Window is the number of states used for rest detection.
+	Window int
+
+	// RestStdDev is the standard deviation of the accelerometer
+	// below which the controller is considered at rest.
+	RestStdDev float64
+
+	// Samples is the number of states at rest needed for calibration.
+	Samples int
+
+	rest restDetector
+
+	nrest int
+	sum   [7]float64 // LX, LY, RX, RY, XGyro, YGyro, ZGyro
+
+	ranged   bool
+	min, max [4]byte
+}
+
+// NewCalibrator returns a new Calibrator using default settings.
+func NewCalibrator() *Calibrator {
+	return &Calibrator{
+		Window:     DefaultRestWindow,
+		RestStdDev: DefaultRestStdDev,
+		Samples:    defaultCalibrationSamples,
+	}
+}
+
+// Add adds the raw state s. It reports whether the controller is at rest.
+func (c *Calibrator) Add(s *ds4.State) bool {
+	axes := [4]byte{s.LX, s.LY, s.RX, s.RY}
+	if !c.ranged {
+		c.ranged = true
+		c.min, c.max = axes, axes
+	}
+	for i, v := range axes {
+		if v < c.min[i] {
+			c.min[i] = v
+		}
+		if v > c.max[i] {
+			c.max[i] = v
+		}
+	}
+
+	if !c.rest.add(s, c.Window, c.RestStdDev) {
+		return false
+	}
+	c.nrest++
+	for i, v := range axes {
+		c.sum[i] += float64(v)
+	}
+	c.sum[4] += float64(s.XGyro)
+	c.sum[5] += float64(s.YGyro)
+	c.sum[6] += float64(s.ZGyro)
+	return true
+}
+
+// Progress returns the fraction of the rest samples collected.
+func (c *Calibrator) Progress() float64 {
+	if c.Samples <= 0 {
+		return 1
+	}
+	return math.Min(float64(c.nrest)/float64(c.Samples), 1)
+}
+
+// Done reports if enough states were collected at rest.
+func (c *Calibrator) Done() bool {
+	return c.nrest > 0 && c.Progress() >= 1
+}
+
+// Calibration returns the measured calibration.
+// Offsets are zero until the controller was at rest, and stick ranges
+// are set only for axes moved far enough in both directions.
+func (c *Calibrator) Calibration() ds4.Calibration {
+	var cal ds4.Calibration
+	if c.nrest == 0 {
+		return cal
+	}
+	var off [7]int
+	for i, s := range c.sum {
+		off[i] = int(math.Round(s / float64(c.nrest)))
+	}
+	cal.LX, cal.LY, cal.RX, cal.RY = off[0]-128, off[1]-128, off[2]-128, off[3]-128
+	cal.XGyro, cal.YGyro, cal.ZGyro = off[4], off[5], off[6]
+
+	for i := range c.min {
+		ctr := off[i]
+		if int(c.max[i])-ctr >= minStickRange && ctr-int(c.min[i]) >= minStickRange {
+			cal.Min[i], cal.Max[i] = c.min[i], c.max[i]
+		}
+	}
+	return cal
+}
+
+// DriftCorrector corrects gyroscope drift while the controller rests
+// by adjusting the gyroscope bias of the device slowly.
+type DriftCorrector struct {
+	// Window and RestStdDev are used for rest detection like in Calibrator.
+	Window     int
+	RestStdDev float64
+
+	// Alpha is the fraction of the residual bias
+	// corrected for each state at rest.
+	Alpha float64
+
+	rest restDetector
+	acc  [3]float64 // correction not yet applied
+}
+
+// Update adds the calibrated state s read from d.
+// If the controller is at rest, the gyroscope bias of d
+// is adjusted towards the residual rate in s.
+func (c *DriftCorrector) Update(d *ds4.Device, s *ds4.State) {
+	if !c.rest.add(s, c.Window, c.RestStdDev) {
+		return
+	}
+	a := c.Alpha
+	if a <= 0 {
+		a = defaultDriftAlpha
+	}
+	r := [3]int16{s.XGyro, s.YGyro, s.ZGyro}
+	var adj [3]int
+	changed := false
+	for i := range c.acc {
+		c.acc[i] += a * float64(r[i])
+		if n := math.Trunc(c.acc[i]); n != 0 {
+			adj[i] = int(n)
+			c.acc[i] -= n
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+	cal := d.Calibration()
+	cal.XGyro += adj[0]
+	cal.YGyro += adj[1]
+	cal.ZGyro += adj[2]
+	d.SetCalibration(cal)
+}
@@ -0,0 +1,112 @@
+package ds4util
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/tajtiattila/hid/ds4"
+)
+
+// restState returns a state at rest with sensor noise.
+func restState(r *rand.Rand) ds4.State {
+	return ds4.State{
+		LX: 131, LY: 126, RX: 128, RY: 129,
+		XAcc:  int16(r.Intn(100)),
+		YAcc:  int16(8192 + r.Intn(100)),
+		ZAcc:  int16(r.Intn(100)),
+		XGyro: int16(12 + r.Intn(5) - 2),
+		YGyro: int16(-7 + r.Intn(5) - 2),
+		ZGyro: int16(r.Intn(5) - 2),
+	}
+}
+
+func TestCalibrator(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	c := NewCalibrator()
+
+	// moving controller
+	for i := 0; i < 500; i++ {
+		s := restState(r)
+		s.XAcc = int16(r.Intn(4000))
+		s.XGyro = int16(r.Intn(4000))
+		if c.Add(&s) {
+			t.Fatal("moving controller at rest")
+		}
+	}
+	if c.Progress() != 0 {
+		t.Fatal("progress while moving")
+	}
+
+	for i := 0; !c.Done(); i++ {
+		if i > 1000 {
+			t.Fatal("calibration not done")
+		}
+		s := restState(r)
+		c.Add(&s)
+	}
+
+	// stick range
+	for _, v := range []byte{10, 250} {
+		s := restState(r)
+		s.LX = v
+		c.Add(&s)
+	}
+
+	cal := c.Calibration()
+	if cal.LX != 3 || cal.LY != -2 || cal.RX != 0 || cal.RY != 1 {
+		t.Errorf("stick offsets %+v", cal)
+	}
+	if cal.XGyro != 12 || cal.YGyro != -7 || cal.ZGyro != 0 {
+		t.Errorf("gyro bias %+v", cal)
+	}
+	if cal.Min[0] != 10 || cal.Max[0] != 250 || cal.Max[1] != 0 {
+		t.Errorf("stick ranges %v %v", cal.Min, cal.Max)
+	}
+}
+
+func TestCalibratorDevice(t *testing.T) {
+	c := newFakeConn("bt", "01:02:03:04:05:06", true)
+	c.setMotion([3]int16{12, -7, 3}, [3]int16{100, 8000, -300})
+	d, err := ds4.NewDevice(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cal := NewCalibrator()
+	var s ds4.State
+	for i := 0; !cal.Done(); i++ {
+		if i > 1000 {
+			t.Fatal("calibration not done")
+		}
+		if err := d.ReadState(&s); err != nil {
+			t.Fatal(err)
+		}
+		cal.Add(&s)
+	}
+	if x := cal.Calibration(); x.XGyro != 12 || x.YGyro != -7 || x.ZGyro != 3 {
+		t.Errorf("gyro bias %+v", x)
+	}
+}
+
+func TestDriftCorrector(t *testing.T) {
+	d, err := ds4.NewDevice(newFakeConn("usb", "01:02:03:04:05:06", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetCalibration(ds4.Calibration{XGyro: 10})
+
+	r := rand.New(rand.NewSource(1))
+	var c DriftCorrector
+	for i := 0; i < 2000; i++ {
+		s := restState(r)
+		cal := d.Calibration()
+		s.XGyro -= int16(cal.XGyro)
+		s.YGyro -= int16(cal.YGyro)
+		s.ZGyro -= int16(cal.ZGyro)
+		c.Update(d, &s)
+	}
+	cal := d.Calibration()
+	if cal.XGyro < 11 || cal.XGyro > 13 || cal.YGyro < -8 || cal.YGyro > -6 {
+		t.Errorf("gyro bias not corrected: %+v", cal)
+	}
+}
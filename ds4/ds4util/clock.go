@@ -0,0 +1,50 @@
+package ds4util
+
+import (
+	"time"
+
+	"github.com/tajtiattila/hid/ds4"
+)
+
+// ReportClock converts the wrapping report timestamps
+// of a controller to times.
+//
+// Time is measured using report timestamps, so handlers using
+// a ReportClock must be passed every report of the controller.
+type ReportClock struct {
+	// Base is the time of the first report.
+	// The time of the first call to Time is used if Base is zero.
+	Base time.Time
+
+	init    bool
+	last    uint16
+	elapsed time.Duration
+}
+
+// Time returns the time of the report s.
+//
+// Reports must be passed in order, and the time
+// between consecutive reports must be less than 349 ms.
+func (c *ReportClock) Time(s *ds4.State) time.Time {
+	if !c.init {
+		c.init = true
+		if c.Base.IsZero() {
+			c.Base = time.Now()
+		}
+		c.last = s.Timestamp
+		return c.Base
+	}
+	c.elapsed += tickDuration(s.Timestamp - c.last)
+	c.last = s.Timestamp
+	return c.Base.Add(c.elapsed)
+}
+
+// Reset restarts c at the next report. It clears Base.
+func (c *ReportClock) Reset() {
+	*c = ReportClock{}
+}
+
+// tickDuration returns the duration of n report timestamp ticks of 16/3 µs.
+func tickDuration(n uint16) time.Duration {
+	return time.Duration(n) * 16 * time.Microsecond / 3
+}
@@ -0,0 +1,298 @@
+package ds4util
+
+import (
+	"time"
+
+	"github.com/tajtiattila/hid/ds4"
+)
+
+// ComboKind is the kind of a Combo.
+type ComboKind int
+
+const (
+	// ChordCombo is active while its buttons are held,
+	// if they were pressed within ChordTime.
+	ChordCombo ComboKind = iota + 1
+
+	// SequenceCombo fires when its steps are pressed in order,
+	// each within SequenceTime of the previous one.
+	SequenceCombo
+
+	// TapCombo fires when its buttons are released within HoldTime.
+	TapCombo
+
+	// HoldCombo is active while its buttons are held longer than HoldTime.
+	HoldCombo
+
+	// TurboCombo is pressed and released repeatedly with TurboPeriod
+	// while its buttons are held.
+	TurboCombo
+)
+
+var comboKindStr = []string{"", "chord", "sequence", "tap", "hold", "turbo"}
+
+func (k ComboKind) String() string {
+	if k > 0 && int(k) < len(comboKindStr) {
+		return comboKindStr[k]
+	}
+	return "combo?"
+}
+
+// Combo is a button combination recognised by Combos.
+// Buttons are the bits returned by Buttons.
+type Combo struct {
+	// Name identifies the combo for handlers.
+	Name string
+
+	Kind ComboKind
+
+	// Buttons holds the buttons of all combos but SequenceCombo.
+	Buttons uint32
+
+	// Steps holds the buttons of the steps of a SequenceCombo.
+	// A step is matched when its buttons become pressed.
+	// D-pad directions in a step must match exactly, so
+	// DpadDown, DpadDown|DpadRight, DpadRight|Square
+	// is a quarter circle forward with Square.
+	Steps []uint32
+
+	// Time overrides the ChordTime, SequenceTime, HoldTime
+	// or TurboPeriod of the kind if non-zero.
+	Time time.Duration
+}
+
+// ComboEvent reports a change of a combo.
+type ComboEvent struct {
+	Combo *Combo
+
+	// Time is the report time of the event.
+	Time time.Time
+
+	// Pressed is set when the combo becomes active,
+	// and cleared when it ends. SequenceCombo and TapCombo
+	// are reported as a press followed by a release.
+	Pressed bool
+}
+
+// ComboHandler receives combo events.
+type ComboHandler interface {
+	Combo(e *ComboEvent)
+}
+
+// ComboConfig holds the combo timing parameters.
+type ComboConfig struct {
+	// ChordTime is the longest time between
+	// the first and last button press of a chord.
+	ChordTime time.Duration
+
+	// SequenceTime is the longest time between sequence steps.
+	SequenceTime time.Duration
+
+	// HoldTime separates taps from holds.
+	HoldTime time.Duration
+
+	// TurboPeriod is the time between turbo presses.
+	TurboPeriod time.Duration
+}
+
+// DefaultComboConfig is the default combo configuration.
+var DefaultComboConfig = ComboConfig{
+	ChordTime:    50 * time.Millisecond,
+	SequenceTime: 250 * time.Millisecond,
+	HoldTime:     300 * time.Millisecond,
+	TurboPeriod:  100 * time.Millisecond,
+}
+
+// Combos recognises button combinations.
+// Events are sent in the order of Combos within a report.
+// It uses a ReportClock.
+type Combos struct {
+	Handler ComboHandler
+
+	// Combos holds the combos to recognise.
+	Combos []*Combo
+
+	// Config holds the combo parameters.
+	// It is set to DefaultComboConfig by NewCombos.
+	Config ComboConfig
+
+	clock ReportClock
+	now   time.Time
+
+	prev uint32
+	down [32]time.Time // press time of button bits
+
+	state map[*Combo]*comboState
+}
+
+type comboState struct {
+	active bool
+	t      time.Time // time of press or last sequence step
+	step   int       // next sequence step
+	on     bool      // turbo output
+}
+
+// NewCombos returns a new combo recogniser for combos
+// using DefaultComboConfig.
+func NewCombos(h ComboHandler, combos ...*Combo) *Combos {
+	return &Combos{
+		Handler: h,
+		Combos:  combos,
+		Config:  DefaultComboConfig,
+	}
+}
+
+// HandleState recognises combos in s.
+func (c *Combos) HandleState(s *ds4.State) {
+	c.now = c.clock.Time(s)
+	if c.state == nil {
+		c.state = make(map[*Combo]*comboState)
+	}
+
+	b := Buttons(s.Button)
+	pressed := b &^ c.prev
+	for i := range c.down {
+		if pressed&(1<<uint(i)) != 0 {
+			c.down[i] = c.now
+		}
+	}
+	for _, x := range c.Combos {
+		st := c.state[x]
+		if st == nil {
+			st = new(comboState)
+			c.state[x] = st
+		}
+		c.update(x, st, b, pressed)
+	}
+	c.prev = b
+}
+
+// Reset resets the combo state and the report clock
+// without sending events.
+func (c *Combos) Reset() {
+	c.clock.Reset()
+	c.prev = 0
+	c.state = nil
+}
+
+func (c *Combos) update(x *Combo, st *comboState, b, pressed uint32) {
+	held := x.Buttons != 0 && b&x.Buttons == x.Buttons
+	was := x.Buttons != 0 && c.prev&x.Buttons == x.Buttons
+	if held && !was {
+		st.t = c.now
+	}
+	switch x.Kind {
+	case ChordCombo:
+		if !st.active && held && pressed&x.Buttons != 0 && c.spread(x.Buttons) <= c.time(x) {
+			st.active = true
+			c.send(x, true)
+		} else if st.active && !held {
+			st.active = false
+			c.send(x, false)
+		}
+
+	case SequenceCombo:
+		if st.step > 0 && c.now.Sub(st.t) > c.time(x) {
+			st.step = 0
+		}
+		if len(x.Steps) == 0 {
+			return
+		}
+		switch {
+		case c.stepPressed(x.Steps[st.step], b):
+			st.step++
+			st.t = c.now
+		case st.step > 0 && c.stepPressed(x.Steps[0], b):
+			st.step = 1
+			st.t = c.now
+		}
+		if st.step == len(x.Steps) {
+			st.step = 0
+			c.send(x, true)
+			c.send(x, false)
+		}
+
+	case TapCombo:
+		if was && !held && c.now.Sub(st.t) < c.time(x) {
+			c.send(x, true)
+			c.send(x, false)
+		}
+
+	case HoldCombo:
+		if !st.active && held && c.now.Sub(st.t) >= c.time(x) {
+			st.active = true
+			c.send(x, true)
+		} else if st.active && !held {
+			st.active = false
+			c.send(x, false)
+		}
+
+	case TurboCombo:
+		on := false
+		if held {
+			p := c.time(x)
+			on = c.now.Sub(st.t)%p < p/2
+		}
+		if on != st.on {
+			st.on = on
+			c.send(x, on)
+		}
+	}
+}
+
+// spread returns the time between the first and last press of bits.
+func (c *Combos) spread(bits uint32) time.Duration {
+	var first, last time.Time
+	for i, t := range c.down {
+		if bits&(1<<uint(i)) == 0 {
+			continue
+		}
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+		if t.After(last) {
+			last = t
+		}
+	}
+	return last.Sub(first)
+}
+
+// stepPressed reports whether the sequence step m
+// became pressed with the buttons b.
+func (c *Combos) stepPressed(m, b uint32) bool {
+	return stepMatch(m, b) && !stepMatch(m, c.prev)
+}
+
+// stepMatch reports whether the buttons b match the sequence step m.
+func stepMatch(m, b uint32) bool {
+	return b&m == m && (m&DpadMask == 0 || b&DpadMask == m&DpadMask)
+}
+
+// time returns the time parameter of x.
+func (c *Combos) time(x *Combo) time.Duration {
+	if x.Time != 0 {
+		return x.Time
+	}
+	cfg, d := c.Config, DefaultComboConfig
+	var t, dt time.Duration
+	switch x.Kind {
+	case ChordCombo:
+		t, dt = cfg.ChordTime, d.ChordTime
+	case SequenceCombo:
+		t, dt = cfg.SequenceTime, d.SequenceTime
+	case TapCombo, HoldCombo:
+		t, dt = cfg.HoldTime, d.HoldTime
+	case TurboCombo:
+		t, dt = cfg.TurboPeriod, d.TurboPeriod
+	}
+	if t == 0 {
+		return dt
+	}
+	return t
+}
+
+func (c *Combos) send(x *Combo, pressed bool) {
+	if c.Handler != nil {
+		c.Handler.Combo(&ComboEvent{Combo: x, Time: c.now, Pressed: pressed})
+	}
+}
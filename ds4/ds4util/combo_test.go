@@ -0,0 +1,176 @@
+package ds4util
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tajtiattila/hid/ds4"
+)
+
+// D-pad values of ds4.State used in tests.
+const (
+	dpadNone      = ds4.DpadOff
+	dpadDown      = 4
+	dpadDownRight = 3
+	dpadRight     = 2
+)
+
+// buttonStep is a button state at ms milliseconds.
+type buttonStep struct {
+	ms     int
+	button uint32
+}
+
+// buttonStates returns the states for steps.
+func buttonStates(steps []buttonStep) []ds4.State {
+	v := make([]ds4.State, len(steps))
+	for i, st := range steps {
+		v[i] = testState(i, st.ms)
+		v[i].Button = st.button
+	}
+	return v
+}
+
+func TestCombos(t *testing.T) {
+	chord := &Combo{Name: "chord", Kind: ChordCombo, Buttons: ds4.L1 | ds4.R1}
+	tap := &Combo{Name: "tap", Kind: TapCombo, Buttons: ds4.Cross}
+	hold := &Combo{Name: "hold", Kind: HoldCombo, Buttons: ds4.Cross}
+	qcf := &Combo{Name: "qcf", Kind: SequenceCombo, Steps: []uint32{
+		DpadDown, DpadDown | DpadRight, DpadRight | ds4.Square,
+	}}
+	turbo := &Combo{Name: "turbo", Kind: TurboCombo, Buttons: ds4.Circle, Time: 90 * time.Millisecond}
+
+	tests := []struct {
+		name   string
+		combos []*Combo
+		steps  []buttonStep
+		want   []string
+	}{
+		{
+			name:   "chord",
+			combos: []*Combo{chord},
+			steps: []buttonStep{
+				{0, dpadNone},
+				{10, dpadNone | ds4.L1},
+				{30, dpadNone | ds4.L1 | ds4.R1},
+				{100, dpadNone | ds4.R1},
+				{120, dpadNone},
+			},
+			want: []string{"30 chord+", "100 chord-"},
+		},
+		{
+			name:   "chord too slow",
+			combos: []*Combo{chord},
+			steps: []buttonStep{
+				{0, dpadNone},
+				{10, dpadNone | ds4.L1},
+				{90, dpadNone | ds4.L1 | ds4.R1},
+				{150, dpadNone},
+			},
+			want: nil,
+		},
+		{
+			name:   "tap",
+			combos: []*Combo{tap, hold},
+			steps: []buttonStep{
+				{0, dpadNone},
+				{10, dpadNone | ds4.Cross},
+				{110, dpadNone},
+			},
+			want: []string{"110 tap+", "110 tap-"},
+		},
+		{
+			name:   "hold",
+			combos: []*Combo{tap, hold},
+			steps: []buttonStep{
+				{0, dpadNone},
+				{10, dpadNone | ds4.Cross},
+				{200, dpadNone | ds4.Cross},
+				{320, dpadNone | ds4.Cross},
+				{400, dpadNone},
+			},
+			want: []string{"320 hold+", "400 hold-"},
+		},
+		{
+			name:   "sequence",
+			combos: []*Combo{qcf},
+			steps: []buttonStep{
+				{0, dpadNone},
+				{20, dpadDown},
+				{60, dpadDownRight},
+				{100, dpadRight},
+				{140, dpadRight | ds4.Square},
+				{200, dpadNone},
+			},
+			want: []string{"140 qcf+", "140 qcf-"},
+		},
+		{
+			name:   "sequence restart",
+			combos: []*Combo{qcf},
+			steps: []buttonStep{
+				{0, dpadDown},
+				{40, dpadNone},
+				{80, dpadDown},
+				{120, dpadDownRight | ds4.Square},
+				{160, dpadRight | ds4.Square},
+			},
+			want: []string{"160 qcf+", "160 qcf-"},
+		},
+		{
+			name:   "sequence too slow",
+			combos: []*Combo{qcf},
+			steps: []buttonStep{
+				{0, dpadNone},
+				{20, dpadDown},
+				{320, dpadDownRight},
+				{360, dpadRight | ds4.Square},
+			},
+			want: nil,
+		},
+		{
+			name:   "turbo",
+			combos: []*Combo{turbo},
+			steps: []buttonStep{
+				{0, dpadNone},
+				{20, dpadNone | ds4.Circle},
+				{40, dpadNone | ds4.Circle},
+				{60, dpadNone | ds4.Circle},
+				{80, dpadNone | ds4.Circle},
+				{100, dpadNone | ds4.Circle},
+				{120, dpadNone | ds4.Circle},
+				{140, dpadNone | ds4.Circle},
+				{160, dpadNone},
+			},
+			want: []string{"20 turbo+", "80 turbo-", "120 turbo+", "160 turbo-"},
+		},
+	}
+	for _, tt := range tests {
+		var r recorder
+		c := NewCombos(&r, tt.combos...)
+		c.clock.Base = testBase
+		for _, s := range buttonStates(tt.steps) {
+			c.HandleState(&s)
+		}
+		if !reflect.DeepEqual(r.calls, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, r.calls, tt.want)
+		}
+	}
+}
+
+func TestButtons(t *testing.T) {
+	tests := []struct {
+		b, want uint32
+	}{
+		{ds4.DpadOff, 0},
+		{ds4.DpadOff | ds4.Cross, ds4.Cross},
+		{0 | ds4.L2, DpadUp | ds4.L2},
+		{dpadDownRight, DpadDown | DpadRight},
+		{7, DpadUp | DpadLeft},
+	}
+	for _, tt := range tests {
+		if got := Buttons(tt.b); got != tt.want {
+			t.Errorf("Buttons(%#x) = %#x, want %#x", tt.b, got, tt.want)
+		}
+	}
+}
@@ -1,7 +1,8 @@
 package ds4util
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"log"
 	"sort"
 	"sync"
@@ -20,7 +21,19 @@ type Entry struct {
 	Name    string
 	Serial  string
 	Conn    int
-	Battery byte
+	Battery ds4.Battery
+
+	// Slot is the player slot starting from 1,
+	// or zero if the controller has no slot.
+	Slot int
+
+	// Settings holds the settings from the registry,
+	// or nil if the controller is not in the registry.
+	Settings *Settings
+
+	// Info is the controller firmware info,
+	// or nil if it could not be read.
+	Info *ds4.Info
 }
 
 func (e *Entry) String() string {
@@ -33,6 +46,15 @@ func (e *Entry) String() string {
 	return e.Serial + "(" + conn + ")"
 }
 
+// DisplayName returns the name of the controller from the registry,
+// or its serial number if it has no name.
+func (e *Entry) DisplayName() string {
+	if e.Settings != nil && e.Settings.Name != "" {
+		return e.Settings.Name
+	}
+	return e.Serial
+}
+
 func (e *Entry) ConnString() string {
 	switch e.Conn {
 	case ConnUSB:
@@ -44,24 +66,35 @@ func (e *Entry) ConnString() string {
 }
 
 func (e *Entry) BatteryString() string {
-	return batteryString(e.Battery)
+	return e.Battery.String()
 }
 
 // Charging reports if the battery is charging.
 func (e *Entry) Charging() bool {
-	return e.Battery&0xF0 != 0
+	return e.Battery.Charging()
 }
 
 // BatteryLevel reports the battery level percentage divided by 10.
 func (e *Entry) BatteryLevel() byte {
-	return e.Battery & 0x0F
+	return byte(e.Battery.Percent() / 10)
 }
 
-// Event is sent when a new device is connected,
-// an old device is disconnected, or its battery state changes.
+// Event is sent when a new device is connected, an old device is
+// disconnected, its transport changes or its battery state changes.
 type Event struct {
 	Entry
 	Removed bool
+
+	// TransportChanged is set when the controller switched
+	// between USB and bluetooth. Entry.Conn is the new transport.
+	TransportChanged bool
+
+	// LowBattery is set when the battery became low.
+	LowBattery bool
+
+	// SlotChanged is set when the player slot
+	// of the controller was changed using SwapSlots.
+	SlotChanged bool
 }
 
 type StateHandler interface {
@@ -78,198 +111,709 @@ type ConnectHandler interface {
 	Connect(d *ds4.Device, e Entry) (StateHandler, error)
 }
 
-type DeviceManager struct {
-	// protects dev
-	mtx sync.RWMutex
-	dev map[string]Entry
+// TransportHandler may be implemented by a StateHandler
+// to be notified when its controller switches between USB and bluetooth.
+//
+// The handler keeps receiving states without being closed,
+// but the device passed to Connect is closed after TransportChanged
+// returns, and d should be used instead.
+type TransportHandler interface {
+	TransportChanged(d *ds4.Device, e Entry)
+}
+
+// TransportPolicy selects the transport used for controllers
+// connected using both USB and bluetooth.
+type TransportPolicy int
 
-	log *log.Logger
-	che chan Event
+const (
+	// KeepTransport keeps using the transport a controller
+	// was first seen with, and switches only when it is lost.
+	KeepTransport TransportPolicy = iota
+
+	// PreferUSB switches to USB when the cable is attached.
+	PreferUSB
 
-	chq chan chan struct{}
+	// PreferBluetooth switches to bluetooth when it becomes available.
+	PreferBluetooth
+)
 
-	chqwork chan struct{}
-	grpwork sync.WaitGroup
+// DeviceManager finds DS4 controllers, and runs a StateHandler
+// for each of them until the controller is disconnected.
+//
+// A controller connected both using USB and bluetooth is handled only once.
+// The manager switches between the transports transparently
+// according to Transport, or when the transport in use is lost.
+type DeviceManager struct {
+	// Backend finds and opens controllers.
+	// It may be changed before Run is called.
+	Backend Backend
+
+	// PollInterval is the time between searches for new controllers.
+	// It may be changed before Run is called.
+	PollInterval time.Duration
+
+	// Transport is the transport policy.
+	// It may be changed before Run is called.
+	Transport TransportPolicy
+
+	// LowBattery is the battery percentage at or below which the battery
+	// of a controller not connected to a cable is considered low.
+	// A low battery event is sent when the battery becomes low.
+	// Zero disables low battery events.
+	// It may be changed before Run is called.
+	LowBattery int
+
+	// LowBatteryFlash is the duration the lightbar flashes
+	// LowBatteryColor when the battery becomes low.
+	// Zero disables the lightbar warning.
+	// It may be changed before Run is called.
+	LowBatteryFlash time.Duration
+	LowBatteryColor ds4.Color
+
+	// Slots assigns player slots to controllers, and sets their
+	// lightbar colour before their handler is connected.
+	// Player slots are disabled if Slots is nil.
+	// It may be set before Run is called.
+	Slots *Slots
+
+	// Registry holds controller settings applied
+	// before their handler is connected.
+	// The controller colour in the registry overrides the slot colour.
+	// It may be set before Run is called.
+	Registry *Registry
+
+	// DriftCorrection enables correcting gyroscope drift
+	// while controllers rest, see DriftCorrector.
+	// It may be changed before Run is called.
+	DriftCorrection bool
+
+	// BatteryHold is the time a new battery level must be read
+	// continuously before it is reported, see ds4.BatteryFilter.
+	// It may be changed before Run is called.
+	BatteryHold time.Duration
 
 	connh ConnectHandler
+	log   *log.Logger
+	now   func() time.Time
+
+	events *eventQueue
+
+	// protects fields below
+	mtx sync.RWMutex
+	dev map[string]*managed
+
+	running bool
+	stop    context.CancelFunc
+	done    chan struct{}
+}
+
+// managed is a controller handled by the manager.
+type managed struct {
+	// e is valid only after the handler is connected
+	e     Entry
+	ready bool
+
+	// d is the device in use, valid if ready is set
+	d *ds4.Device
+
+	// switching is set while a device for the other transport is
+	// being opened, and until it is taken from sw
+	switching bool
+	sw        chan *ds4.Device
+
+	// ended is set when the handler is stopped
+	ended bool
+
+	// used only by the goroutine running the controller
+	drift   DriftCorrector
+	bat     ds4.BatteryFilter
+	low     bool
+	warnEnd time.Time  // end of low battery warning
+	warnOut ds4.Output // warning output
+	prevOut ds4.Output // output before warning
 }
 
+// NewDeviceManager creates a new device manager using h
+// to handle new controllers. Log may be nil to disable logging.
+//
+// Run must be called to start the manager.
 func NewDeviceManager(h ConnectHandler, log *log.Logger) *DeviceManager {
-	m := &DeviceManager{
-		dev:     make(map[string]Entry),
-		che:     make(chan Event),
-		chqwork: make(chan struct{}),
-		chq:     make(chan chan struct{}),
-		connh:   h,
-		log:     log,
-	}
-	go func() {
-		t := time.NewTicker(time.Second)
-		defer t.Stop()
-		for {
-			select {
-			case <-t.C:
-				m.findDevices()
-			case c := <-m.chq:
-				close(m.chqwork)
-				m.grpwork.Wait()
-				close(m.che)
-				close(c)
-				return
-			}
-		}
-	}()
-	return m
+	return &DeviceManager{
+		Backend:      HIDBackend,
+		PollInterval: time.Second,
+
+		LowBattery:      15,
+		LowBatteryColor: ds4.Color{R: 0xff},
+		BatteryHold:     5 * time.Second,
+
+		connh:  h,
+		log:    log,
+		now:    time.Now,
+		events: newEventQueue(eventQueueLen),
+		dev:    make(map[string]*managed),
+	}
 }
 
+// Event returns the channel of device events.
+//
+// Events are never blocking the manager. If events are not received,
+// at most eventQueueLen of the newest events are kept.
+// The channel is closed after Run returns and all queued events
+// have been received, or discarded after eventDrainTimeout.
 func (m *DeviceManager) Event() <-chan Event {
-	return m.che
+	return m.events.out
+}
+
+// Entries returns the entries of the connected controllers.
+func (m *DeviceManager) Entries() []Entry {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	var v []Entry
+	for _, x := range m.dev {
+		if x.ready {
+			e := x.e
+			e.Slot = m.slotOf(e.Serial)
+			v = append(v, e)
+		}
+	}
+	sort.Sort(entrySort(v))
+	return v
+}
+
+// DeviceStats holds the statistics of a controller.
+type DeviceStats struct {
+	Entry
+	ds4.Stats
+}
+
+// Stats returns the statistics of the connected controllers.
+//
+// Statistics are restarted when a controller switches transport.
+func (m *DeviceManager) Stats() []DeviceStats {
+	m.mtx.RLock()
+	var v []DeviceStats
+	var d []*ds4.Device
+	for _, x := range m.dev {
+		if x.ready {
+			v = append(v, DeviceStats{Entry: x.e})
+			d = append(d, x.d)
+		}
+	}
+	m.mtx.RUnlock()
+	for i := range v {
+		v[i].Slot = m.slotOf(v[i].Serial)
+		v[i].Stats = d[i].Stats()
+	}
+	sort.Sort(deviceStatsSort(v))
+	return v
 }
 
+// SwapSlots swaps the controllers in player slots a and b,
+// and updates their lightbar colours.
+// Either of the slots may be free.
+func (m *DeviceManager) SwapSlots(a, b int) error {
+	if m.Slots == nil {
+		return errors.New("ds4util: player slots disabled")
+	}
+	sa, sb, err := m.Slots.swap(a, b)
+	if err != nil {
+		return err
+	}
+	for _, sno := range []string{sa, sb} {
+		if sno == "" {
+			continue
+		}
+		m.mtx.RLock()
+		x := m.dev[sno]
+		var d *ds4.Device
+		var e Entry
+		if x != nil && x.ready {
+			d, e = x.d, x.e
+		}
+		m.mtx.RUnlock()
+		if d == nil {
+			continue
+		}
+		e.Slot = m.slotOf(sno)
+		m.setSlotColor(d, &e)
+		m.push(Event{Entry: e, SlotChanged: true})
+	}
+	return nil
+}
+
+// Run searches for controllers and runs their handlers until ctx is done
+// or Close is called. All handlers are stopped and devices are closed
+// before Run returns. The bluetooth radio of the controllers is
+// disconnected when stopping.
+//
+// Run may be called only once. It returns ctx.Err(), or nil if
+// the manager was stopped with Close.
+func (m *DeviceManager) Run(ctx context.Context) error {
+	m.mtx.Lock()
+	if m.running {
+		m.mtx.Unlock()
+		return errors.New("ds4util: DeviceManager.Run called twice")
+	}
+	m.running = true
+	rctx, stop := context.WithCancel(ctx)
+	m.stop, m.done = stop, make(chan struct{})
+	m.mtx.Unlock()
+
+	defer close(m.done)
+	defer stop()
+
+	go m.events.pump()
+
+	var wg sync.WaitGroup
+	t := time.NewTicker(m.PollInterval)
+	defer t.Stop()
+	for {
+		m.findDevices(rctx, &wg)
+		select {
+		case <-t.C:
+		case <-rctx.Done():
+			wg.Wait()
+			m.events.close()
+			return ctx.Err()
+		}
+	}
+}
+
+// Close stops a running manager, and waits until Run returns.
 func (m *DeviceManager) Close() error {
-	c := make(chan struct{})
-	m.chq <- c
-	<-c
+	m.mtx.Lock()
+	stop, done := m.stop, m.done
+	m.mtx.Unlock()
+	if stop == nil {
+		return nil
+	}
+	stop()
+	<-done
 	return nil
 }
 
-func (m *DeviceManager) findDevices() {
-	dlist, err := hid.VendorDevices(0x54C, 0x5C4) // DualShock 4
+func (m *DeviceManager) findDevices(ctx context.Context, wg *sync.WaitGroup) {
+	if ctx.Err() != nil {
+		return
+	}
+	dlist, err := m.Backend.Devices()
 	if err != nil {
-		m.log.Println(err)
+		m.logf("finding devices: %v", err)
 		return
 	}
 
-	sort.Sort(InputLenSort(dlist))
+	// start new controllers using the preferred transport
+	if m.Transport == PreferUSB {
+		sort.Sort(sort.Reverse(InputLenSort(dlist)))
+	} else {
+		sort.Sort(InputLenSort(dlist))
+	}
 
 	for _, di := range dlist {
-		if di.Attr.SerialNo == "" {
+		sno := di.Attr.SerialNo
+		if sno == "" {
 			continue
 		}
-		m.mtx.RLock()
-		_, ok := m.dev[di.Attr.SerialNo]
-		m.mtx.RUnlock()
-		if !ok {
-			m.runDevice(di)
+		m.mtx.Lock()
+		x, ok := m.dev[sno]
+		switch {
+		case !ok:
+			x = &managed{sw: make(chan *ds4.Device, 1)}
+			m.dev[sno] = x
+			wg.Add(1)
+			go func(di *hid.DeviceInfo) {
+				defer wg.Done()
+				m.runDevice(ctx, di, x)
+			}(di)
+		case x.ready && !x.switching && x.e.Conn != connOf(di) && m.prefers(connOf(di)):
+			x.switching = true
+			wg.Add(1)
+			go func(di *hid.DeviceInfo) {
+				defer wg.Done()
+				m.prepareSwitch(ctx, di, x)
+			}(di)
 		}
+		m.mtx.Unlock()
+	}
+}
+
+// prefers reports if the transport policy prefers conn.
+func (m *DeviceManager) prefers(conn int) bool {
+	switch m.Transport {
+	case PreferUSB:
+		return conn == ConnUSB
+	case PreferBluetooth:
+		return conn == ConnBT
+	}
+	return false
+}
+
+// runDevice runs the controller di until ctx is done or the device fails.
+func (m *DeviceManager) runDevice(ctx context.Context, di *hid.DeviceInfo, x *managed) {
+	e, err := m.serveDevice(ctx, di, x)
+
+	// device and handler closed, allow reopening
+	m.mtx.Lock()
+	x.ended = true
+	select {
+	case d := <-x.sw:
+		d.Close()
+	default:
+	}
+	delete(m.dev, di.Attr.SerialNo)
+	m.mtx.Unlock()
+
+	if e != nil {
+		m.logf("stopping %s: %v", e.String(), err)
+		m.push(Event{Entry: *e, Removed: true})
+	}
+	if m.Slots != nil {
+		m.Slots.release(di.Attr.SerialNo)
 	}
 }
 
-func (m *DeviceManager) runDevice(di *hid.DeviceInfo) {
-	d, err := ds4.Open(di.Name)
+// serveDevice opens and initializes the controller di,
+// and runs its handler until ctx is done or reading fails
+// on all transports.
+// It returns the device entry if the handler was connected,
+// and the error that stopped the device.
+func (m *DeviceManager) serveDevice(ctx context.Context, di *hid.DeviceInfo, x *managed) (*Entry, error) {
+	sno := di.Attr.SerialNo
+
+	d, s, err := m.openDevice(ctx, di)
 	if err != nil {
-		m.log.Print("opening device ", di.Attr.SerialNo, ": ", err)
-		return
+		m.logf("opening device %s: %v", sno, err)
+		return nil, err
+	}
+	defer func() {
+		d.Close()
+	}()
+
+	info, err := d.Info()
+	if err != nil {
+		m.logf("firmware info %s: %v", sno, err)
+	}
+
+	e := &Entry{
+		Name:    di.Name,
+		Serial:  sno,
+		Conn:    connOf(di),
+		Battery: s.Battery,
+		Info:    info,
+	}
+
+	if m.Slots != nil {
+		e.Slot = m.Slots.assign(sno)
+		m.setSlotColor(d, e)
+	}
+
+	if m.Registry != nil {
+		if st, ok := m.Registry.Lookup(sno); ok {
+			m.applySettings(d, e, &st)
+		}
+	}
+
+	x.bat = ds4.BatteryFilter{Hold: m.BatteryHold}
+	x.bat.Update(s.Battery, m.now())
+
+	h, err := m.connh.Connect(d, *e)
+	if err != nil {
+		m.logf("handler init %s: %v", e.String(), err)
+		return nil, err
+	}
+	defer h.Close()
+
+	m.setDevice(x, d, *e)
+	m.push(Event{Entry: *e})
+	m.logf("starting %s", e.String())
+
+	for {
+		nd, err := m.handleStates(ctx, d, h, e, x)
+		if nd == nil && ctx.Err() == nil &&
+			(errors.Is(err, hid.ErrDeviceGone) || errors.Is(err, hid.ErrTimeout)) {
+			// transport lost, try the other one
+			nd = m.findAlternative(ctx, x, e)
+		}
+		if nd == nil {
+			if ctx.Err() != nil {
+				// manager stopped
+				err = d.DisconnectRadio()
+				if err == hid.ErrNotBluetooth {
+					err = nil
+				}
+			}
+			return e, err
+		}
+		m.switchDevice(x, h, e, d, nd)
+		d = nd
+	}
+}
+
+// openDevice opens and initializes the device di.
+// It returns the last state read during initialization.
+func (m *DeviceManager) openDevice(ctx context.Context, di *hid.DeviceInfo) (*ds4.Device, *ds4.State, error) {
+	d, err := m.Backend.Open(di)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	d.SetTimeout(time.Second)
 
 	// read a few states before commencing
-	var s ds4.State
+	s := new(ds4.State)
 	for i := 0; i < 10; i++ {
-		if err := d.ReadState(&s); err != nil {
-			m.log.Print("initialization", di.Attr.SerialNo, ": ", err)
+		if err := d.ReadStateContext(ctx, s); err != nil && !isCorrupt(err) {
 			d.Close()
-			return
+			return nil, nil, err
 		}
 	}
+	return d, s, nil
+}
 
-	battery := s.Battery
-	var conn int
-	if d.Bluetooth() {
-		conn = ConnBT
-	} else {
-		conn = ConnUSB
+// prepareSwitch opens di for the controller x,
+// and passes it to the running handler.
+func (m *DeviceManager) prepareSwitch(ctx context.Context, di *hid.DeviceInfo, x *managed) {
+	d, _, err := m.openDevice(ctx, di)
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if err != nil {
+		m.logf("opening device %s: %v", di.Attr.SerialNo, err)
+		x.switching = false
+		return
 	}
+	if x.ended {
+		d.Close()
+		return
+	}
+	// at most one switch is pending, so sw has room
+	x.sw <- d
+}
 
-	e := Entry{
-		Name:    di.Name,
-		Serial:  di.Attr.SerialNo,
-		Conn:    conn,
-		Battery: battery,
+// findAlternative opens the controller of e using the transport not in use.
+// It returns nil if the controller is not available using the other transport.
+func (m *DeviceManager) findAlternative(ctx context.Context, x *managed, e *Entry) *ds4.Device {
+	select {
+	case d := <-x.sw:
+		// switch already prepared
+		return d
+	default:
 	}
 
-	h, err := m.connh.Connect(d, e)
+	dlist, err := m.Backend.Devices()
 	if err != nil {
-		m.log.Print("handler init ", e.String(), ": ", err)
-		d.Close()
-		return
+		m.logf("finding devices: %v", err)
+		return nil
 	}
+	for _, di := range dlist {
+		if di.Attr.SerialNo != e.Serial || connOf(di) == e.Conn {
+			continue
+		}
+		d, _, err := m.openDevice(ctx, di)
+		if err != nil {
+			m.logf("opening device %s: %v", e.Serial, err)
+			continue
+		}
+		return d
+	}
+	return nil
+}
 
-	m.mtx.Lock()
-	if _, ok := m.dev[e.Serial]; ok {
-		m.mtx.Unlock()
-		d.Close()
-		h.Close()
-		return
+// switchDevice moves the handler h of the controller x from old to d.
+func (m *DeviceManager) switchDevice(x *managed, h StateHandler, e *Entry, old, d *ds4.Device) {
+	out := old.Output()
+	if err := d.SetOutput(&out); err != nil {
+		m.logf("restoring output %s: %v", e.String(), err)
 	}
-	m.dev[e.Serial] = e
-	m.mtx.Unlock()
+	d.SetCalibration(old.Calibration())
 
-	m.che <- Event{e, false}
-	m.grpwork.Add(1)
+	from := e.String()
+	e.Name = d.Name()
+	if d.Bluetooth() {
+		e.Conn = ConnBT
+	} else {
+		e.Conn = ConnUSB
+	}
+	if th, ok := h.(TransportHandler); ok {
+		th.TransportChanged(d, *e)
+	}
+	old.Close()
 
-	m.log.Println("starting", e.String())
+	m.mtx.Lock()
+	x.switching = false
+	m.mtx.Unlock()
+	m.setDevice(x, d, *e)
 
-	chq := m.chqwork
+	m.logf("switching %s to %s", from, e.String())
+	m.push(Event{Entry: *e, TransportChanged: true})
+}
 
-	go func() {
-		defer func() {
-			h.Close()
-			d.Close()
-			m.log.Print("stopping ", e.String(), ": ", err)
-
-			m.mtx.Lock()
-			delete(m.dev, e.Serial)
-			m.mtx.Unlock()
-
-			m.che <- Event{e, true}
-			m.grpwork.Done()
-		}()
-		var s ds4.State
-		for {
-			select {
-			case <-chq:
-				d.DisconnectRadio()
-				break
-			default:
-			}
-			err := d.ReadState(&s)
-			if err == nil {
-				err = h.State(&s)
-			}
-			if err != nil {
-				break
-			}
-			if battery != s.Battery {
-				// report new battery state
-				battery = s.Battery
-				e.Battery = battery
-				m.che <- Event{e, false}
+// handleStates feeds states read from d into h until reading or h fails,
+// or a device for switching transports arrives for the controller x.
+func (m *DeviceManager) handleStates(ctx context.Context, d *ds4.Device, h StateHandler, e *Entry, x *managed) (*ds4.Device, error) {
+	var s ds4.State
+	for {
+		select {
+		case nd := <-x.sw:
+			return nd, nil
+		default:
+		}
+		err := d.ReadStateContext(ctx, &s)
+		if isCorrupt(err) {
+			// skip corrupt report
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := h.State(&s); err != nil {
+			return nil, err
+		}
+		if m.DriftCorrection {
+			x.drift.Update(d, &s)
+		}
+		m.updateBattery(d, e, x, &s)
+	}
+}
+
+// updateBattery reports battery changes of the controller x in s.
+func (m *DeviceManager) updateBattery(d *ds4.Device, e *Entry, x *managed, s *ds4.State) {
+	now := m.now()
+	if !x.warnEnd.IsZero() && now.After(x.warnEnd) {
+		// restore output unless the handler changed it
+		x.warnEnd = time.Time{}
+		if d.Output() == x.warnOut {
+			if err := d.SetOutput(&x.prevOut); err != nil {
+				m.logf("restoring output %s: %v", e.String(), err)
 			}
 		}
-	}()
+	}
+
+	b, changed := x.bat.Update(s.Battery, now)
+	if !changed {
+		return
+	}
+
+	e.Battery = b
+	m.setEntry(nil, *e)
+
+	ev := Event{Entry: *e}
+	low := m.LowBattery > 0 && !b.Cable() && b.Percent() <= m.LowBattery
+	if low && !x.low {
+		m.logf("low battery %s: %v", e.String(), b)
+		ev.LowBattery = true
+		if m.LowBatteryFlash > 0 {
+			m.warnBattery(d, e, x, now)
+		}
+	}
+	x.low = low
+	m.push(ev)
 }
 
-func batteryString(b byte) string {
-	return batstr[int(b&0x1F)]
+// warnBattery flashes the lightbar of d to indicate low battery.
+func (m *DeviceManager) warnBattery(d *ds4.Device, e *Entry, x *managed, now time.Time) {
+	if x.warnEnd.IsZero() {
+		x.prevOut = d.Output()
+	}
+	x.warnOut = x.prevOut
+	x.warnOut.Led = m.LowBatteryColor
+	x.warnOut.On, x.warnOut.Off = 250*time.Millisecond, 250*time.Millisecond
+	if err := d.SetOutput(&x.warnOut); err != nil {
+		m.logf("low battery warning %s: %v", e.String(), err)
+		return
+	}
+	x.warnEnd = now.Add(m.LowBatteryFlash)
 }
 
-var batstr []string
+// setSlotColor sets the lightbar colour of d to the colour of its player slot.
+func (m *DeviceManager) setSlotColor(d *ds4.Device, e *Entry) {
+	if e.Slot == 0 {
+		return
+	}
+	out := d.Output()
+	out.Led = m.Slots.Color(e.Slot)
+	out.On, out.Off = 0, 0
+	if err := d.SetOutput(&out); err != nil {
+		m.logf("setting slot colour %s: %v", e.String(), err)
+	}
+}
 
-func init() {
-	batstr = make([]string, 32)
-	for i := 0; i < 16; i++ {
-		j := i
-		if j > 10 {
-			j = 10
+// applySettings applies the registry settings st to d, and stores them in e.
+func (m *DeviceManager) applySettings(d *ds4.Device, e *Entry, st *Settings) {
+	e.Settings = st
+	d.SetCalibration(st.Calibration)
+	if st.Color != nil {
+		out := d.Output()
+		out.Led = *st.Color
+		if err := d.SetOutput(&out); err != nil {
+			m.logf("setting colour %s: %v", e.String(), err)
 		}
-		s := fmt.Sprint(j*10, "%+")
-		batstr[i] = s[:len(s)-1]
-		batstr[i+16] = s
 	}
 }
 
+// slotOf returns the player slot of serial.
+func (m *DeviceManager) slotOf(serial string) int {
+	if m.Slots == nil {
+		return 0
+	}
+	return m.Slots.Slot(serial)
+}
+
+// push sends ev to the event queue.
+func (m *DeviceManager) push(ev Event) {
+	ev.Slot = m.slotOf(ev.Serial)
+	m.events.push(ev)
+}
+
+func (m *DeviceManager) setEntry(x *managed, e Entry) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if x == nil {
+		x = m.dev[e.Serial]
+	}
+	if x != nil {
+		x.e, x.ready = e, true
+	}
+}
+
+func (m *DeviceManager) setDevice(x *managed, d *ds4.Device, e Entry) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	x.d, x.e, x.ready = d, e, true
+}
+
+func (m *DeviceManager) logf(format string, v ...interface{}) {
+	if m.log != nil {
+		m.log.Printf(format, v...)
+	}
+}
+
+// connOf returns the connection type of di.
+func connOf(di *hid.DeviceInfo) int {
+	if di.Caps.InputLen > 64 {
+		return ConnBT
+	}
+	return ConnUSB
+}
+
+// isCorrupt reports whether err is caused by a corrupt input report,
+// as opposed to device removal or timeout.
+func isCorrupt(err error) bool {
+	return errors.Is(err, hid.ErrShortReport) || errors.Is(err, hid.ErrUnknownReport)
+}
+
+type entrySort []Entry
+
+func (s entrySort) Len() int           { return len(s) }
+func (s entrySort) Less(i, j int) bool { return s[i].Serial < s[j].Serial }
+func (s entrySort) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+type deviceStatsSort []DeviceStats
+
+func (s deviceStatsSort) Len() int           { return len(s) }
+func (s deviceStatsSort) Less(i, j int) bool { return s[i].Serial < s[j].Serial }
+func (s deviceStatsSort) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
 type InputLenSort []*hid.DeviceInfo
 
 func (s InputLenSort) Len() int           { return len(s) }
@@ -0,0 +1,420 @@
+package ds4util
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tajtiattila/hid/ds4"
+)
+
+func newTestManager(b *fakeBackend, h ConnectHandler) *DeviceManager {
+	m := NewDeviceManager(h, nil)
+	m.Backend = b
+	m.PollInterval = time.Millisecond
+	m.BatteryHold = 0
+	return m
+}
+
+func nextEvent(t *testing.T, m *DeviceManager) Event {
+	t.Helper()
+	select {
+	case e, ok := <-m.Event():
+		if !ok {
+			t.Fatal("event channel closed")
+		}
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+	panic("unreachable")
+}
+
+func TestDeviceManagerRun(t *testing.T) {
+	b := newFakeBackend()
+	usb := newFakeConn("usb", "01:02:03:04:05:06", false)
+	bt := newFakeConn("bt", "0a:0b:0c:0d:0e:0f", true)
+	b.plug(usb)
+	b.plug(bt)
+
+	h := new(fakeHandler)
+	m := newTestManager(b, h)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runerr := make(chan error)
+	go func() { runerr <- m.Run(ctx) }()
+
+	seen := make(map[string]int)
+	for i := 0; i < 2; i++ {
+		e := nextEvent(t, m)
+		if e.Removed {
+			t.Fatalf("unexpected removal %v", e.Entry.String())
+		}
+		seen[e.Serial] = e.Conn
+	}
+	if seen[usb.di.Attr.SerialNo] != ConnUSB || seen[bt.di.Attr.SerialNo] != ConnBT {
+		t.Fatalf("got connections %v", seen)
+	}
+	if n := len(m.Entries()); n != 2 {
+		t.Fatalf("got %d entries, want 2", n)
+	}
+
+	cancel()
+	if err := <-runerr; err != context.Canceled {
+		t.Fatalf("Run returned %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if e := nextEvent(t, m); !e.Removed {
+			t.Fatalf("want removal, got %v", e)
+		}
+	}
+	if _, ok := <-m.Event(); ok {
+		t.Fatal("event channel not closed")
+	}
+
+	if n := h.closed(); n != 2 {
+		t.Errorf("%d handlers closed, want 2", n)
+	}
+	if !usb.isClosed() || !bt.isClosed() {
+		t.Error("devices not closed")
+	}
+	if !bt.disconnected {
+		t.Error("bluetooth radio not disconnected")
+	}
+	if len(m.Entries()) != 0 {
+		t.Error("entries left after Run")
+	}
+}
+
+func TestDeviceManagerUnplug(t *testing.T) {
+	b := newFakeBackend()
+	c := newFakeConn("usb", "01:02:03:04:05:06", false)
+	b.plug(c)
+
+	h := new(fakeHandler)
+	m := newTestManager(b, h)
+	go m.Run(context.Background())
+	defer m.Close()
+
+	if e := nextEvent(t, m); e.Removed {
+		t.Fatal("want connect event")
+	}
+
+	c.setBattery(0x15)
+	e := nextEvent(t, m)
+	if e.Removed || e.Battery != 0x15 {
+		t.Fatalf("want battery event, got %+v", e)
+	}
+
+	b.unplug(c)
+	if e := nextEvent(t, m); !e.Removed {
+		t.Fatal("want removal event")
+	}
+	if !c.isClosed() || h.closed() != 1 {
+		t.Fatal("device or handler not closed after unplug")
+	}
+
+	// plug in again
+	c = newFakeConn("usb", "01:02:03:04:05:06", false)
+	b.plug(c)
+	if e := nextEvent(t, m); e.Removed {
+		t.Fatal("want connect event after replug")
+	}
+}
+
+func TestDeviceManagerHandlerError(t *testing.T) {
+	b := newFakeBackend()
+	c := newFakeConn("bt", "01:02:03:04:05:06", true)
+	b.plug(c)
+
+	h := &fakeHandler{err: errors.New("handler failed")}
+	m := newTestManager(b, h)
+	go m.Run(context.Background())
+	defer m.Close()
+
+	if e := nextEvent(t, m); e.Removed {
+		t.Fatal("want connect event")
+	}
+	if e := nextEvent(t, m); !e.Removed {
+		t.Fatal("want removal event")
+	}
+	if c.disconnected {
+		t.Error("radio disconnected on handler error")
+	}
+}
+
+func TestDeviceManagerNoEventReader(t *testing.T) {
+	b := newFakeBackend()
+	c := newFakeConn("usb", "01:02:03:04:05:06", false)
+	b.plug(c)
+
+	m := newTestManager(b, new(fakeHandler))
+	go m.Run(context.Background())
+
+	// generate more events than the queue holds
+	for i := 0; i < 2*eventQueueLen; i++ {
+		c.setBattery(byte(i))
+		c.waitReads(2)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		m.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close blocked")
+	}
+}
+
+func TestDeviceManagerTransportSwitch(t *testing.T) {
+	const sno = "01:02:03:04:05:06"
+	b := newFakeBackend()
+	bt := newFakeConn("bt", sno, true)
+	b.plug(bt)
+
+	h := new(fakeHandler)
+	m := newTestManager(b, h)
+	m.Transport = PreferUSB
+	go m.Run(context.Background())
+	defer m.Close()
+
+	if e := nextEvent(t, m); e.Removed || e.Conn != ConnBT {
+		t.Fatalf("want bluetooth connect event, got %+v", e)
+	}
+
+	// attach cable
+	usb := newFakeConn("usb", sno, false)
+	b.plug(usb)
+	e := nextEvent(t, m)
+	if !e.TransportChanged || e.Conn != ConnUSB || e.Name != "usb" {
+		t.Fatalf("want switch to USB, got %+v", e)
+	}
+	if !bt.isClosed() {
+		t.Error("bluetooth device not closed after switch")
+	}
+
+	// remove cable
+	b.unplug(usb)
+	e = nextEvent(t, m)
+	if !e.TransportChanged || e.Conn != ConnBT {
+		t.Fatalf("want switch to bluetooth, got %+v", e)
+	}
+	if !usb.isClosed() {
+		t.Error("USB device not closed after unplug")
+	}
+
+	h.mu.Lock()
+	nconn, nclosed := len(h.conn), h.nclosed
+	h.mu.Unlock()
+	if nconn != 1 || nclosed != 0 {
+		t.Errorf("handler connected %d and closed %d times, want 1 and 0", nconn, nclosed)
+	}
+	if n := h.transports(); n != 2 {
+		t.Errorf("TransportChanged called %d times, want 2", n)
+	}
+	if v := m.Entries(); len(v) != 1 || v[0].Conn != ConnBT {
+		t.Errorf("got entries %v", v)
+	}
+}
+
+func TestDeviceManagerKeepTransport(t *testing.T) {
+	const sno = "01:02:03:04:05:06"
+	b := newFakeBackend()
+	usb := newFakeConn("usb", sno, false)
+	b.plug(usb)
+
+	h := new(fakeHandler)
+	m := newTestManager(b, h)
+	go m.Run(context.Background())
+	defer m.Close()
+
+	if e := nextEvent(t, m); e.Conn != ConnUSB {
+		t.Fatalf("want USB connect event, got %+v", e)
+	}
+
+	// bluetooth available, but USB is kept
+	bt := newFakeConn("bt", sno, true)
+	b.plug(bt)
+	b.waitPolls(2)
+	if n := h.transports(); n != 0 || bt.isClosed() {
+		t.Fatalf("switched transport")
+	}
+
+	// switch to bluetooth only when USB is lost
+	b.unplug(usb)
+	if e := nextEvent(t, m); !e.TransportChanged || e.Conn != ConnBT {
+		t.Fatalf("want switch to bluetooth, got %+v", e)
+	}
+}
+
+func TestDeviceManagerStats(t *testing.T) {
+	const sno = "01:02:03:04:05:06"
+	b := newFakeBackend()
+	b.plug(newFakeConn("usb", sno, false))
+
+	m := newTestManager(b, new(fakeHandler))
+	go m.Run(context.Background())
+	defer m.Close()
+
+	// states read while opening the device are counted
+	nextEvent(t, m)
+
+	v := m.Stats()
+	if len(v) != 1 || v[0].Serial != sno || v[0].Reports == 0 {
+		t.Fatalf("got stats %+v", v)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMetrics(&buf, v); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# TYPE ds4_reports_total counter\n",
+		`ds4_reports_total{serial="01:02:03:04:05:06",conn="USB"} `,
+		`ds4_report_delay_seconds_bucket{serial="01:02:03:04:05:06",conn="USB",le="+Inf"} `,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("metrics missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestDeviceManagerLowBattery(t *testing.T) {
+	b := newFakeBackend()
+	c := newFakeConn("bt", "01:02:03:04:05:06", true)
+	c.setBattery(0x15) // charging
+	b.plug(c)
+
+	h := new(fakeHandler)
+	m := newTestManager(b, h)
+	clk := &fakeClock{t: testBase}
+	m.now = clk.now
+	m.LowBattery = 25
+	m.LowBatteryFlash = 50 * time.Millisecond
+	go m.Run(context.Background())
+	defer m.Close()
+
+	if e := nextEvent(t, m); !e.Battery.Charging() || e.LowBattery {
+		t.Fatalf("want charging connect event, got %+v", e)
+	}
+	d := h.device()
+	d.SetColor(ds4.Color{B: 0xff})
+
+	c.setBattery(0x03) // unplugged, 35%
+	if e := nextEvent(t, m); e.LowBattery || e.Battery.Percent() != 35 {
+		t.Fatalf("want battery event, got %+v", e)
+	}
+
+	c.setBattery(0x02) // 25%
+	if e := nextEvent(t, m); !e.LowBattery {
+		t.Fatalf("want low battery event, got %+v", e)
+	}
+	if o := d.Output(); o.Led != m.LowBatteryColor || o.On == 0 {
+		t.Errorf("lightbar not flashing, output %+v", o)
+	}
+
+	c.setBattery(0x01) // still low
+	if e := nextEvent(t, m); e.LowBattery {
+		t.Fatalf("repeated low battery event")
+	}
+
+	clk.advance(100 * time.Millisecond)
+	c.waitReads(2)
+	if o := d.Output(); o.Led != (ds4.Color{B: 0xff}) || o.On != 0 {
+		t.Errorf("output not restored, got %+v", o)
+	}
+}
+
+func TestDeviceManagerSlots(t *testing.T) {
+	b := newFakeBackend()
+	c1 := newFakeConn("usb", "01:02:03:04:05:06", false)
+	c2 := newFakeConn("bt", "0a:0b:0c:0d:0e:0f", true)
+	b.plug(c1)
+
+	h := new(fakeHandler)
+	m := newTestManager(b, h)
+	m.Slots = NewSlots(4)
+	go m.Run(context.Background())
+	defer m.Close()
+
+	if e := nextEvent(t, m); e.Slot != 1 {
+		t.Fatalf("first controller got slot %d", e.Slot)
+	}
+	d1 := h.device()
+	if d1.Output().Led != PlayerColors[0] {
+		t.Errorf("slot colour not set")
+	}
+
+	b.plug(c2)
+	if e := nextEvent(t, m); e.Slot != 2 {
+		t.Fatalf("second controller got slot %d", e.Slot)
+	}
+	d2 := h.device()
+
+	if err := m.SwapSlots(1, 2); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		e := nextEvent(t, m)
+		if !e.SlotChanged || e.Slot != m.Slots.Slot(e.Serial) {
+			t.Fatalf("want slot change event, got %+v", e)
+		}
+	}
+	if d1.Output().Led != PlayerColors[1] || d2.Output().Led != PlayerColors[0] {
+		t.Errorf("slot colours not swapped")
+	}
+
+	// reconnect keeps slot
+	b.unplug(c1)
+	if e := nextEvent(t, m); !e.Removed || e.Slot != 2 {
+		t.Fatalf("want removal from slot 2, got %+v", e)
+	}
+	b.plug(newFakeConn("usb", "01:02:03:04:05:06", false))
+	if e := nextEvent(t, m); e.Removed || e.Slot != 2 {
+		t.Fatalf("want reconnect to slot 2, got %+v", e)
+	}
+}
+
+func TestDeviceManagerRegistry(t *testing.T) {
+	const sno = "01:02:03:04:05:06"
+	r, err := OpenRegistry(filepath.Join(t.TempDir(), "ds4.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := Settings{
+		Name:        "left",
+		Color:       &ds4.Color{G: 0xff},
+		Calibration: ds4.Calibration{LX: 2},
+		Profile:     "racing",
+	}
+	r.Set(sno, st)
+
+	b := newFakeBackend()
+	b.plug(newFakeConn("usb", sno, false))
+
+	h := new(fakeHandler)
+	m := newTestManager(b, h)
+	m.Slots = NewSlots(4)
+	m.Registry = r
+	go m.Run(context.Background())
+	defer m.Close()
+
+	e := nextEvent(t, m)
+	if e.Settings == nil || e.Settings.Profile != "racing" || e.DisplayName() != "left" {
+		t.Fatalf("settings not in entry: %+v", e)
+	}
+	h.mu.Lock()
+	out, cal := h.out, h.cal
+	h.mu.Unlock()
+	if out.Led != *st.Color || cal != st.Calibration {
+		t.Errorf("settings not applied before Connect: %+v %+v", out, cal)
+	}
+}
@@ -0,0 +1,140 @@
+package ds4util
+
+import (
+	"math"
+	"time"
+)
+
+// OneEuro is the 1€ adaptive low pass filter.
+//
+// It smooths slow movements strongly to remove jitter, and lowers
+// smoothing for fast movements to reduce lag. The cutoff frequency
+// is MinCutoff plus Beta times the filtered speed of the input.
+//
+// See https://gery.casiez.net/1euro/
+type OneEuro struct {
+	// MinCutoff is the minimum cutoff frequency in Hz.
+	// Lower values reduce jitter.
+	MinCutoff float64
+
+	// Beta is the speed coefficient. Higher values reduce lag.
+	Beta float64
+
+	// DCutoff is the cutoff frequency in Hz used for filtering the speed.
+	// 1 Hz is used if DCutoff is zero.
+	DCutoff float64
+
+	init bool
+	t    time.Time
+	x    []float64 // filtered values
+	dx   []float64 // filtered speeds
+}
+
+func (f *OneEuro) Filter(t time.Time, v []float64) []float64 {
+	if !f.init || len(f.x) != len(v) {
+		f.init, f.t = true, t
+		f.x = append(f.x[:0], v...)
+		f.dx = append(f.dx[:0], make([]float64, len(v))...)
+		return f.x
+	}
+	dt := t.Sub(f.t).Seconds()
+	if dt <= 0 {
+		return f.x
+	}
+	f.t = t
+
+	dc := f.DCutoff
+	if dc <= 0 {
+		dc = 1
+	}
+	ad := smoothing(dc, dt)
+	for i := range v {
+		d := (v[i] - f.x[i]) / dt
+		f.dx[i] += ad * (d - f.dx[i])
+		c := f.MinCutoff + f.Beta*math.Abs(f.dx[i])
+		f.x[i] += smoothing(c, dt) * (v[i] - f.x[i])
+	}
+	return f.x
+}
+
+func (f *OneEuro) Reset() {
+	f.init = false
+}
+
+// smoothing returns the exponential smoothing factor
+// for the cutoff frequency in Hz and sampling period dt in seconds.
+func smoothing(cutoff, dt float64) float64 {
+	tau := 1 / (2 * math.Pi * cutoff)
+	return 1 / (1 + tau/dt)
+}
+
+// Kalman is a constant velocity Kalman filter
+// filtering each element of the input independently.
+type Kalman struct {
+	// ProcessNoise is the variance of the acceleration
+	// of the input per second squared.
+	// Higher values follow changes faster.
+	ProcessNoise float64
+
+	// MeasurementNoise is the variance of the input noise.
+	MeasurementNoise float64
+
+	init bool
+	t    time.Time
+	s    []kalmanState
+	out  []float64
+}
+
+type kalmanState struct {
+	x, v float64       // position and velocity
+	p    [2][2]float64 // covariance
+}
+
+func (f *Kalman) Filter(t time.Time, v []float64) []float64 {
+	r := f.MeasurementNoise
+	if !f.init || len(f.s) != len(v) {
+		f.init, f.t = true, t
+		f.s = make([]kalmanState, len(v))
+		for i, z := range v {
+			f.s[i] = kalmanState{x: z, p: [2][2]float64{{r, 0}, {0, r}}}
+		}
+		f.out = append(f.out[:0], v...)
+		return f.out
+	}
+	dt := t.Sub(f.t).Seconds()
+	if dt < 0 {
+		dt = 0
+	}
+	f.t = t
+
+	q := f.ProcessNoise
+	dt2 := dt * dt
+	q00, q01, q11 := q*dt2*dt2/4, q*dt2*dt/2, q*dt2
+	for i, z := range v {
+		s := &f.s[i]
+
+		// predict
+		s.x += s.v * dt
+		p := s.p
+		p00 := p[0][0] + dt*(p[1][0]+p[0][1]) + dt2*p[1][1] + q00
+		p01 := p[0][1] + dt*p[1][1] + q01
+		p10 := p[1][0] + dt*p[1][1] + q01
+		p11 := p[1][1] + q11
+
+		// update
+		y := z - s.x
+		k0, k1 := p00/(p00+r), p10/(p00+r)
+		s.x += k0 * y
+		s.v += k1 * y
+		s.p = [2][2]float64{
+			{(1 - k0) * p00, (1 - k0) * p01},
+			{p10 - k1*p00, p11 - k1*p01},
+		}
+		f.out[i] = s.x
+	}
+	return f.out
+}
+
+func (f *Kalman) Reset() {
+	f.init = false
+}
@@ -0,0 +1,105 @@
+package ds4util
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// eventQueueLen is the number of undelivered events kept
+	eventQueueLen = 64
+
+	// eventDrainTimeout is how long queued events are kept after
+	// the manager is stopped.
+	eventDrainTimeout = time.Second
+)
+
+// eventQueue delivers events without blocking the sender.
+type eventQueue struct {
+	out chan Event
+
+	mu     sync.Mutex
+	q      []Event
+	head   uint64 // sequence number of q[0]
+	max    int
+	closed bool
+	wake   chan struct{}
+}
+
+func newEventQueue(max int) *eventQueue {
+	return &eventQueue{
+		out:  make(chan Event),
+		max:  max,
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// push queues e, dropping the oldest event if the queue is full.
+func (q *eventQueue) push(e Event) {
+	q.mu.Lock()
+	if len(q.q) == q.max {
+		q.pop()
+	}
+	q.q = append(q.q, e)
+	q.mu.Unlock()
+	q.signal()
+}
+
+// close makes pump close out after the queued events are delivered,
+// or after eventDrainTimeout.
+func (q *eventQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.signal()
+}
+
+func (q *eventQueue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// pump delivers events to out until close is called.
+func (q *eventQueue) pump() {
+	defer close(q.out)
+	var drain <-chan time.Time
+	for {
+		q.mu.Lock()
+		if q.closed && drain == nil {
+			drain = time.After(eventDrainTimeout)
+		}
+		if len(q.q) == 0 {
+			closed := q.closed
+			q.mu.Unlock()
+			if closed {
+				return
+			}
+			<-q.wake
+			continue
+		}
+		e, seq := q.q[0], q.head
+		q.mu.Unlock()
+
+		select {
+		case q.out <- e:
+			q.mu.Lock()
+			if q.head == seq {
+				q.pop()
+			}
+			q.mu.Unlock()
+		case <-q.wake:
+			// queue changed
+		case <-drain:
+			return
+		}
+	}
+}
+
+// pop removes the oldest event. q.mu must be held.
+func (q *eventQueue) pop() {
+	copy(q.q, q.q[1:])
+	q.q = q.q[:len(q.q)-1]
+	q.head++
+}
@@ -0,0 +1,304 @@
+package ds4util
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/tajtiattila/hid"
+	"github.com/tajtiattila/hid/ds4"
+)
+
+// fakeConn is a fake DS4 HID connection implementing ds4.Conn.
+// Reads return the current input report without waiting.
+type fakeConn struct {
+	di *hid.DeviceInfo
+
+	gone chan struct{} // closed on unplug
+
+	mu           sync.Mutex
+	read         *sync.Cond // signalled after reads
+	nread        int        // number of reports read
+	report       []byte     // current input report
+	closed       bool
+	disconnected bool
+}
+
+func newFakeConn(name, sno string, bt bool) *fakeConn {
+	di := &hid.DeviceInfo{
+		Name: name,
+		Attr: &hid.Attr{
+			VendorId:  0x54C,
+			ProductId: 0x5C4,
+			SerialNo:  sno,
+			Bluetooth: bt,
+		},
+		Caps: &hid.Caps{InputLen: 64, OutputLen: 32},
+	}
+	r := make([]byte, 64)
+	r[0] = 0x01
+	if bt {
+		di.Caps.InputLen, di.Caps.OutputLen = 547, 78
+		r = make([]byte, 547)
+		r[0] = 0x11
+	}
+	c := &fakeConn{di: di, gone: make(chan struct{}), report: r}
+	c.read = sync.NewCond(&c.mu)
+	return c
+}
+
+// waitReads waits until n more reports are read from c.
+// A state is fully handled by the manager when the next one is read,
+// so waitReads(2) after changing the report waits until
+// the change was handled.
+func (c *fakeConn) waitReads(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for want := c.nread + n; c.nread < want; {
+		c.read.Wait()
+	}
+}
+
+// setBattery sets the battery byte of subsequent reports.
+func (c *fakeConn) setBattery(b byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.report[0] == 0x11 {
+		c.report[32] = b
+	} else {
+		c.report[30] = b
+	}
+}
+
+// setMotion sets the gyroscope and accelerometer values
+// of subsequent reports.
+func (c *fakeConn) setMotion(gyro, acc [3]int16) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p := c.report[13:]
+	if c.report[0] == 0x11 {
+		p = c.report[15:]
+	}
+	for i, v := range append(gyro[:], acc[:]...) {
+		binary.LittleEndian.PutUint16(p[2*i:], uint16(v))
+	}
+}
+
+func (c *fakeConn) unplug() { close(c.gone) }
+
+func (c *fakeConn) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+func (c *fakeConn) Name() string                         { return c.di.Name }
+func (c *fakeConn) DeviceInfo() (*hid.DeviceInfo, error) { return c.di, nil }
+
+func (c *fakeConn) Read(p []byte) (int, error) {
+	return c.ReadContext(context.Background(), p)
+}
+
+func (c *fakeConn) ReadContext(ctx context.Context, p []byte) (int, error) {
+	select {
+	case <-c.gone:
+		return 0, hid.ErrDeviceGone
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nread++
+	c.read.Broadcast()
+	return copy(p, c.report), nil
+}
+
+func (c *fakeConn) Write(p []byte) (int, error) {
+	select {
+	case <-c.gone:
+		return 0, hid.ErrDeviceGone
+	default:
+	}
+	return len(p), nil
+}
+
+func (c *fakeConn) WriteContext(ctx context.Context, p []byte) (int, error) {
+	return c.Write(p)
+}
+
+func (c *fakeConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *fakeConn) SetTimeout(time.Duration)           {}
+func (c *fakeConn) SetDeadline(t time.Time) error      { return nil }
+func (c *fakeConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *fakeConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func (c *fakeConn) SetOutputReport(p []byte) error {
+	_, err := c.Write(p)
+	return err
+}
+
+func (c *fakeConn) GetFeatureReport(p []byte) error { return hid.ErrNotSupported }
+func (c *fakeConn) SetFeatureReport(p []byte) error { return hid.ErrNotSupported }
+
+func (c *fakeConn) DisconnectRadio() error {
+	if !c.di.Attr.Bluetooth {
+		return hid.ErrNotBluetooth
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disconnected = true
+	return nil
+}
+
+// fakeBackend is a Backend with plugged in fakeConns.
+type fakeBackend struct {
+	mu    sync.Mutex
+	poll  *sync.Cond // signalled when devices are listed
+	npoll int        // number of Devices calls
+	dev   []*fakeConn
+}
+
+func newFakeBackend() *fakeBackend {
+	b := new(fakeBackend)
+	b.poll = sync.NewCond(&b.mu)
+	return b
+}
+
+// waitPolls waits until the devices are listed n more times.
+// The manager has acted on a device list when it lists the devices again,
+// so waitPolls(2) after plugging a device waits until
+// the manager has seen it.
+func (b *fakeBackend) waitPolls(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for want := b.npoll + n; b.npoll < want; {
+		b.poll.Wait()
+	}
+}
+
+func (b *fakeBackend) plug(c *fakeConn) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dev = append(b.dev, c)
+}
+
+func (b *fakeBackend) unplug(c *fakeConn) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, x := range b.dev {
+		if x == c {
+			b.dev = append(b.dev[:i], b.dev[i+1:]...)
+			break
+		}
+	}
+	c.unplug()
+}
+
+func (b *fakeBackend) Devices() ([]*hid.DeviceInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.npoll++
+	b.poll.Broadcast()
+	var v []*hid.DeviceInfo
+	for _, c := range b.dev {
+		v = append(v, c.di)
+	}
+	return v, nil
+}
+
+func (b *fakeBackend) Open(di *hid.DeviceInfo) (*ds4.Device, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, c := range b.dev {
+		if c.di == di {
+			return ds4.NewDevice(c)
+		}
+	}
+	return nil, hid.ErrDeviceGone
+}
+
+// fakeHandler records handler calls.
+type fakeHandler struct {
+	mu      sync.Mutex
+	dev     *ds4.Device // last connected device
+	out     ds4.Output  // output of dev when connected
+	cal     ds4.Calibration
+	conn    []Entry
+	nstate  int
+	nclosed int
+	ntrans  []Entry // TransportChanged calls
+	err     error   // returned from State if set
+}
+
+func (h *fakeHandler) Connect(d *ds4.Device, e Entry) (StateHandler, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.dev, h.out, h.cal = d, d.Output(), d.Calibration()
+	h.conn = append(h.conn, e)
+	return h, nil
+}
+
+func (h *fakeHandler) State(s *ds4.State) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nstate++
+	return h.err
+}
+
+func (h *fakeHandler) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nclosed++
+	return nil
+}
+
+func (h *fakeHandler) TransportChanged(d *ds4.Device, e Entry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.dev = d
+	h.ntrans = append(h.ntrans, e)
+}
+
+func (h *fakeHandler) device() *ds4.Device {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.dev
+}
+
+func (h *fakeHandler) transports() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.ntrans)
+}
+
+func (h *fakeHandler) closed() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.nclosed
+}
+
+// fakeClock is a manually advanced clock.
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
@@ -1,10 +1,23 @@
 package ds4util
 
+import (
+	"math"
+	"time"
+)
+
 // Filter filters its input.
+//
+// New code should use VecFilter. Filters can be adapted
+// between the interfaces using IntFilter and FloatFilter.
 type Filter interface {
 	Filter([]int) []int
 }
 
+// Resetter is implemented by filters having state that can be reset.
+type Resetter interface {
+	Reset()
+}
+
 // Input is a filter that returns its input as output.
 var Input Filter = &input{}
 
@@ -18,6 +31,9 @@ type combined struct {
 	v []Filter
 }
 
+// Combine returns a filter applying the filters f in order.
+// The returned filter implements Resetter, and resets
+// all filters in f that implement Resetter.
 func Combine(f ...Filter) Filter {
 	return &combined{f}
 }
@@ -28,3 +44,198 @@ func (f *combined) Filter(v []int) []int {
 	}
 	return v
 }
+
+func (f *combined) Reset() {
+	for _, x := range f.v {
+		if r, ok := x.(Resetter); ok {
+			r.Reset()
+		}
+	}
+}
+
+// VecFilter filters vectors sampled at explicit times.
+//
+// Sample times should come from the reports, see ReportClock,
+// so that filters can be driven deterministically.
+type VecFilter interface {
+	// Filter filters v sampled at t, and returns the filtered vector.
+	// The result is valid until the next call to Filter or Reset.
+	Filter(t time.Time, v []float64) []float64
+
+	// Reset clears the filter state.
+	Reset()
+}
+
+// Identity is a VecFilter that returns its input as output.
+var Identity VecFilter = identity{}
+
+type identity struct{}
+
+func (identity) Filter(t time.Time, v []float64) []float64 { return v }
+func (identity) Reset()                                    {}
+
+type combinedVec []VecFilter
+
+// CombineVec returns a VecFilter applying the filters f in order.
+// Reset resets all filters in f.
+func CombineVec(f ...VecFilter) VecFilter {
+	return combinedVec(f)
+}
+
+func (f combinedVec) Filter(t time.Time, v []float64) []float64 {
+	for _, x := range f {
+		v = x.Filter(t, v)
+	}
+	return v
+}
+
+func (f combinedVec) Reset() {
+	for _, x := range f {
+		x.Reset()
+	}
+}
+
+// LowPass is an exponential low pass filter.
+// It starts from the first sample.
+type LowPass struct {
+	// Alpha is the smoothing factor between 0 and 1,
+	// used when Tau is zero. Smaller values smooth more.
+	Alpha float64
+
+	// Tau is the time constant. If set, the smoothing factor
+	// is computed from the time between samples.
+	Tau time.Duration
+
+	init bool
+	t    time.Time
+	v    []float64
+}
+
+func (f *LowPass) Filter(t time.Time, v []float64) []float64 {
+	if !f.init || len(f.v) != len(v) {
+		f.init, f.t = true, t
+		f.v = append(f.v[:0], v...)
+		return f.v
+	}
+	a := f.Alpha
+	if f.Tau > 0 {
+		dt := t.Sub(f.t).Seconds()
+		if dt < 0 {
+			dt = 0
+		}
+		a = 1 - math.Exp(-dt/f.Tau.Seconds())
+	}
+	f.t = t
+	for i := range v {
+		f.v[i] += a * (v[i] - f.v[i])
+	}
+	return f.v
+}
+
+func (f *LowPass) Reset() {
+	f.init = false
+}
+
+// MovingAverage averages the samples within the last D.
+type MovingAverage struct {
+	// D is the duration of samples averaged.
+	D time.Duration
+
+	// N limits the number of samples averaged if positive.
+	N int
+
+	q   []timedSample
+	out []float64
+}
+
+type timedSample struct {
+	t time.Time
+	v []float64
+}
+
+func (a *MovingAverage) Filter(t time.Time, v []float64) []float64 {
+	a.q = append(a.q, timedSample{t, append([]float64(nil), v...)})
+	t0 := t.Add(-a.D)
+	for len(a.q) > 1 && (a.q[0].t.Before(t0) || (a.N > 0 && len(a.q) > a.N)) {
+		a.q = a.q[1:]
+	}
+
+	a.out = append(a.out[:0], make([]float64, len(v))...)
+	for _, s := range a.q {
+		for i := range a.out {
+			if i < len(s.v) {
+				a.out[i] += s.v[i]
+			}
+		}
+	}
+	for i := range a.out {
+		a.out[i] /= float64(len(a.q))
+	}
+	return a.out
+}
+
+func (a *MovingAverage) Reset() {
+	a.q = a.q[:0]
+}
+
+// IntFilter adapts the VecFilter f to the Filter interface.
+// Samples are timed using time.Now, and results are rounded.
+// The returned filter implements Resetter.
+func IntFilter(f VecFilter) Filter {
+	return &intFilter{f: f}
+}
+
+type intFilter struct {
+	f  VecFilter
+	fv []float64
+	iv []int
+}
+
+func (a *intFilter) Filter(v []int) []int {
+	a.fv = a.fv[:0]
+	for _, x := range v {
+		a.fv = append(a.fv, float64(x))
+	}
+	r := a.f.Filter(time.Now(), a.fv)
+	a.iv = a.iv[:0]
+	for _, x := range r {
+		a.iv = append(a.iv, int(math.Round(x)))
+	}
+	return a.iv
+}
+
+func (a *intFilter) Reset() {
+	a.f.Reset()
+}
+
+// FloatFilter adapts the Filter f to the VecFilter interface.
+// Sample times are ignored, and the input is rounded to integers.
+// Reset resets f if it implements Resetter.
+func FloatFilter(f Filter) VecFilter {
+	return &floatFilter{f: f}
+}
+
+type floatFilter struct {
+	f  Filter
+	iv []int
+	fv []float64
+}
+
+func (a *floatFilter) Filter(t time.Time, v []float64) []float64 {
+	a.iv = a.iv[:0]
+	for _, x := range v {
+		a.iv = append(a.iv, int(math.Round(x)))
+	}
+	r := a.f.Filter(a.iv)
+	a.fv = a.fv[:0]
+	for _, x := range r {
+		a.fv = append(a.fv, float64(x))
+	}
+	return a.fv
+}
+
+func (a *floatFilter) Reset() {
+	if r, ok := a.f.(Resetter); ok {
+		r.Reset()
+	}
+}
@@ -0,0 +1,178 @@
+package ds4util
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/tajtiattila/hid/ds4"
+)
+
+var t0 = time.Unix(1000, 0)
+
+func ms(n int) time.Time {
+	return t0.Add(time.Duration(n) * time.Millisecond)
+}
+
+func TestLowPass(t *testing.T) {
+	f := &LowPass{Alpha: 0.5}
+	f.Filter(ms(0), []float64{10})
+	if v := f.Filter(ms(1), []float64{20}); v[0] != 15 {
+		t.Errorf("alpha: got %v", v)
+	}
+
+	f = &LowPass{Tau: 10 * time.Millisecond}
+	f.Filter(ms(0), []float64{0})
+	v := f.Filter(ms(10), []float64{1})
+	if want := 1 - math.Exp(-1); math.Abs(v[0]-want) > 1e-9 {
+		t.Errorf("tau: got %v, want %v", v[0], want)
+	}
+
+	f.Reset()
+	if v := f.Filter(ms(20), []float64{5}); v[0] != 5 {
+		t.Errorf("after reset: got %v", v)
+	}
+}
+
+func TestMovingAverage(t *testing.T) {
+	f := &MovingAverage{D: 10 * time.Millisecond}
+	steps := []struct {
+		ms   int
+		in   float64
+		want float64
+	}{
+		{0, 2, 2},
+		{4, 4, 3},
+		{8, 6, 4},
+		{12, 8, 6}, // first sample dropped
+		{40, 1, 1}, // all but last dropped
+	}
+	for _, s := range steps {
+		if v := f.Filter(ms(s.ms), []float64{s.in}); v[0] != s.want {
+			t.Errorf("%dms: got %v, want %v", s.ms, v[0], s.want)
+		}
+	}
+
+	f = &MovingAverage{D: time.Second, N: 2}
+	f.Filter(ms(0), []float64{1})
+	f.Filter(ms(1), []float64{2})
+	if v := f.Filter(ms(2), []float64{3}); v[0] != 2.5 {
+		t.Errorf("N limit: got %v", v[0])
+	}
+}
+
+func TestCombineVecReset(t *testing.T) {
+	a := &LowPass{Alpha: 0.5}
+	m := &MovingAverage{D: time.Second}
+	f := CombineVec(a, m)
+	f.Filter(ms(0), []float64{0})
+	f.Filter(ms(1), []float64{8})
+	f.Reset()
+	if v := f.Filter(ms(2), []float64{4}); v[0] != 4 {
+		t.Errorf("got %v after reset", v[0])
+	}
+}
+
+func TestCombineReset(t *testing.T) {
+	a := NewAlphaFilter(1, 0.5)
+	m := NewMovAvg(1, 10, time.Second)
+	f := Combine(a, m)
+	f.Filter([]int{100})
+	f.(Resetter).Reset()
+	if a.V[0] != 0 || m.N() != 0 {
+		t.Errorf("stages not reset: %v %d", a.V, m.N())
+	}
+}
+
+func TestFilterAdapters(t *testing.T) {
+	f := IntFilter(&LowPass{Alpha: 0.5})
+	f.Filter([]int{0, 10})
+	if v := f.Filter([]int{5, 20}); v[0] != 3 || v[1] != 15 {
+		t.Errorf("IntFilter: got %v", v)
+	}
+
+	g := FloatFilter(NewAlphaFilter(1, 0.5))
+	if v := g.Filter(ms(0), []float64{10.4}); v[0] != 5 {
+		t.Errorf("FloatFilter: got %v", v)
+	}
+	g.Reset()
+	if v := g.Filter(ms(1), []float64{2}); v[0] != 1 {
+		t.Errorf("FloatFilter after reset: got %v", v)
+	}
+}
+
+func TestReportClock(t *testing.T) {
+	c := ReportClock{Base: t0}
+	for _, tt := range []struct {
+		ts   uint16
+		want time.Duration
+	}{
+		{65000, 0},
+		{65300, 1600 * time.Microsecond},
+		{64, 3200 * time.Microsecond}, // wrapped
+	} {
+		if got := c.Time(&ds4.State{Timestamp: tt.ts}).Sub(t0); got != tt.want {
+			t.Errorf("%d: got %v, want %v", tt.ts, got, tt.want)
+		}
+	}
+}
+
+func TestOneEuro(t *testing.T) {
+	// constant input is passed unchanged
+	f := &OneEuro{MinCutoff: 1, Beta: 0.01}
+	for i := 0; i < 10; i++ {
+		if v := f.Filter(ms(4*i), []float64{3}); v[0] != 3 {
+			t.Fatalf("constant: got %v", v)
+		}
+	}
+
+	// higher beta follows a step faster
+	step := func(beta float64) float64 {
+		f := &OneEuro{MinCutoff: 1, Beta: beta}
+		f.Filter(ms(0), []float64{0})
+		var v []float64
+		for i := 1; i <= 5; i++ {
+			v = f.Filter(ms(4*i), []float64{100})
+		}
+		return v[0]
+	}
+	slow, fast := step(0), step(0.1)
+	if !(0 < slow && slow < fast && fast < 100) {
+		t.Errorf("step response: beta=0 %v, beta=0.1 %v", slow, fast)
+	}
+
+	// beta zero is a low pass filter with MinCutoff
+	f = &OneEuro{MinCutoff: 10}
+	f.Filter(ms(0), []float64{0})
+	v := f.Filter(ms(10), []float64{1})
+	if want := smoothing(10, 0.01); math.Abs(v[0]-want) > 1e-9 {
+		t.Errorf("got %v, want %v", v[0], want)
+	}
+}
+
+func TestKalman(t *testing.T) {
+	// noisy constant converges
+	f := &Kalman{ProcessNoise: 1, MeasurementNoise: 4}
+	var v []float64
+	for i := 0; i < 200; i++ {
+		noise := float64(i%5 - 2)
+		v = f.Filter(ms(4*i), []float64{10 + noise})
+	}
+	if math.Abs(v[0]-10) > 0.5 {
+		t.Errorf("constant: got %v", v[0])
+	}
+
+	// ramp is tracked without steady state lag
+	f = &Kalman{ProcessNoise: 100, MeasurementNoise: 1}
+	for i := 0; i < 1500; i++ {
+		v = f.Filter(ms(4*i), []float64{float64(i)})
+	}
+	if math.Abs(v[0]-1499) > 0.01 {
+		t.Errorf("ramp: got %v, want 1499", v[0])
+	}
+
+	f.Reset()
+	if v := f.Filter(ms(0), []float64{-5}); v[0] != -5 {
+		t.Errorf("after reset: got %v", v[0])
+	}
+}
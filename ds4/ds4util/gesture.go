@@ -0,0 +1,451 @@
+package ds4util
+
+import (
+	"math"
+	"time"
+
+	"github.com/tajtiattila/hid/ds4"
+)
+
+// Touchpad size in touchpad units.
+const (
+	TouchpadWidth  = 1920
+	TouchpadHeight = 943
+)
+
+// GestureKind is the kind of a Gesture.
+type GestureKind int
+
+const (
+	// Tap is a short touch without movement.
+	Tap GestureKind = iota + 1
+
+	// DoubleTap is a tap shortly after another tap nearby.
+	// It follows the Tap event of the second tap.
+	DoubleTap
+
+	// LongPress is a touch held without movement.
+	LongPress
+
+	// Scroll is a two-finger movement.
+	// DX and DY hold the movement since the last event.
+	// Scrolling continues with Momentum set
+	// after the fingers are lifted.
+	Scroll
+
+	// Pinch is a two-finger zoom. Scale holds the finger distance
+	// relative to the last event.
+	Pinch
+
+	// Rotate is a two-finger rotation. Angle holds the
+	// clockwise rotation in radians since the last event.
+	Rotate
+
+	// EdgeSwipe is a swipe starting at an edge of the touchpad.
+	// Edge is the edge the swipe started from.
+	EdgeSwipe
+)
+
+var gestureKindStr = []string{"", "tap", "doubletap", "longpress", "scroll", "pinch", "rotate", "edgeswipe"}
+
+func (k GestureKind) String() string {
+	if k > 0 && int(k) < len(gestureKindStr) {
+		return gestureKindStr[k]
+	}
+	return "gesture?"
+}
+
+// Gesture is a recognised touchpad gesture.
+type Gesture struct {
+	Kind GestureKind
+
+	// Time is the report time of the gesture.
+	Time time.Time
+
+	// X and Y is the touch position,
+	// or the centre of the fingers for two-finger gestures.
+	X, Y int
+
+	// Fingers is the number of fingers in the gesture.
+	Fingers int
+
+	// DX and DY is the Scroll movement.
+	DX, DY float64
+
+	// Momentum is set for Scroll events after the fingers are lifted.
+	Momentum bool
+
+	// Scale is the Pinch scale.
+	Scale float64
+
+	// Angle is the Rotate angle.
+	Angle float64
+
+	// Edge is the EdgeSwipe edge: SwipeLeft, SwipeRight, SwipeUp or SwipeDown
+	// for the left, right, top or bottom edge.
+	Edge int
+}
+
+// GestureHandler receives recognised gestures.
+type GestureHandler interface {
+	Gesture(g *Gesture)
+}
+
+// GestureConfig holds the gesture recognition parameters.
+// Distances are in touchpad units.
+type GestureConfig struct {
+	// TapTime and TapDist are the longest duration
+	// and movement of a tap.
+	TapTime time.Duration
+	TapDist int
+
+	// DoubleTapTime is the longest time between double taps.
+	DoubleTapTime time.Duration
+
+	// LongPressTime is the duration of a long press.
+	LongPressTime time.Duration
+
+	// ScrollDist, PinchDist and RotateAngle are the movement, change in
+	// finger distance and rotation in radians needed to start scrolling,
+	// pinching and rotating.
+	ScrollDist  int
+	PinchDist   int
+	RotateAngle float64
+
+	// Friction is the exponential decay rate per second of the scroll
+	// momentum. MinMomentum is the speed in units per second
+	// below which momentum stops.
+	Friction    float64
+	MinMomentum float64
+
+	// EdgeWidth is the width of the edge areas starting edge swipes,
+	// and EdgeDist is the distance an edge swipe must travel inwards.
+	EdgeWidth int
+	EdgeDist  int
+}
+
+// DefaultGestureConfig is the default gesture configuration.
+var DefaultGestureConfig = GestureConfig{
+	TapTime:       200 * time.Millisecond,
+	TapDist:       40,
+	DoubleTapTime: 300 * time.Millisecond,
+	LongPressTime: 600 * time.Millisecond,
+	ScrollDist:    40,
+	PinchDist:     80,
+	RotateAngle:   15 * math.Pi / 180,
+	Friction:      4,
+	MinMomentum:   100,
+	EdgeWidth:     100,
+	EdgeDist:      300,
+}
+
+// Gestures recognises touchpad gestures.
+// Fingers are tracked by touch Id. It uses a ReportClock.
+type Gestures struct {
+	Handler GestureHandler
+
+	// Config holds the gesture parameters.
+	// It is set to DefaultGestureConfig by NewGestures.
+	Config GestureConfig
+
+	clock ReportClock
+	now   time.Time
+	init  bool
+	pkt   byte
+
+	// active fingers in order of arrival
+	tr []touchTrack
+
+	// single finger state
+	moved    bool // moved or other finger touched
+	long     bool // long press sent
+	edge     int  // edge of start
+	edgeDone bool
+	tapTime  time.Time // last tap
+	tapX     int
+	tapY     int
+
+	// two-finger state
+	mode     GestureKind // Scroll, Pinch, Rotate or zero if undecided
+	cx0, cy0 float64     // centre at start
+	d0, a0   float64     // distance and angle at start
+	cx, cy   float64     // values at last event
+	d, a     float64
+	vx, vy   float64   // scroll velocity
+	tl       time.Time // time of last touch update
+
+	momentum bool
+}
+
+type touchTrack struct {
+	id     byte
+	t0     time.Time
+	x0, y0 int
+	x, y   int
+}
+
+// NewGestures returns a new gesture recogniser using DefaultGestureConfig.
+func NewGestures(h GestureHandler) *Gestures {
+	return &Gestures{Handler: h, Config: DefaultGestureConfig}
+}
+
+// HandleState recognises gestures in s.
+func (g *Gestures) HandleState(s *ds4.State) {
+	g.now = g.clock.Time(s)
+	if g.momentum {
+		g.coast()
+	}
+	if g.init && s.Packet == g.pkt {
+		g.checkLongPress()
+		return
+	}
+	g.init, g.pkt = true, s.Packet
+	g.update(s)
+	g.tl = g.now
+}
+
+// Reset resets the gesture state and the report clock.
+func (g *Gestures) Reset() {
+	h, c := g.Handler, g.Config
+	*g = Gestures{Handler: h, Config: c}
+}
+
+func (g *Gestures) update(s *ds4.State) {
+	var cur []ds4.Touch
+	for _, t := range s.Touch {
+		if t.Active() {
+			cur = append(cur, t)
+		}
+	}
+
+	// lifted fingers
+	for i := 0; i < len(g.tr); {
+		if hasTouch(cur, g.tr[i].id) {
+			i++
+			continue
+		}
+		g.lift()
+		g.tr = append(g.tr[:i], g.tr[i+1:]...)
+	}
+
+	// moved and new fingers
+	for _, t := range cur {
+		x, y := int(t.X), int(t.Y)
+		if i := g.find(t.Id); i >= 0 {
+			g.tr[i].x, g.tr[i].y = x, y
+		} else {
+			g.tr = append(g.tr, touchTrack{id: t.Id, t0: g.now, x0: x, y0: y, x: x, y: y})
+			g.press()
+		}
+	}
+
+	switch len(g.tr) {
+	case 1:
+		g.one()
+	case 2:
+		g.two()
+	}
+}
+
+func hasTouch(v []ds4.Touch, id byte) bool {
+	for _, t := range v {
+		if t.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Gestures) find(id byte) int {
+	for i, t := range g.tr {
+		if t.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// press handles a new finger, already added to tr.
+func (g *Gestures) press() {
+	g.momentum = false
+	switch len(g.tr) {
+	case 1:
+		t := &g.tr[0]
+		g.moved, g.long, g.edgeDone = false, false, false
+		g.edge = g.edgeOf(t.x, t.y)
+	case 2:
+		// the remaining finger can't start a swipe
+		g.moved, g.edge = true, 0
+		g.mode = 0
+		g.cx0, g.cy0, g.d0, g.a0 = g.pair()
+		g.cx, g.cy, g.d, g.a = g.cx0, g.cy0, g.d0, g.a0
+		g.vx, g.vy = 0, 0
+	}
+}
+
+// lift handles a finger lifted, before it is removed from tr.
+func (g *Gestures) lift() {
+	c := &g.Config
+	switch len(g.tr) {
+	case 1:
+		t := &g.tr[0]
+		if g.moved || g.now.Sub(t.t0) > c.TapTime {
+			return
+		}
+		g.emit(Gesture{Kind: Tap, X: t.x, Y: t.y, Fingers: 1})
+		dx, dy := t.x-g.tapX, t.y-g.tapY
+		if !g.tapTime.IsZero() && g.now.Sub(g.tapTime) <= c.DoubleTapTime &&
+			dx*dx+dy*dy <= 4*c.TapDist*c.TapDist {
+			g.emit(Gesture{Kind: DoubleTap, X: t.x, Y: t.y, Fingers: 1})
+			g.tapTime = time.Time{}
+		} else {
+			g.tapTime, g.tapX, g.tapY = g.now, t.x, t.y
+		}
+	case 2:
+		if g.mode == Scroll && math.Hypot(g.vx, g.vy) >= c.MinMomentum {
+			g.momentum = true
+		}
+		g.mode = 0
+	}
+}
+
+func (g *Gestures) one() {
+	c := &g.Config
+	t := &g.tr[0]
+	dx, dy := t.x-t.x0, t.y-t.y0
+	if dx*dx+dy*dy > c.TapDist*c.TapDist {
+		g.moved = true
+	}
+
+	if g.edge != 0 && !g.edgeDone {
+		var inward int
+		switch g.edge {
+		case SwipeLeft:
+			inward = dx
+		case SwipeRight:
+			inward = -dx
+		case SwipeUp:
+			inward = dy
+		case SwipeDown:
+			inward = -dy
+		}
+		if inward >= c.EdgeDist {
+			g.edgeDone = true
+			g.emit(Gesture{Kind: EdgeSwipe, X: t.x, Y: t.y, Fingers: 1, Edge: g.edge})
+		}
+	}
+
+	g.checkLongPress()
+}
+
+func (g *Gestures) checkLongPress() {
+	if len(g.tr) != 1 || g.moved || g.long {
+		return
+	}
+	t := &g.tr[0]
+	if g.now.Sub(t.t0) >= g.Config.LongPressTime {
+		g.long = true
+		g.emit(Gesture{Kind: LongPress, X: t.x, Y: t.y, Fingers: 1})
+	}
+}
+
+func (g *Gestures) two() {
+	c := &g.Config
+	cx, cy, d, a := g.pair()
+
+	if g.mode == 0 {
+		switch {
+		case math.Hypot(cx-g.cx0, cy-g.cy0) > float64(c.ScrollDist):
+			g.mode = Scroll
+		case math.Abs(d-g.d0) > float64(c.PinchDist):
+			g.mode = Pinch
+		case math.Abs(angleDiff(a, g.a0)) > c.RotateAngle:
+			g.mode = Rotate
+		default:
+			return
+		}
+	}
+
+	ev := Gesture{Kind: g.mode, X: int(cx), Y: int(cy), Fingers: 2}
+	switch g.mode {
+	case Scroll:
+		ev.DX, ev.DY = cx-g.cx, cy-g.cy
+		if dt := g.now.Sub(g.tl).Seconds(); dt > 0 {
+			g.vx = (g.vx + ev.DX/dt) / 2
+			g.vy = (g.vy + ev.DY/dt) / 2
+		}
+	case Pinch:
+		if g.d == 0 {
+			return
+		}
+		ev.Scale = d / g.d
+	case Rotate:
+		ev.Angle = angleDiff(a, g.a)
+	}
+	g.cx, g.cy, g.d, g.a = cx, cy, d, a
+	g.emit(ev)
+}
+
+// coast sends scroll momentum events.
+func (g *Gestures) coast() {
+	dt := g.now.Sub(g.tl).Seconds()
+	g.tl = g.now
+	if dt <= 0 {
+		return
+	}
+	g.emit(Gesture{
+		Kind:     Scroll,
+		X:        int(g.cx),
+		Y:        int(g.cy),
+		Fingers:  2,
+		DX:       g.vx * dt,
+		DY:       g.vy * dt,
+		Momentum: true,
+	})
+	f := math.Exp(-g.Config.Friction * dt)
+	g.vx, g.vy = g.vx*f, g.vy*f
+	if math.Hypot(g.vx, g.vy) < g.Config.MinMomentum {
+		g.momentum = false
+	}
+}
+
+// pair returns the centre, distance and angle of the two fingers.
+func (g *Gestures) pair() (cx, cy, d, a float64) {
+	p, q := &g.tr[0], &g.tr[1]
+	dx, dy := float64(q.x-p.x), float64(q.y-p.y)
+	cx, cy = float64(p.x+q.x)/2, float64(p.y+q.y)/2
+	return cx, cy, math.Hypot(dx, dy), math.Atan2(dy, dx)
+}
+
+// edgeOf returns the edge at x, y or zero.
+func (g *Gestures) edgeOf(x, y int) int {
+	w := g.Config.EdgeWidth
+	switch {
+	case x < w:
+		return SwipeLeft
+	case x >= TouchpadWidth-w:
+		return SwipeRight
+	case y < w:
+		return SwipeUp
+	case y >= TouchpadHeight-w:
+		return SwipeDown
+	}
+	return 0
+}
+
+func (g *Gestures) emit(ev Gesture) {
+	ev.Time = g.now
+	g.Handler.Gesture(&ev)
+}
+
+// angleDiff returns a-b normalized between -π and π.
+func angleDiff(a, b float64) float64 {
+	d := math.Mod(a-b, 2*math.Pi)
+	switch {
+	case d > math.Pi:
+		d -= 2 * math.Pi
+	case d < -math.Pi:
+		d += 2 * math.Pi
+	}
+	return d
+}
@@ -0,0 +1,199 @@
+package ds4util
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestGestures(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []touchStep
+		want  []string
+	}{
+		{
+			name: "tap",
+			steps: []touchStep{
+				{0, []finger{{1, 500, 400}}, false},
+				{50, []finger{{1, 505, 402}}, false},
+				{100, nil, false},
+			},
+			want: []string{"tap 505 402"},
+		},
+		{
+			name: "double tap",
+			steps: []touchStep{
+				{0, []finger{{1, 500, 400}}, false},
+				{50, nil, false},
+				{200, []finger{{2, 510, 410}}, false},
+				{250, nil, false},
+			},
+			want: []string{"tap 500 400", "tap 510 410", "doubletap 510 410"},
+		},
+		{
+			name: "taps too far apart",
+			steps: []touchStep{
+				{0, []finger{{1, 500, 400}}, false},
+				{50, nil, false},
+				{300, nil, false},
+				{500, []finger{{2, 500, 400}}, false},
+				{550, nil, false},
+			},
+			want: []string{"tap 500 400", "tap 500 400"},
+		},
+		{
+			name: "long press",
+			steps: []touchStep{
+				{0, []finger{{1, 500, 400}}, false},
+				{300, []finger{{1, 502, 400}}, false},
+				{600, []finger{{1, 502, 401}}, false},
+				{700, nil, false},
+			},
+			want: []string{"longpress 502 401"},
+		},
+		{
+			name: "moved",
+			steps: []touchStep{
+				{0, []finger{{1, 500, 400}}, false},
+				{50, []finger{{1, 600, 400}}, false},
+				{100, nil, false},
+			},
+			want: nil,
+		},
+		{
+			name: "edge swipe",
+			steps: []touchStep{
+				{0, []finger{{1, 1900, 400}}, false},
+				{50, []finger{{1, 1700, 400}}, false},
+				{100, []finger{{1, 1500, 410}}, false},
+				{150, []finger{{1, 1300, 410}}, false},
+				{200, nil, false},
+			},
+			want: []string{"edgeswipe right"},
+		},
+		{
+			name: "no edge swipe from centre",
+			steps: []touchStep{
+				{0, []finger{{1, 1500, 400}}, false},
+				{50, []finger{{1, 1300, 400}}, false},
+				{100, []finger{{1, 1100, 410}}, false},
+				{150, nil, false},
+			},
+			want: nil,
+		},
+		{
+			name: "no edge swipe after second finger",
+			steps: []touchStep{
+				{0, []finger{{1, 1900, 400}}, false},
+				{20, []finger{{1, 1900, 400}, {2, 1000, 400}}, false},
+				{40, []finger{{1, 1900, 400}}, false},
+				{60, []finger{{1, 1700, 400}}, false},
+				{80, []finger{{1, 1500, 400}}, false},
+				{100, nil, false},
+			},
+			want: nil,
+		},
+		{
+			name: "scroll",
+			steps: []touchStep{
+				{0, []finger{{1, 500, 300}, {2, 700, 300}}, false},
+				{20, []finger{{1, 500, 350}, {2, 700, 350}}, false},
+				{40, []finger{{1, 500, 380}, {2, 700, 380}}, false},
+				{60, []finger{{1, 500, 380}, {2, 700, 380}}, false},
+				{80, nil, false},
+			},
+			want: []string{"scroll 0 50", "scroll 0 30", "scroll 0 0"},
+		},
+		{
+			name: "pinch",
+			steps: []touchStep{
+				{0, []finger{{1, 800, 400}, {2, 1000, 400}}, false},
+				{20, []finger{{1, 750, 400}, {2, 1050, 400}}, false},
+				{40, []finger{{1, 700, 400}, {2, 1100, 400}}, false},
+				{60, nil, false},
+			},
+			want: []string{"pinch 1.50", "pinch 1.33"},
+		},
+		{
+			name: "rotate",
+			steps: []touchStep{
+				{0, []finger{{1, 800, 400}, {2, 1000, 400}}, false},
+				{20, []finger{{1, 800, 400}, {2, 1000, 400}}, false},
+				{40, []finger{{1, 900 - 87, 450}, {2, 900 + 87, 350}}, false},
+				{60, nil, false},
+			},
+			want: []string{"rotate -30"},
+		},
+		{
+			name: "second finger cancels tap",
+			steps: []touchStep{
+				{0, []finger{{1, 500, 400}}, false},
+				{20, []finger{{1, 500, 400}, {2, 700, 400}}, false},
+				{40, []finger{{2, 700, 400}}, false},
+				{60, nil, false},
+			},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		var r recorder
+		g := NewGestures(&r)
+		for _, s := range touchStates(tt.steps) {
+			g.HandleState(&s)
+		}
+		if !reflect.DeepEqual(r.calls, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, r.calls, tt.want)
+		}
+	}
+}
+
+func TestGestureMomentum(t *testing.T) {
+	steps := []touchStep{
+		{0, []finger{{1, 500, 300}, {2, 700, 300}}, false},
+		{10, []finger{{1, 500, 350}, {2, 700, 350}}, false},
+		{20, []finger{{1, 500, 400}, {2, 700, 400}}, false},
+		{30, []finger{{1, 500, 450}, {2, 700, 450}}, false},
+		{40, nil, false},
+	}
+	for ms := 50; ms < 3000; ms += 10 {
+		steps = append(steps, touchStep{ms, nil, false})
+	}
+
+	var r recorder
+	g := NewGestures(&r)
+	for _, s := range touchStates(steps) {
+		g.HandleState(&s)
+	}
+
+	if len(r.momentum) < 2 {
+		t.Fatalf("got %d momentum events, want more", len(r.momentum))
+	}
+	var sum float64
+	prev := math.Inf(1)
+	for _, m := range r.momentum {
+		if m.DX != 0 || m.DY <= 0 || m.DY > prev {
+			t.Fatalf("momentum scroll %v %v not decaying downwards", m.DX, m.DY)
+		}
+		prev = m.DY
+		sum += m.DY
+	}
+	last := r.momentum[len(r.momentum)-1]
+	if v := last.DY / 0.01; v > 2*DefaultGestureConfig.MinMomentum {
+		t.Errorf("momentum stopped at speed %v", v)
+	}
+	if sum < 100 {
+		t.Errorf("momentum scrolled %v, want more", sum)
+	}
+
+	// a new touch stops momentum
+	r = recorder{}
+	g.Reset()
+	steps = append(steps[:6], touchStep{60, []finger{{3, 500, 400}}, false}, touchStep{70, []finger{{3, 500, 400}}, false})
+	for _, s := range touchStates(steps) {
+		g.HandleState(&s)
+	}
+	if n := len(r.momentum); n != 2 {
+		t.Errorf("got %d momentum events before touch, want 2", n)
+	}
+}
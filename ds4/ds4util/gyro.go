@@ -0,0 +1,198 @@
+package ds4util
+
+import (
+	"math"
+	"time"
+
+	"github.com/tajtiattila/hid/ds4"
+)
+
+// GyroScale is the raw gyroscope units per degree per second.
+const GyroScale = 16.384
+
+// GyroSpace selects the axes used for gyro aiming.
+type GyroSpace int
+
+const (
+	// LocalSpace turns around the axes of the controller.
+	// Yaw is rotation around the controller's vertical axis.
+	LocalSpace GyroSpace = iota
+
+	// WorldSpace turns around the axes of the world. Yaw is rotation
+	// around the direction of gravity, regardless of how the controller is held.
+	WorldSpace
+
+	// PlayerSpace is a compromise between local and world space.
+	// Yaw combines the controller's yaw and roll close to the world yaw,
+	// working well however the controller is held.
+	PlayerSpace
+)
+
+var gyroSpaceStr = []string{"local", "world", "player"}
+
+func (s GyroSpace) String() string {
+	if s >= 0 && int(s) < len(gyroSpaceStr) {
+		return gyroSpaceStr[s]
+	}
+	return "space?"
+}
+
+// Defaults for gyro aiming.
+const (
+	defaultGravityTau = 250 * time.Millisecond
+
+	// yaw relax factor of PlayerSpace
+	playerYawRelax = 1.41
+)
+
+// GyroAim converts the calibrated angular velocity of the controller
+// into pointer or camera movement.
+//
+// Rates are in degrees per second, and outputs are in degrees
+// multiplied by Sensitivity. Positive DX turns right,
+// and positive DY turns down. It uses a ReportClock.
+type GyroAim struct {
+	Space GyroSpace
+
+	// Sensitivity is the output per degree of rotation.
+	Sensitivity float64
+
+	// Accel maps the rotation speed in degrees per second
+	// to the gain of the output. The gain is 1 if Accel is nil.
+	Accel Curve
+
+	// Smoothing filters the yaw and pitch rates if set.
+	Smoothing VecFilter
+
+	// Tightening is the rotation speed in degrees per second
+	// below which the output is reduced gradually to suppress noise.
+	Tightening float64
+
+	// Ratchet are the buttons that disable the output while held,
+	// so that the controller can be repositioned.
+	Ratchet uint32
+
+	// InvertX and InvertY invert the output axes.
+	InvertX, InvertY bool
+
+	clock ReportClock
+	init  bool
+	t     time.Time
+	grav  LowPass
+	rates [2]float64
+}
+
+// NewGyroAim returns a new GyroAim using PlayerSpace.
+func NewGyroAim() *GyroAim {
+	return &GyroAim{Space: PlayerSpace, Sensitivity: 1, Tightening: 2}
+}
+
+// Update processes the calibrated state s,
+// and returns the output movement since the previous state.
+func (g *GyroAim) Update(s *ds4.State) (dx, dy float64) {
+	t := g.clock.Time(s)
+	if g.grav.Tau == 0 {
+		g.grav.Tau = defaultGravityTau
+	}
+	acc := g.grav.Filter(t, []float64{float64(s.XAcc), float64(s.YAcc), float64(s.ZAcc)})
+	if !g.init {
+		g.init, g.t = true, t
+		return 0, 0
+	}
+	dt := t.Sub(g.t).Seconds()
+	g.t = t
+
+	w := [3]float64{
+		float64(s.XGyro) / GyroScale,
+		float64(s.YGyro) / GyroScale,
+		float64(s.ZGyro) / GyroScale,
+	}
+	yaw, pitch := g.axes(w, [3]float64{acc[0], acc[1], acc[2]})
+
+	v := g.rates[:]
+	v[0], v[1] = yaw, pitch
+	if g.Smoothing != nil {
+		v = g.Smoothing.Filter(t, v)
+	}
+	yaw, pitch = v[0], v[1]
+
+	if s.Button&g.Ratchet != 0 || dt <= 0 {
+		return 0, 0
+	}
+
+	speed := math.Hypot(yaw, pitch)
+	gain := g.Sensitivity
+	if g.Accel != nil {
+		gain *= g.Accel(speed)
+	}
+	if g.Tightening > 0 && speed < g.Tightening {
+		gain *= speed / g.Tightening
+	}
+
+	dx, dy = yaw*gain*dt, pitch*gain*dt
+	if g.InvertX {
+		dx = -dx
+	}
+	if g.InvertY {
+		dy = -dy
+	}
+	return dx, dy
+}
+
+// Reset resets the state of g and its Smoothing filter.
+func (g *GyroAim) Reset() {
+	g.clock.Reset()
+	g.grav.Reset()
+	g.init = false
+	if g.Smoothing != nil {
+		g.Smoothing.Reset()
+	}
+}
+
+// axes returns the yaw and pitch rates from the angular velocity w
+// and the accelerometer vector acc, in controller coordinates.
+func (g *GyroAim) axes(w, acc [3]float64) (yaw, pitch float64) {
+	// controller axes: x points left, y down and z forward
+	up := [3]float64{0, -1, 0}
+	left := [3]float64{1, 0, 0}
+
+	// the accelerometer measures the reaction to gravity, pointing up
+	n := math.Sqrt(dot3(acc, acc))
+	if g.Space == LocalSpace || n == 0 {
+		return -dot3(w, up), -dot3(w, left)
+	}
+	for i := range acc {
+		acc[i] /= n
+	}
+
+	switch g.Space {
+	case PlayerSpace:
+		wy := -dot3(w, acc)
+		lim := math.Hypot(w[1], w[2])
+		yaw = math.Copysign(math.Min(math.Abs(wy)*playerYawRelax, lim), wy)
+		return yaw, -dot3(w, left)
+	default: // WorldSpace
+		yaw = -dot3(w, acc)
+
+		// pitch around the controller's left axis flattened
+		// to the horizontal plane, fading out when it points
+		// along gravity
+		var axis [3]float64
+		d := dot3(left, acc)
+		for i := range axis {
+			axis[i] = left[i] - acc[i]*d
+		}
+		if an := math.Sqrt(dot3(axis, axis)); an > 0 {
+			for i := range axis {
+				axis[i] /= an
+			}
+		}
+		flat := math.Max(math.Abs(dot3(acc, up)), math.Abs(acc[2]))
+		side := clamp01((flat - 0.125) / 0.125)
+		return yaw, -dot3(w, axis) * side
+	}
+}
+
+func dot3(a, b [3]float64) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
@@ -0,0 +1,109 @@
+package ds4util
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tajtiattila/hid/ds4"
+)
+
+// gyroStates returns n states 4 ms apart
+// with angular velocity w and acceleration acc.
+func gyroStates(n int, w, acc [3]int16) []ds4.State {
+	v := make([]ds4.State, n)
+	for i := range v {
+		v[i] = testState(i, i*4)
+		s := &v[i]
+		s.XGyro, s.YGyro, s.ZGyro = w[0], w[1], w[2]
+		s.XAcc, s.YAcc, s.ZAcc = acc[0], acc[1], acc[2]
+	}
+	return v
+}
+
+// aimSum returns the total output of g for states.
+func aimSum(g *GyroAim, states []ds4.State) (x, y float64) {
+	for i := range states {
+		dx, dy := g.Update(&states[i])
+		x += dx
+		y += dy
+	}
+	return x, y
+}
+
+func TestGyroAimSpaces(t *testing.T) {
+	const r = 1638 // ~100°/s
+	deg := float64(r) / GyroScale
+	flat := [3]int16{0, -8000, 0}
+	upright := [3]int16{0, 0, -8000}
+	tests := []struct {
+		name   string
+		space  GyroSpace
+		w, acc [3]int16
+		x, y   float64
+	}{
+		{"local yaw", LocalSpace, [3]int16{0, r, 0}, flat, deg, 0},
+		{"local pitch", LocalSpace, [3]int16{-r, 0, 0}, flat, 0, deg},
+		{"world flat", WorldSpace, [3]int16{0, r, 0}, flat, deg, 0},
+		{"player flat", PlayerSpace, [3]int16{0, r, 0}, flat, deg, 0},
+		{"local upright", LocalSpace, [3]int16{0, 0, r}, upright, 0, 0},
+		{"world upright", WorldSpace, [3]int16{0, 0, r}, upright, deg, 0},
+		{"player upright", PlayerSpace, [3]int16{0, 0, r}, upright, deg, 0},
+		{"world upright pitch", WorldSpace, [3]int16{-r, 0, 0}, upright, 0, deg},
+		{"no gravity", WorldSpace, [3]int16{0, r, 0}, [3]int16{}, deg, 0},
+	}
+	for _, tt := range tests {
+		g := &GyroAim{Space: tt.space, Sensitivity: 1}
+		// one second of movement after the first state
+		x, y := aimSum(g, gyroStates(251, tt.w, tt.acc))
+		if math.Abs(x-tt.x) > 1e-6 || math.Abs(y-tt.y) > 1e-6 {
+			t.Errorf("%s: got %.3f %.3f, want %.3f %.3f", tt.name, x, y, tt.x, tt.y)
+		}
+	}
+}
+
+func TestGyroAimOptions(t *testing.T) {
+	flat := [3]int16{0, -8000, 0}
+	fast := gyroStates(251, [3]int16{0, 1638, 0}, flat)
+	slow := gyroStates(251, [3]int16{0, 16, 0}, flat) // ~1°/s
+	deg := 1638 / GyroScale
+
+	g := &GyroAim{Sensitivity: 2, InvertX: true}
+	if x, _ := aimSum(g, fast); math.Abs(x+2*deg) > 1e-6 {
+		t.Errorf("sensitivity: got %.3f", x)
+	}
+
+	g = &GyroAim{Sensitivity: 1, Accel: func(v float64) float64 { return v / 50 }}
+	if x, _ := aimSum(g, fast); math.Abs(x-deg*deg/50) > 1e-6 {
+		t.Errorf("accel: got %.3f", x)
+	}
+
+	g = &GyroAim{Sensitivity: 1, Tightening: 2}
+	if x, _ := aimSum(g, fast); math.Abs(x-deg) > 1e-6 {
+		t.Errorf("tightening fast: got %.3f", x)
+	}
+	g = &GyroAim{Sensitivity: 1, Tightening: 2}
+	sdeg := 16 / GyroScale
+	if x, _ := aimSum(g, slow); math.Abs(x-sdeg*sdeg/2) > 1e-6 {
+		t.Errorf("tightening slow: got %.3f", x)
+	}
+
+	g = &GyroAim{Sensitivity: 1, Ratchet: ds4.L1}
+	held := gyroStates(251, [3]int16{0, 1638, 0}, flat)
+	for i := range held[:126] {
+		held[i].Button = ds4.L1
+	}
+	if x, _ := aimSum(g, held); math.Abs(x-deg/2) > 1e-6 {
+		t.Errorf("ratchet: got %.3f", x)
+	}
+
+	start := gyroStates(251, [3]int16{0, 1638, 0}, flat)
+	for i := range start[:10] {
+		start[i].YGyro = 0
+	}
+	raw, _ := aimSum(&GyroAim{Sensitivity: 1}, start)
+	g = &GyroAim{Sensitivity: 1, Smoothing: &LowPass{Alpha: 0.5}}
+	x, _ := aimSum(g, start)
+	if x >= raw || x < raw-deg*0.01 {
+		t.Errorf("smoothing: got %.3f, unsmoothed %.3f", x, raw)
+	}
+}
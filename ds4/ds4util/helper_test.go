@@ -0,0 +1,107 @@
+package ds4util
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/tajtiattila/hid/ds4"
+)
+
+// testBase is the report clock base used in tests.
+var testBase = time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// testState returns the i-th state of a test, at ms milliseconds.
+// Packet changes in every state.
+func testState(i, ms int) ds4.State {
+	return ds4.State{
+		Packet:    byte(i + 1),
+		Timestamp: uint16(ms * 3000 / 16),
+	}
+}
+
+// recorder records the events of the handlers in tests.
+// Times are in ms from testBase.
+type recorder struct {
+	calls    []string
+	momentum []*Gesture // momentum scrolling, omitted from calls
+}
+
+func (r *recorder) add(format string, args ...interface{}) {
+	r.calls = append(r.calls, fmt.Sprintf(format, args...))
+}
+
+func testMs(t time.Time) time.Duration {
+	return t.Sub(testBase).Round(time.Millisecond) / time.Millisecond
+}
+
+func pressString(pressed bool) string {
+	if pressed {
+		return "+"
+	}
+	return "-"
+}
+
+func (r *recorder) Combo(e *ComboEvent) {
+	r.add("%d %s%s", testMs(e.Time), e.Combo.Name, pressString(e.Pressed))
+}
+
+func (r *recorder) MacroEvent(e *MacroEvent) {
+	r.add("%d %d%s", testMs(e.Time), e.Code, pressString(e.Pressed))
+}
+
+func (r *recorder) MouseEvent(e *MouseEvent) {
+	switch {
+	case e.Button == 0:
+		r.add("move %+d %+d", e.DX, e.DY)
+	case e.Pressed:
+		r.add("press %v", e.Button)
+	default:
+		r.add("release %v", e.Button)
+	}
+}
+
+func (r *recorder) Gesture(g *Gesture) {
+	switch g.Kind {
+	case Scroll:
+		if g.Momentum {
+			r.momentum = append(r.momentum, g)
+			return
+		}
+		r.add("scroll %.0f %.0f", g.DX, g.DY)
+	case Pinch:
+		r.add("pinch %.2f", g.Scale)
+	case Rotate:
+		r.add("rotate %.0f", g.Angle*180/math.Pi)
+	case EdgeSwipe:
+		r.add("edgeswipe %s", dirString(g.Edge))
+	default:
+		r.add("%v %d %d", g.Kind, g.X, g.Y)
+	}
+}
+
+func (r *recorder) Swipe(dir, ntouch int) {
+	r.add("swipe %s %d", dirString(dir), ntouch)
+}
+
+func (r *recorder) Touch(x, y int) {
+	r.add("touch %d %d", x, y)
+}
+
+func (r *recorder) Click(x, y int) {
+	r.add("click %d %d", x, y)
+}
+
+func dirString(dir int) string {
+	var v []string
+	for _, d := range []struct {
+		bit  int
+		name string
+	}{{SwipeUp, "up"}, {SwipeDown, "down"}, {SwipeLeft, "left"}, {SwipeRight, "right"}} {
+		if dir&d.bit != 0 {
+			v = append(v, d.name)
+		}
+	}
+	return strings.Join(v, "+")
+}
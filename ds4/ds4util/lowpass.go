@@ -11,6 +11,13 @@ func NewAlphaFilter(n int, a float64) *AlphaFilter {
 	return &AlphaFilter{A: a, V: make([]int, n)}
 }
 
+// Reset resets the filter value to zero.
+func (f *AlphaFilter) Reset() {
+	for i := range f.V {
+		f.V[i] = 0
+	}
+}
+
 func (f *AlphaFilter) Filter(v []int) []int {
 	for i := range v {
 		f.V[i] += int(f.A * float64(v[i]-f.V[i]))
@@ -0,0 +1,184 @@
+package ds4util
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tajtiattila/hid/ds4"
+)
+
+// MacroStep is a step of a Macro.
+type MacroStep struct {
+	// Delay is the time since the previous step,
+	// or the start of the macro for the first step.
+	Delay time.Duration
+
+	// Code identifies the output, such as a key code.
+	Code int
+
+	// Pressed is the new state of the output.
+	Pressed bool
+}
+
+// Macro is a timed output sequence.
+type Macro []MacroStep
+
+// TapMacro returns a macro tapping codes in order.
+// Each output is held for hold, with gap between the outputs.
+func TapMacro(hold, gap time.Duration, codes ...int) Macro {
+	var m Macro
+	for i, c := range codes {
+		var d time.Duration
+		if i > 0 {
+			d = gap
+		}
+		m = append(m,
+			MacroStep{Delay: d, Code: c, Pressed: true},
+			MacroStep{Delay: hold, Code: c, Pressed: false})
+	}
+	return m
+}
+
+// MacroEvent reports an output change of a playing macro.
+type MacroEvent struct {
+	// Time is the scheduled time of the step.
+	Time time.Time
+
+	Code    int
+	Pressed bool
+}
+
+// MacroHandler receives macro output changes.
+type MacroHandler interface {
+	MacroEvent(e *MacroEvent)
+}
+
+// MacroPlayer plays macros.
+//
+// Outputs are counted, so an output pressed by several macros
+// is released when the last one releases it.
+//
+// MacroPlayer uses a ReportClock. Steps are played
+// at the first report at or after their time, in time order.
+type MacroPlayer struct {
+	Handler MacroHandler
+
+	clock ReportClock
+	now   time.Time
+
+	playing []*playback
+	pressed map[int]int
+}
+
+type playback struct {
+	m Macro
+	i int       // next step
+	t time.Time // time of the previous step or start, zero before start
+}
+
+// NewMacroPlayer returns a new macro player.
+func NewMacroPlayer(h MacroHandler) *MacroPlayer {
+	return &MacroPlayer{Handler: h}
+}
+
+// Play starts playing m at the next report passed to HandleState.
+func (p *MacroPlayer) Play(m Macro) {
+	if len(m) != 0 {
+		p.playing = append(p.playing, &playback{m: m})
+	}
+}
+
+// Playing reports whether macros are playing.
+func (p *MacroPlayer) Playing() bool {
+	return len(p.playing) != 0
+}
+
+// Stop stops the macros playing,
+// and releases the outputs they pressed.
+func (p *MacroPlayer) Stop() {
+	p.playing = nil
+	codes := make([]int, 0, len(p.pressed))
+	for code := range p.pressed {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		p.send(p.now, code, false)
+	}
+	p.pressed = nil
+}
+
+// HandleState plays the steps due at the time of s.
+func (p *MacroPlayer) HandleState(s *ds4.State) {
+	p.now = p.clock.Time(s)
+
+	for {
+		var next *playback
+		var nt time.Time
+		for _, pb := range p.playing {
+			if pb.t.IsZero() {
+				pb.t = p.now
+			}
+			if pb.i == len(pb.m) {
+				continue
+			}
+			t := pb.t.Add(pb.m[pb.i].Delay)
+			if !t.After(p.now) && (next == nil || t.Before(nt)) {
+				next, nt = pb, t
+			}
+		}
+		if next == nil {
+			break
+		}
+		st := next.m[next.i]
+		next.i++
+		next.t = nt
+		p.step(nt, st.Code, st.Pressed)
+	}
+
+	v := p.playing[:0]
+	for _, pb := range p.playing {
+		if pb.i < len(pb.m) {
+			v = append(v, pb)
+		}
+	}
+	p.playing = v
+}
+
+// Reset stops playing without releasing outputs,
+// and resets the report clock.
+func (p *MacroPlayer) Reset() {
+	p.clock.Reset()
+	p.now = time.Time{}
+	p.playing = nil
+	p.pressed = nil
+}
+
+func (p *MacroPlayer) step(t time.Time, code int, pressed bool) {
+	if p.pressed == nil {
+		p.pressed = make(map[int]int)
+	}
+	n := p.pressed[code]
+	if pressed {
+		p.pressed[code] = n + 1
+		if n == 0 {
+			p.send(t, code, true)
+		}
+		return
+	}
+	if n == 0 {
+		return
+	}
+	if n == 1 {
+		delete(p.pressed, code)
+		p.send(t, code, false)
+	} else {
+		p.pressed[code] = n - 1
+	}
+}
+
+func (p *MacroPlayer) send(t time.Time, code int, pressed bool) {
+	if p.Handler != nil {
+		p.Handler.MacroEvent(&MacroEvent{Time: t, Code: code, Pressed: pressed})
+	}
+}
@@ -0,0 +1,149 @@
+package ds4util
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tajtiattila/hid/ds4"
+)
+
+// idleStates returns states every step ms until end ms.
+func idleStates(step, end int) []ds4.State {
+	var steps []buttonStep
+	for ms := 0; ms <= end; ms += step {
+		steps = append(steps, buttonStep{ms, ds4.DpadOff})
+	}
+	return buttonStates(steps)
+}
+
+func TestMacroPlayer(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name   string
+		macros []Macro
+		want   []string
+	}{
+		{
+			name:   "taps",
+			macros: []Macro{TapMacro(30*ms, 20*ms, 1, 2)},
+			want:   []string{"0 1+", "30 1-", "50 2+", "80 2-"},
+		},
+		{
+			name: "overlap",
+			macros: []Macro{
+				{{0, 1, true}, {100 * ms, 1, false}},
+				{{50 * ms, 1, true}, {50 * ms, 2, true}, {100 * ms, 1, false}, {0, 2, false}},
+			},
+			want: []string{"0 1+", "100 2+", "200 1-", "200 2-"},
+		},
+	}
+	for _, tt := range tests {
+		var r recorder
+		p := NewMacroPlayer(&r)
+		p.clock.Base = testBase
+		for _, m := range tt.macros {
+			p.Play(m)
+		}
+		for _, s := range idleStates(20, 300) {
+			p.HandleState(&s)
+		}
+		if !reflect.DeepEqual(r.calls, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, r.calls, tt.want)
+		}
+		if p.Playing() {
+			t.Errorf("%s: still playing", tt.name)
+		}
+	}
+}
+
+func TestMacroPlayerStop(t *testing.T) {
+	var r recorder
+	p := NewMacroPlayer(&r)
+	p.clock.Base = testBase
+	p.Play(Macro{{0, 2, true}, {0, 1, true}, {time.Second, 1, false}, {0, 2, false}})
+	for _, s := range idleStates(20, 100) {
+		p.HandleState(&s)
+	}
+	if !p.Playing() {
+		t.Fatal("stopped early")
+	}
+	p.Stop()
+	want := []string{"0 2+", "0 1+", "100 1-", "100 2-"}
+	if !reflect.DeepEqual(r.calls, want) {
+		t.Errorf("got %q, want %q", r.calls, want)
+	}
+}
+
+// comboMacros plays macros for combos.
+type comboMacros struct {
+	p      *MacroPlayer
+	macros map[string]Macro
+}
+
+func (h *comboMacros) Combo(e *ComboEvent) {
+	if e.Pressed {
+		h.p.Play(h.macros[e.Combo.Name])
+	}
+}
+
+func TestComboMacro(t *testing.T) {
+	var r recorder
+	p := NewMacroPlayer(&r)
+	p.clock.Base = testBase
+	h := &comboMacros{p: p, macros: map[string]Macro{
+		"qcf": TapMacro(20*time.Millisecond, 10*time.Millisecond, 30, 31),
+	}}
+	c := NewCombos(h, &Combo{Name: "qcf", Kind: SequenceCombo, Steps: []uint32{
+		DpadDown, DpadDown | DpadRight, DpadRight | ds4.Square,
+	}})
+	c.clock.Base = testBase
+
+	steps := []buttonStep{
+		{0, dpadNone},
+		{20, dpadDown},
+		{40, dpadDownRight},
+		{60, dpadRight | ds4.Square},
+	}
+	for ms := 80; ms <= 200; ms += 20 {
+		steps = append(steps, buttonStep{ms, dpadNone})
+	}
+	for _, s := range buttonStates(steps) {
+		c.HandleState(&s)
+		p.HandleState(&s)
+	}
+	want := []string{"60 30+", "80 30-", "90 31+", "110 31-"}
+	if !reflect.DeepEqual(r.calls, want) {
+		t.Errorf("got %q, want %q", r.calls, want)
+	}
+}
+
+// Combos and MacroPlayer handle every report,
+// even if the packet counter doesn't change.
+func TestComboMacroSamePacket(t *testing.T) {
+	var r recorder
+	p := NewMacroPlayer(&r)
+	p.clock.Base = testBase
+	h := &comboMacros{p: p, macros: map[string]Macro{
+		"tap": TapMacro(10*time.Millisecond, 0, 30),
+	}}
+	c := NewCombos(h, &Combo{Name: "tap", Kind: TapCombo, Buttons: ds4.Cross})
+	c.clock.Base = testBase
+
+	states := buttonStates([]buttonStep{
+		{0, dpadNone},
+		{10, dpadNone | ds4.Cross},
+		{20, dpadNone},
+		{30, dpadNone},
+		{40, dpadNone},
+	})
+	for _, s := range states {
+		s.Packet = 7
+		c.HandleState(&s)
+		p.HandleState(&s)
+	}
+	want := []string{"20 30+", "30 30-"}
+	if !reflect.DeepEqual(r.calls, want) {
+		t.Errorf("got %q, want %q", r.calls, want)
+	}
+}
@@ -0,0 +1,91 @@
+package ds4util
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/tajtiattila/hid/ds4"
+)
+
+// WriteMetrics writes stats to w in the Prometheus text exposition format.
+func WriteMetrics(w io.Writer, stats []DeviceStats) error {
+	bw := bufio.NewWriter(w)
+
+	counter := func(name, help string, f func(s *DeviceStats) uint64) {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+		for i := range stats {
+			s := &stats[i]
+			fmt.Fprintf(bw, "%s{%s} %d\n", name, labels(s), f(s))
+		}
+	}
+	gauge := func(name, help string, f func(s *DeviceStats) float64) {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for i := range stats {
+			s := &stats[i]
+			fmt.Fprintf(bw, "%s{%s} %s\n", name, labels(s), fmtFloat(f(s)))
+		}
+	}
+
+	counter("ds4_reports_total", "Input reports read.",
+		func(s *DeviceStats) uint64 { return s.Reports })
+	counter("ds4_dropped_reports_total", "Input reports missed according to the report counter.",
+		func(s *DeviceStats) uint64 { return s.Dropped })
+	counter("ds4_decode_errors_total", "Input reports that could not be decoded.",
+		func(s *DeviceStats) uint64 { return s.DecodeErrors })
+	counter("ds4_crc_errors_total", "Bluetooth input reports with a bad checksum.",
+		func(s *DeviceStats) uint64 { return s.CRCErrors })
+	gauge("ds4_report_rate", "Input reports per second.",
+		func(s *DeviceStats) float64 { return s.Rate })
+	gauge("ds4_battery_level", "Battery level percentage.",
+		func(s *DeviceStats) float64 { return float64(s.Entry.Battery.Percent()) })
+	gauge("ds4_battery_charging", "Whether the battery is charging.",
+		func(s *DeviceStats) float64 {
+			if s.Charging() {
+				return 1
+			}
+			return 0
+		})
+
+	const lat = "ds4_report_delay_seconds"
+	fmt.Fprintf(bw, "# HELP %s Input report delay estimated from report timestamps.\n# TYPE %s histogram\n", lat, lat)
+	for i := range stats {
+		s := &stats[i]
+		l := labels(s)
+		var n uint64
+		for j, c := range s.Latency.Counts {
+			n += c
+			le := "+Inf"
+			if j < ds4.NumLatencyBuckets {
+				le = fmtFloat(ds4.LatencyBuckets[j].Seconds())
+			}
+			fmt.Fprintf(bw, "%s_bucket{%s,le=%q} %d\n", lat, l, le, n)
+		}
+		fmt.Fprintf(bw, "%s_sum{%s} %s\n", lat, l, fmtFloat(s.Latency.Sum.Seconds()))
+		fmt.Fprintf(bw, "%s_count{%s} %d\n", lat, l, n)
+	}
+
+	return bw.Flush()
+}
+
+// MetricsHandler returns a HTTP handler serving
+// the statistics of the controllers of m for Prometheus.
+// Errors writing the response are logged to the log of m.
+func MetricsHandler(m *DeviceManager) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		if err := WriteMetrics(w, m.Stats()); err != nil {
+			m.logf("writing metrics: %v", err)
+		}
+	})
+}
+
+func labels(s *DeviceStats) string {
+	return "serial=" + strconv.Quote(s.Serial) + ",conn=" + strconv.Quote(s.ConnString())
+}
+
+func fmtFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
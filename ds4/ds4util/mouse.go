@@ -0,0 +1,239 @@
+package ds4util
+
+import (
+	"math"
+	"time"
+
+	"github.com/tajtiattila/hid/ds4"
+)
+
+// MouseButton is a pointer button.
+type MouseButton int
+
+const (
+	MouseLeft MouseButton = iota + 1
+	MouseRight
+	MouseMiddle
+)
+
+var mouseButtonStr = []string{"", "left", "right", "middle"}
+
+func (b MouseButton) String() string {
+	if b > 0 && int(b) < len(mouseButtonStr) {
+		return mouseButtonStr[b]
+	}
+	return "button?"
+}
+
+// MouseEvent is a pointer event.
+// It is either a relative motion, or a button press or release
+// if Button is nonzero.
+type MouseEvent struct {
+	Time time.Time
+
+	// DX and DY is the relative motion in pointer units.
+	DX, DY int
+
+	Button  MouseButton
+	Pressed bool
+}
+
+// MouseHandler receives pointer events.
+type MouseHandler interface {
+	MouseEvent(e *MouseEvent)
+}
+
+// PointerAccel returns an acceleration curve for Mouse.Accel.
+// The gain is 1 below threshold, and increases by rate
+// for each 1000 units per second above it, up to max.
+func PointerAccel(threshold, rate, max float64) Curve {
+	return func(speed float64) float64 {
+		if speed <= threshold {
+			return 1
+		}
+		return math.Min(1+rate*(speed-threshold)/1000, max)
+	}
+}
+
+// Mouse converts touchpad input into pointer events.
+//
+// The first finger on the touchpad moves the pointer.
+// A short touch without movement is a left click, and a two-finger
+// tap is a right click. Clicking the touchpad with one finger presses
+// the left button, and with two fingers the right button.
+// It uses a ReportClock.
+type Mouse struct {
+	Handler MouseHandler
+
+	// Sensitivity is the pointer units per touchpad unit.
+	Sensitivity float64
+
+	// Accel maps the finger speed in touchpad units per second
+	// to the gain of the pointer motion. The gain is 1 if Accel is nil.
+	Accel Curve
+
+	// TapToClick enables clicking by tapping.
+	TapToClick bool
+
+	// TapTime and TapDist are the longest duration
+	// and movement of a tap.
+	TapTime time.Duration
+	TapDist int
+
+	clock ReportClock
+	now   time.Time
+	init  bool
+	pkt   byte
+	tl    time.Time // time of last touch update
+
+	ptr    byte // pointer finger id
+	down   bool // pointer finger down
+	px, py int  // last pointer finger position
+	fx, fy float64
+
+	// touch sequence from first finger down to all fingers up
+	t0      time.Time
+	travel  int
+	fingers int  // max fingers
+	clicked bool // touchpad clicked
+
+	button MouseButton // pressed by touchpad click
+}
+
+// NewMouse returns a new Mouse with tap to click enabled.
+func NewMouse(h MouseHandler) *Mouse {
+	return &Mouse{
+		Handler:     h,
+		Sensitivity: 1,
+		TapToClick:  true,
+		TapTime:     DefaultGestureConfig.TapTime,
+		TapDist:     DefaultGestureConfig.TapDist,
+	}
+}
+
+// HandleState converts the touches and touchpad click of s into pointer events.
+func (m *Mouse) HandleState(s *ds4.State) {
+	m.now = m.clock.Time(s)
+
+	var cur []ds4.Touch
+	for _, t := range s.Touch {
+		if t.Active() {
+			cur = append(cur, t)
+		}
+	}
+
+	if !m.init || s.Packet != m.pkt {
+		m.init, m.pkt = true, s.Packet
+		m.touch(cur)
+		m.tl = m.now
+	}
+
+	m.click(s.Button&ds4.Click != 0, len(cur))
+}
+
+// Reset resets the touch state and releases buttons pressed.
+func (m *Mouse) Reset() {
+	if m.button != 0 {
+		m.emit(MouseEvent{Button: m.button})
+	}
+	m.clock.Reset()
+	m.init, m.down, m.fingers, m.button = false, false, 0, 0
+	m.fx, m.fy = 0, 0
+}
+
+func (m *Mouse) touch(cur []ds4.Touch) {
+	if len(cur) == 0 {
+		if m.fingers != 0 {
+			m.release()
+		}
+		m.down, m.fingers = false, 0
+		return
+	}
+
+	if m.fingers == 0 {
+		m.t0, m.travel, m.clicked = m.now, 0, false
+	}
+	if len(cur) > m.fingers {
+		m.fingers = len(cur)
+	}
+
+	var p *ds4.Touch
+	for i := range cur {
+		if m.down && cur[i].Id == m.ptr {
+			p = &cur[i]
+		}
+	}
+	if p == nil {
+		// new pointer finger
+		p = &cur[0]
+		m.ptr, m.down = p.Id, true
+		m.px, m.py = int(p.X), int(p.Y)
+		m.fx, m.fy = 0, 0
+		return
+	}
+
+	x, y := int(p.X), int(p.Y)
+	dx, dy := x-m.px, y-m.py
+	m.px, m.py = x, y
+	if dx == 0 && dy == 0 {
+		return
+	}
+	m.travel += abs(dx) + abs(dy)
+	if len(cur) > 1 {
+		return
+	}
+
+	gain := m.Sensitivity
+	if dt := m.now.Sub(m.tl).Seconds(); m.Accel != nil && dt > 0 {
+		gain *= m.Accel(math.Hypot(float64(dx), float64(dy)) / dt)
+	}
+	m.fx += float64(dx) * gain
+	m.fy += float64(dy) * gain
+	ix, iy := math.Trunc(m.fx), math.Trunc(m.fy)
+	m.fx -= ix
+	m.fy -= iy
+	if ix != 0 || iy != 0 {
+		m.emit(MouseEvent{DX: int(ix), DY: int(iy)})
+	}
+}
+
+// release handles the end of a touch sequence.
+func (m *Mouse) release() {
+	if !m.TapToClick || m.clicked || m.travel > m.TapDist ||
+		m.now.Sub(m.t0) > m.TapTime {
+		return
+	}
+	b := MouseLeft
+	if m.fingers > 1 {
+		b = MouseRight
+	}
+	m.emit(MouseEvent{Button: b, Pressed: true})
+	m.emit(MouseEvent{Button: b})
+}
+
+func (m *Mouse) click(click bool, nf int) {
+	switch {
+	case click && m.button == 0:
+		m.button = MouseLeft
+		if nf > 1 {
+			m.button = MouseRight
+		}
+		m.clicked = true
+		m.emit(MouseEvent{Button: m.button, Pressed: true})
+	case !click && m.button != 0:
+		m.emit(MouseEvent{Button: m.button})
+		m.button = 0
+	}
+}
+
+func (m *Mouse) emit(e MouseEvent) {
+	e.Time = m.now
+	m.Handler.MouseEvent(&e)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,125 @@
+package ds4util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMouse(t *testing.T) {
+	tests := []struct {
+		name  string
+		m     Mouse
+		steps []touchStep
+		want  []string
+	}{
+		{
+			name: "move",
+			m:    Mouse{Sensitivity: 0.5},
+			steps: []touchStep{
+				{0, []finger{{1, 500, 400}}, false},
+				{10, []finger{{1, 520, 401}}, false},
+				{20, []finger{{1, 540, 402}}, false},
+				{30, nil, false},
+			},
+			want: []string{"move +10 +0", "move +10 +1"},
+		},
+		{
+			name: "second finger does not move",
+			m:    Mouse{Sensitivity: 1},
+			steps: []touchStep{
+				{0, []finger{{1, 500, 400}}, false},
+				{10, []finger{{1, 510, 400}}, false},
+				{20, []finger{{1, 510, 400}, {2, 800, 400}}, false},
+				{30, []finger{{1, 530, 400}, {2, 900, 400}}, false},
+				{40, []finger{{2, 950, 400}}, false}, // becomes the pointer
+				{50, []finger{{2, 960, 400}}, false},
+				{60, nil, false},
+			},
+			want: []string{"move +10 +0", "move +10 +0"},
+		},
+		{
+			name: "acceleration",
+			m:    Mouse{Sensitivity: 1, Accel: PointerAccel(1000, 1, 3)},
+			steps: []touchStep{
+				{0, []finger{{1, 500, 400}}, false},
+				{10, []finger{{1, 505, 400}}, false}, // 500/s
+				{20, []finger{{1, 525, 400}}, false}, // 2000/s
+				{30, []finger{{1, 625, 400}}, false}, // 10000/s
+				{40, nil, false},
+			},
+			want: []string{"move +5 +0", "move +40 +0", "move +300 +0"},
+		},
+		{
+			name: "tap",
+			m:    *NewMouse(nil),
+			steps: []touchStep{
+				{0, []finger{{1, 500, 400}}, false},
+				{50, []finger{{1, 502, 400}}, false},
+				{100, nil, false},
+			},
+			want: []string{"move +2 +0", "press left", "release left"},
+		},
+		{
+			name: "two finger tap",
+			m:    *NewMouse(nil),
+			steps: []touchStep{
+				{0, []finger{{1, 500, 400}}, false},
+				{20, []finger{{1, 500, 400}, {2, 700, 400}}, false},
+				{40, []finger{{2, 700, 400}}, false},
+				{60, nil, false},
+			},
+			want: []string{"press right", "release right"},
+		},
+		{
+			name: "slow tap",
+			m:    *NewMouse(nil),
+			steps: []touchStep{
+				{0, []finger{{1, 500, 400}}, false},
+				{300, nil, false},
+			},
+			want: nil,
+		},
+		{
+			name: "tap disabled",
+			m:    Mouse{Sensitivity: 1},
+			steps: []touchStep{
+				{0, []finger{{1, 500, 400}}, false},
+				{50, nil, false},
+			},
+			want: nil,
+		},
+		{
+			name: "click",
+			m:    *NewMouse(nil),
+			steps: []touchStep{
+				{0, []finger{{1, 500, 400}}, false},
+				{20, []finger{{1, 500, 400}}, true},
+				{40, []finger{{1, 500, 400}}, false},
+				{60, nil, false},
+			},
+			want: []string{"press left", "release left"},
+		},
+		{
+			name: "two finger click",
+			m:    *NewMouse(nil),
+			steps: []touchStep{
+				{0, []finger{{1, 500, 400}, {2, 700, 400}}, false},
+				{200, []finger{{1, 500, 400}, {2, 700, 400}}, true},
+				{400, []finger{{1, 500, 400}}, true},
+				{500, nil, false},
+			},
+			want: []string{"press right", "release right"},
+		},
+	}
+	for _, tt := range tests {
+		var r recorder
+		m := tt.m
+		m.Handler = &r
+		for _, s := range touchStates(tt.steps) {
+			m.HandleState(&s)
+		}
+		if !reflect.DeepEqual(r.calls, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, r.calls, tt.want)
+		}
+	}
+}
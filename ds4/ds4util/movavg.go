@@ -41,8 +41,13 @@ func NewMovAvg(n, m int, d time.Duration) *MovAvg {
 	}
 }
 
+// Filter filters v using the current time as the sample time.
 func (a *MovAvg) Filter(v []int) []int {
-	t := time.Now()
+	return a.FilterAt(time.Now(), v)
+}
+
+// FilterAt filters v sampled at t.
+func (a *MovAvg) FilterAt(t time.Time, v []int) []int {
 	a.push(t, v)
 	if a.w == a.r {
 		a.pop()
@@ -0,0 +1,615 @@
+package profile
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/tajtiattila/hid/ds4"
+	"github.com/tajtiattila/hid/ds4/ds4util"
+	"github.com/tajtiattila/hid/ds4/ds4util/uinput"
+)
+
+// defaultThreshold is used for stick directions and triggers
+// with digital outputs if the binding has no threshold.
+const defaultThreshold = 0.5
+
+// defaultStickSpeed is the pointer speed in units per second
+// of sticks at full deflection.
+const defaultStickSpeed = 1000
+
+// Feedback sets the controller lightbar and rumble.
+// It is implemented by ds4.Device.
+type Feedback interface {
+	Output() ds4.Output
+	SetOutput(o *ds4.Output) error
+}
+
+// Outputs are the devices driven by an Engine.
+// Events for nil outputs are dropped.
+type Outputs struct {
+	Gamepad, Keyboard, Mouse uinput.Emitter
+
+	Feedback Feedback
+}
+
+// Engine runs a profile on controller states.
+// It implements ds4util.StateHandler.
+type Engine struct {
+	p   *Profile
+	out uinput.Output
+	fb  Feedback
+
+	sets map[string]*bindingSet // by layer, "" for base
+
+	clock    ds4util.ReportClock
+	t        time.Time
+	dt       float64
+	sticks   [2]ds4util.Stick
+	triggers [2]ds4util.Trigger
+	regions  ds4util.TouchRegions
+	gestures *ds4util.Gestures
+	mouse    *ds4util.Mouse
+	aim      *ds4util.GyroAim
+
+	// inputs of the current state
+	buttons  uint32
+	stick    [2]ds4util.Vec
+	trigger  [2]float64
+	rs       []ds4util.RegionState
+	fired    []ds4util.Gesture
+	aimDX    float64
+	aimDY    float64
+	touchpad bool // touchpad drives the pointer
+
+	down    map[*Binding]bool
+	count   map[output]int
+	toggled string
+
+	axes [6]float64
+
+	fbActive []output
+	fbSaved  *ds4.Output
+	fbDirty  bool
+
+	err error
+}
+
+// bindingSet is the set of bindings active in a layer.
+type bindingSet struct {
+	list     []*Binding
+	set      map[*Binding]bool
+	touchpad *Binding // binding of the touchpad input
+}
+
+// gamepad axes in Engine.axes
+var padAxes = [8]uint16{
+	uinput.ABS_X, uinput.ABS_Y, uinput.ABS_RX, uinput.ABS_RY,
+	uinput.ABS_Z, uinput.ABS_RZ, uinput.ABS_HAT0X, uinput.ABS_HAT0Y,
+}
+
+// NewEngine returns a new engine running p with out.
+func NewEngine(p *Profile, out Outputs) *Engine {
+	e := &Engine{
+		p:     p,
+		out:   uinput.Output{Gamepad: out.Gamepad, Keyboard: out.Keyboard, Mouse: out.Mouse},
+		fb:    out.Feedback,
+		sets:  make(map[string]*bindingSet),
+		down:  make(map[*Binding]bool),
+		count: make(map[output]int),
+	}
+
+	dz := p.Deadzone
+	e.sticks[0].Deadzone = dz.LeftStick
+	e.sticks[1].Deadzone = dz.RightStick
+	e.triggers[0].Deadzone = dz.L2
+	e.triggers[1].Deadzone = dz.R2
+	e.regions.Regions = p.Regions
+
+	e.gestures = ds4util.NewGestures((*engineEvents)(e))
+	e.mouse = ds4util.NewMouse((*engineEvents)(e))
+	g := p.Gyro
+	e.aim = &ds4util.GyroAim{
+		Space:       g.Space,
+		Sensitivity: g.Sensitivity,
+		Tightening:  g.Tightening,
+		Ratchet:     g.Ratchet,
+	}
+	if g.Smoothing > 0 {
+		e.aim.Smoothing = &ds4util.LowPass{Alpha: 1 - g.Smoothing}
+	}
+
+	e.sets[""] = newBindingSet("", p.Bindings, nil)
+	for _, l := range p.Layers {
+		e.sets[l.Name] = newBindingSet(l.Name, p.Bindings, l.Bindings)
+	}
+	return e
+}
+
+// newBindingSet returns the set of base bindings
+// not overridden by layer, and the layer bindings.
+// Base bindings activating the layer name are kept,
+// otherwise the layer would turn itself off.
+func newBindingSet(name string, base, layer []*Binding) *bindingSet {
+	over := make(map[input]bool)
+	for _, b := range layer {
+		over[b.in] = true
+	}
+	s := &bindingSet{set: make(map[*Binding]bool)}
+	add := func(b *Binding) {
+		s.list = append(s.list, b)
+		s.set[b] = true
+		if b.in.kind == inTouchpad {
+			s.touchpad = b
+		}
+	}
+	for _, b := range base {
+		if !over[b.in] || activates(b, name) {
+			add(b)
+		}
+	}
+	for _, b := range layer {
+		add(b)
+	}
+	return s
+}
+
+// activates reports whether b activates or toggles the layer name.
+func activates(b *Binding, name string) bool {
+	for _, o := range b.out {
+		if (o.kind == outLayer || o.kind == outToggle) && o.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Layer returns the name of the active layer, or "" for the base layer.
+func (e *Engine) Layer() string {
+	for _, l := range e.p.Layers {
+		if e.count[output{kind: outLayer, name: l.Name}] > 0 {
+			return l.Name
+		}
+	}
+	return e.toggled
+}
+
+// State runs the profile on s.
+func (e *Engine) State(s *ds4.State) error {
+	e.err = nil
+
+	t := e.clock.Time(s)
+	e.dt = 0
+	if !e.t.IsZero() {
+		e.dt = t.Sub(e.t).Seconds()
+	}
+	e.t = t
+
+	bs := e.sets[e.Layer()]
+	e.touchpad = bs.touchpad != nil
+	if e.touchpad {
+		e.mouse.Sensitivity = sensitivity(bs.touchpad, 1)
+	}
+	e.update(s)
+
+	for b, down := range e.down {
+		if down && !bs.set[b] {
+			e.release(b)
+			delete(e.down, b)
+		}
+	}
+
+	for i := range e.axes {
+		e.axes[i] = 0
+	}
+	var fired []*Binding
+	for _, b := range bs.list {
+		if len(b.out) != 0 && b.out[0].analog() {
+			e.analog(b)
+			continue
+		}
+		on := e.pressed(b)
+		if b.in.kind == inGesture {
+			if on {
+				e.press(b)
+				fired = append(fired, b)
+			}
+			continue
+		}
+		if on != e.down[b] {
+			if on {
+				e.press(b)
+			} else {
+				e.release(b)
+			}
+			e.down[b] = on
+		}
+	}
+	e.flush()
+
+	// gestures are released in a separate report
+	if len(fired) != 0 {
+		for _, b := range fired {
+			e.release(b)
+		}
+		e.flush()
+	}
+	return e.err
+}
+
+// Close releases the outputs pressed, restores the controller feedback,
+// and closes the outputs implementing io.Closer.
+func (e *Engine) Close() error {
+	for b, down := range e.down {
+		if down {
+			e.release(b)
+		}
+	}
+	e.down = make(map[*Binding]bool)
+	e.flush()
+	e.check(e.out.Close())
+	return e.err
+}
+
+// update processes the inputs in s.
+func (e *Engine) update(s *ds4.State) {
+	e.buttons = ds4util.Buttons(s.Button) &^ (ds4.L2 | ds4.R2)
+
+	e.stick[0] = e.sticks[0].Left(s)
+	e.stick[1] = e.sticks[1].Right(s)
+	e.trigger[0] = e.triggers[0].Update(s.L2).Value
+	e.trigger[1] = e.triggers[1].Update(s.R2).Value
+
+	if len(e.p.Regions) != 0 {
+		e.rs = e.regions.Update(s)
+	}
+	e.fired = e.fired[:0]
+	e.gestures.HandleState(s)
+	e.mouse.HandleState(s)
+	e.aimDX, e.aimDY = e.aim.Update(s)
+}
+
+// pressed reports whether the digital input of b is pressed.
+func (e *Engine) pressed(b *Binding) bool {
+	in := &b.in
+	th := b.Threshold
+	if th == 0 {
+		th = defaultThreshold
+	}
+	switch in.kind {
+	case inButton:
+		return e.buttons&in.bits == in.bits
+	case inTrigger:
+		return e.trigger[in.index] >= th
+	case inStickDir:
+		v := e.stick[in.index]
+		return v.X*in.dir.X+v.Y*in.dir.Y >= th
+	case inTouch:
+		return e.rs[in.index].Touched
+	case inClick:
+		return e.rs[in.index].Clicked
+	case inGesture:
+		for _, g := range e.fired {
+			if g.Kind == in.gesture && (in.edge == 0 || in.edge == g.Edge) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// analog drives the analog outputs of b.
+func (e *Engine) analog(b *Binding) {
+	in := &b.in
+	for _, o := range b.out {
+		switch {
+		case o.kind == outMove && in.kind == inStick:
+			v := e.stick[in.index]
+			sp := sensitivity(b, defaultStickSpeed) * e.dt
+			e.out.Move(v.X*sp, v.Y*sp)
+		case o.kind == outMove && in.kind == inGyro:
+			sens := sensitivity(b, 1)
+			e.out.Move(e.aimDX*sens, e.aimDY*sens)
+		case o.kind == outStick:
+			v := e.stick[in.index]
+			e.axes[2*o.value] += v.X * uinput.StickMax
+			e.axes[2*o.value+1] += v.Y * uinput.StickMax
+		case o.kind == outTrigger:
+			e.axes[4+o.value] += e.trigger[in.index] * uinput.TriggerMax
+		}
+		// touchpad movement is handled by engineEvents
+	}
+}
+
+func sensitivity(b *Binding, def float64) float64 {
+	if b.Sensitivity != 0 {
+		return b.Sensitivity
+	}
+	return def
+}
+
+// press presses the outputs of b and plays its macro.
+func (e *Engine) press(b *Binding) {
+	for _, o := range b.out {
+		e.begin(o)
+	}
+	for _, o := range b.macro {
+		e.begin(o)
+		e.sync()
+		e.end(o)
+		e.sync()
+	}
+}
+
+// release releases the outputs of b.
+func (e *Engine) release(b *Binding) {
+	for _, o := range b.out {
+		e.end(o)
+	}
+}
+
+// begin starts o unless it is already active.
+func (e *Engine) begin(o output) {
+	e.count[o]++
+	if e.count[o] > 1 {
+		return
+	}
+	switch o.kind {
+	case outKey:
+		e.out.Key(o.code, true)
+	case outWheel:
+		e.out.Wheel(int32(o.value))
+	case outToggle:
+		if e.toggled == o.name {
+			e.toggled = ""
+		} else {
+			e.toggled = o.name
+		}
+	case outLED, outRumble:
+		e.fbActive = append(e.fbActive, o)
+		e.fbDirty = true
+	}
+}
+
+// end stops o when it is not used anymore.
+func (e *Engine) end(o output) {
+	if e.count[o] == 0 {
+		return
+	}
+	e.count[o]--
+	if e.count[o] > 0 {
+		return
+	}
+	delete(e.count, o)
+	switch o.kind {
+	case outKey:
+		e.out.Key(o.code, false)
+	case outLED, outRumble:
+		for i, x := range e.fbActive {
+			if x == o {
+				e.fbActive = append(e.fbActive[:i], e.fbActive[i+1:]...)
+				break
+			}
+		}
+		e.fbDirty = true
+	}
+}
+
+// flush sends the gamepad axes and feedback, and syncs the outputs.
+func (e *Engine) flush() {
+	if e.out.Gamepad != nil {
+		var v [8]float64
+		copy(v[:], e.axes[:])
+		v[0] = clamp(v[0], -uinput.StickMax-1, uinput.StickMax)
+		v[1] = clamp(v[1], -uinput.StickMax-1, uinput.StickMax)
+		v[2] = clamp(v[2], -uinput.StickMax-1, uinput.StickMax)
+		v[3] = clamp(v[3], -uinput.StickMax-1, uinput.StickMax)
+		v[4] = clamp(v[4], 0, uinput.TriggerMax)
+		v[5] = clamp(v[5], 0, uinput.TriggerMax)
+		v[6] = e.hat(uinput.DpadRight) - e.hat(uinput.DpadLeft)
+		v[7] = e.hat(uinput.DpadDown) - e.hat(uinput.DpadUp)
+		for i, x := range v {
+			e.out.Abs(padAxes[i], x)
+		}
+	}
+
+	if e.fbDirty && e.fb != nil {
+		e.fbDirty = false
+		e.feedback()
+	}
+
+	e.sync()
+}
+
+func (e *Engine) hat(bit int) float64 {
+	if e.count[output{kind: outHat, value: bit}] > 0 {
+		return 1
+	}
+	return 0
+}
+
+// feedback sets the controller output from the active feedback outputs,
+// or restores the original output when there are none.
+func (e *Engine) feedback() {
+	if len(e.fbActive) == 0 {
+		if e.fbSaved != nil {
+			e.check(e.fb.SetOutput(e.fbSaved))
+			e.fbSaved = nil
+		}
+		return
+	}
+	if e.fbSaved == nil {
+		o := e.fb.Output()
+		e.fbSaved = &o
+	}
+	o := *e.fbSaved
+	o.On, o.Off = 0, 0
+	for _, x := range e.fbActive {
+		switch x.kind {
+		case outLED:
+			o.Led = x.color
+		case outRumble:
+			o.Light, o.Heavy = x.light, x.heavy
+		}
+	}
+	e.check(e.fb.SetOutput(&o))
+}
+
+// sync delivers the pending events of the outputs.
+func (e *Engine) sync() {
+	e.check(e.out.Sync())
+}
+
+func (e *Engine) check(err error) {
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+func clamp(x, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(x, hi))
+}
+
+// engineEvents receives pointer and gesture events for Engine.
+type engineEvents Engine
+
+func (x *engineEvents) MouseEvent(ev *ds4util.MouseEvent) {
+	e := (*Engine)(x)
+	// releases are processed after the touchpad binding
+	// is deactivated, so that buttons are not left pressed
+	if !e.touchpad && (ev.Button == 0 || ev.Pressed) {
+		return
+	}
+	if ev.Button == 0 {
+		e.out.Move(float64(ev.DX), float64(ev.DY))
+		return
+	}
+	code := map[ds4util.MouseButton]uint16{
+		ds4util.MouseLeft:   uinput.BTN_LEFT,
+		ds4util.MouseRight:  uinput.BTN_RIGHT,
+		ds4util.MouseMiddle: uinput.BTN_MIDDLE,
+	}[ev.Button]
+	o := output{kind: outKey, dev: mouseDev, code: code}
+	if ev.Pressed {
+		e.begin(o)
+	} else {
+		e.end(o)
+	}
+}
+
+func (x *engineEvents) Gesture(g *ds4util.Gesture) {
+	e := (*Engine)(x)
+	e.fired = append(e.fired, *g)
+}
+
+// Handler runs profiles on controllers managed by a ds4util.DeviceManager.
+// It implements ds4util.ConnectHandler.
+type Handler struct {
+	// Profiles holds the profiles by name.
+	Profiles map[string]*Profile
+
+	// Default is the name of the profile used for controllers
+	// without a profile in their settings.
+	Default string
+
+	// Outputs returns the outputs for the controller d running p.
+	// DeviceOutputs is used if Outputs is nil.
+	Outputs func(p *Profile, d *ds4.Device, e ds4util.Entry) (Outputs, error)
+}
+
+// Connect returns an Engine running the profile of the controller.
+// The profile deadzones are taken from the controller settings
+// if the profile has none.
+func (h *Handler) Connect(d *ds4.Device, e ds4util.Entry) (ds4util.StateHandler, error) {
+	name := h.Default
+	if e.Settings != nil && e.Settings.Profile != "" {
+		name = e.Settings.Profile
+	}
+	p, ok := h.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile: unknown profile %q", name)
+	}
+	if p.Deadzone == (ds4util.Deadzone{}) && e.Settings != nil {
+		q := *p
+		q.Deadzone = e.Settings.Deadzone
+		p = &q
+	}
+	outputs := h.Outputs
+	if outputs == nil {
+		outputs = DeviceOutputs
+	}
+	out, err := outputs(p, d, e)
+	if err != nil {
+		return nil, err
+	}
+	return NewEngine(p, out), nil
+}
+
+// Uses reports which output devices p uses.
+func (p *Profile) Uses() (gamepad, keyboard, mouse bool) {
+	var used [numDev]bool
+	all := [][]*Binding{p.Bindings}
+	for _, l := range p.Layers {
+		all = append(all, l.Bindings)
+	}
+	for _, v := range all {
+		for _, b := range v {
+			if b.in.kind == inTouchpad {
+				used[mouseDev] = true
+			}
+			for _, o := range append(b.out[:len(b.out):len(b.out)], b.macro...) {
+				switch o.kind {
+				case outKey:
+					used[o.dev] = true
+				case outWheel, outMove:
+					used[mouseDev] = true
+				case outHat, outStick, outTrigger:
+					used[gamepadDev] = true
+				}
+			}
+		}
+	}
+	return used[gamepadDev], used[keyboardDev], used[mouseDev]
+}
+
+// DeviceOutputs creates the virtual devices used by p,
+// and uses d for feedback.
+func DeviceOutputs(p *Profile, d *ds4.Device, e ds4util.Entry) (Outputs, error) {
+	var out Outputs
+	if d != nil {
+		out.Feedback = d
+	}
+	gamepad, keyboard, mouse := p.Uses()
+	name := "DS4 " + e.DisplayName()
+	var err error
+	create := func(c *uinput.Config) uinput.Emitter {
+		if err != nil {
+			return nil
+		}
+		var dev *uinput.Device
+		dev, err = uinput.Create(c)
+		if err != nil {
+			return nil
+		}
+		return dev
+	}
+	if gamepad {
+		out.Gamepad = create(uinput.GamepadConfig(name + " gamepad"))
+	}
+	if keyboard {
+		out.Keyboard = create(uinput.KeyboardConfig(name + " keyboard"))
+	}
+	if mouse {
+		out.Mouse = create(uinput.MouseConfig(name + " mouse"))
+	}
+	if err != nil {
+		for _, o := range []uinput.Emitter{out.Gamepad, out.Keyboard, out.Mouse} {
+			if c, ok := o.(io.Closer); ok {
+				c.Close()
+			}
+		}
+		return Outputs{}, err
+	}
+	return out, nil
+}
@@ -0,0 +1,317 @@
+package profile
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/tajtiattila/hid/ds4"
+)
+
+// recorder records events, sorted within each report.
+type recorder struct {
+	ev      []string
+	reports [][]string
+}
+
+func (r *recorder) Emit(typ, code uint16, value int32) error {
+	r.ev = append(r.ev, fmt.Sprintf("%d:%#x=%d", typ, code, value))
+	return nil
+}
+
+func (r *recorder) Sync() error {
+	sort.Strings(r.ev)
+	r.reports = append(r.reports, r.ev)
+	r.ev = nil
+	return nil
+}
+
+func (r *recorder) take() [][]string {
+	v := r.reports
+	r.reports = nil
+	return v
+}
+
+type feedback struct {
+	o   ds4.Output
+	set []ds4.Output
+}
+
+func (f *feedback) Output() ds4.Output { return f.o }
+
+func (f *feedback) SetOutput(o *ds4.Output) error {
+	f.o = *o
+	f.set = append(f.set, *o)
+	return nil
+}
+
+// controller produces states 4ms apart.
+type controller struct {
+	s ds4.State
+}
+
+func newController() *controller {
+	return &controller{s: ds4.State{LX: 128, LY: 128, RX: 128, RY: 128, Button: ds4.DpadOff}}
+}
+
+func (c *controller) next(f func(s *ds4.State)) *ds4.State {
+	c.s.Packet++
+	c.s.Timestamp += 750
+	if f != nil {
+		f(&c.s)
+	}
+	s := c.s
+	return &s
+}
+
+func TestEngine(t *testing.T) {
+	p, err := Parse("fps.json", []byte(testProfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pad, kbd, mouse recorder
+	fb := &feedback{o: ds4.Output{Led: ds4.Color{B: 64}}}
+	e := NewEngine(p, Outputs{Gamepad: &pad, Keyboard: &kbd, Mouse: &mouse, Feedback: fb})
+	c := newController()
+
+	e.State(c.next(nil))
+	pad.take()
+
+	tests := []struct {
+		name  string
+		f     func(s *ds4.State)
+		layer string
+		kbd   [][]string
+		mouse [][]string
+		pad   [][]string
+	}{
+		{
+			name: "cross",
+			f:    func(s *ds4.State) { s.Button |= ds4.Cross },
+			kbd:  [][]string{{"1:0x39=1"}},
+		},
+		{
+			name:  "hold shift",
+			f:     func(s *ds4.State) { s.Button |= ds4.R1 },
+			layer: "shift",
+		},
+		{
+			name:  "shift cross",
+			layer: "shift",
+			kbd:   [][]string{{"1:0x1d=1", "1:0x2e=1", "1:0x39=0"}},
+		},
+		{
+			name:  "macro",
+			f:     func(s *ds4.State) { s.Button |= ds4.Circle },
+			layer: "shift",
+			kbd:   [][]string{{"1:0x23=1"}, {"1:0x23=0"}, {"1:0x17=1"}, {"1:0x17=0"}},
+		},
+		{
+			name: "release shift",
+			f:    func(s *ds4.State) { s.Button &^= ds4.R1 | ds4.Circle },
+		},
+		{
+			name: "base cross",
+			kbd:  [][]string{{"1:0x1d=0", "1:0x2e=0", "1:0x39=1"}},
+		},
+		{
+			name:  "toggle menu",
+			f:     func(s *ds4.State) { s.Button = ds4.DpadOff | ds4.Share },
+			layer: "menu",
+			kbd:   [][]string{{"1:0x39=0"}},
+		},
+		{
+			name:  "menu up",
+			f:     func(s *ds4.State) { s.Button = 0 },
+			layer: "menu",
+			kbd:   [][]string{{"1:0x67=1"}},
+		},
+		{
+			name: "toggle base",
+			f:    func(s *ds4.State) { s.Button = ds4.DpadOff | ds4.Share },
+			kbd:  [][]string{{"1:0x67=0"}},
+		},
+		{
+			name: "stick below threshold",
+			f:    func(s *ds4.State) { s.Button = ds4.DpadOff; s.LY = 100 },
+		},
+		{
+			name: "stick up",
+			f:    func(s *ds4.State) { s.LY = 0 },
+			kbd:  [][]string{{"1:0x11=1"}},
+		},
+		{
+			name:  "trigger",
+			f:     func(s *ds4.State) { s.LY = 128; s.L2, s.R2 = 255, 255 },
+			kbd:   [][]string{{"1:0x11=0"}},
+			mouse: [][]string{{"1:0x110=1"}},
+			pad:   [][]string{{"3:0x2=255"}},
+		},
+		{
+			name:  "right stick",
+			f:     func(s *ds4.State) { s.L2, s.R2 = 0, 0; s.RX = 255 },
+			mouse: [][]string{{"1:0x110=0"}},
+			pad:   [][]string{{"3:0x2=0", "3:0x3=32767"}},
+		},
+	}
+	for _, tt := range tests {
+		e.State(c.next(tt.f))
+		if l := e.Layer(); l != tt.layer {
+			t.Errorf("%s: got layer %q, want %q", tt.name, l, tt.layer)
+		}
+		if got := kbd.take(); !reflect.DeepEqual(got, tt.kbd) {
+			t.Errorf("%s: got keyboard %v, want %v", tt.name, got, tt.kbd)
+		}
+		if got := mouse.take(); !reflect.DeepEqual(got, tt.mouse) {
+			t.Errorf("%s: got mouse %v, want %v", tt.name, got, tt.mouse)
+		}
+		if got := pad.take(); !reflect.DeepEqual(got, tt.pad) {
+			t.Errorf("%s: got gamepad %v, want %v", tt.name, got, tt.pad)
+		}
+	}
+
+	e.State(c.next(func(s *ds4.State) { s.RX = 128; s.Button |= ds4.PS }))
+	e.State(c.next(func(s *ds4.State) { s.Button &^= ds4.PS }))
+	want := []ds4.Output{
+		{Led: ds4.Color{R: 255}, Heavy: 64},
+		{Led: ds4.Color{B: 64}},
+	}
+	if !reflect.DeepEqual(fb.set, want) {
+		t.Errorf("got feedback %v, want %v", fb.set, want)
+	}
+}
+
+func TestEngineStickMove(t *testing.T) {
+	p, err := Parse("move.json", []byte(`{"bindings": [
+		{"input": "RightStick", "output": "mouse:move", "sensitivity": 500}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mouse recorder
+	e := NewEngine(p, Outputs{Mouse: &mouse})
+	c := newController()
+	e.State(c.next(nil))
+
+	// 500 units/s for 4ms is 2 units per report
+	var x int
+	for i := 0; i < 10; i++ {
+		e.State(c.next(func(s *ds4.State) { s.RX = 255 }))
+	}
+	for _, r := range mouse.take() {
+		for _, ev := range r {
+			var v int
+			fmt.Sscanf(ev, "2:0x0=%d", &v)
+			x += v
+		}
+	}
+	if x != 20 {
+		t.Errorf("got x = %d, want 20", x)
+	}
+}
+
+func TestEngineClose(t *testing.T) {
+	p, err := Parse("fps.json", []byte(testProfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kbd recorder
+	e := NewEngine(p, Outputs{Keyboard: &kbd})
+	c := newController()
+	e.State(c.next(func(s *ds4.State) { s.Button |= ds4.Cross }))
+	e.Close()
+	want := [][]string{{"1:0x39=1"}, {"1:0x39=0"}}
+	if got := kbd.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestEngineTouchpad(t *testing.T) {
+	p, err := Parse("touch.json", []byte(`{
+		"bindings": [
+			{"input": "gesture:tap", "output": "key:enter"},
+			{"input": "R1", "output": "layer:pointer"}
+		],
+		"layers": {
+			"pointer": [{"input": "touchpad", "output": "mouse:move"}]
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kbd, mouse recorder
+	e := NewEngine(p, Outputs{Keyboard: &kbd, Mouse: &mouse})
+	c := newController()
+	touch := func(s *ds4.State) { s.Touch[0] = ds4.Touch{Id: 1, X: 500, Y: 400} }
+	lift := func(s *ds4.State) {
+		s.Touch[0].Id |= ds4.TouchInactive
+		s.Touch[1].Id |= ds4.TouchInactive
+	}
+	e.State(c.next(lift))
+
+	// the gesture key is released in a separate report
+	e.State(c.next(touch))
+	e.State(c.next(lift))
+	want := [][]string{{"1:0x1c=1"}, {"1:0x1c=0"}}
+	if got := kbd.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("tap: got %v, want %v", got, want)
+	}
+
+	// the button clicked in the layer is released after leaving it
+	e.State(c.next(func(s *ds4.State) { s.Button |= ds4.R1 }))
+	e.State(c.next(func(s *ds4.State) { touch(s); s.Button |= ds4.Click }))
+	e.State(c.next(func(s *ds4.State) { s.Button &^= ds4.R1 }))
+	e.State(c.next(func(s *ds4.State) { lift(s); s.Button &^= ds4.Click }))
+	want = [][]string{{"1:0x110=1"}, {"1:0x110=0"}}
+	if got := mouse.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("click: got %v, want %v", got, want)
+	}
+	if l := e.Layer(); l != "" {
+		t.Errorf("got layer %q", l)
+	}
+	kbd.take()
+}
+
+func TestEngineLayerRebind(t *testing.T) {
+	p, err := Parse("rebind.json", []byte(`{
+		"bindings": [
+			{"input": "L1", "output": "layer:shift"},
+			{"input": "Share", "output": "toggle:menu"}
+		],
+		"layers": {
+			"shift": [{"input": "L1", "output": "key:a"}],
+			"menu": [{"input": "Share", "output": "key:m"}]
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kbd recorder
+	e := NewEngine(p, Outputs{Keyboard: &kbd})
+	c := newController()
+
+	for i := 0; i < 4; i++ {
+		e.State(c.next(func(s *ds4.State) { s.Button |= ds4.L1 }))
+		if l := e.Layer(); l != "shift" {
+			t.Errorf("hold %d: got layer %q, want shift", i, l)
+		}
+	}
+	e.State(c.next(func(s *ds4.State) { s.Button &^= ds4.L1 }))
+	want := [][]string{{"1:0x1e=1"}, {"1:0x1e=0"}}
+	if got := kbd.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("shift: got %v, want %v", got, want)
+	}
+
+	// the toggle still leaves the layer
+	e.State(c.next(func(s *ds4.State) { s.Button |= ds4.Share }))
+	e.State(c.next(func(s *ds4.State) { s.Button &^= ds4.Share }))
+	if l := e.Layer(); l != "menu" {
+		t.Errorf("got layer %q, want menu", l)
+	}
+	e.State(c.next(func(s *ds4.State) { s.Button |= ds4.Share }))
+	if l := e.Layer(); l != "" {
+		t.Errorf("got layer %q, want base", l)
+	}
+	kbd.take()
+}
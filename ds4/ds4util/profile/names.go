@@ -0,0 +1,284 @@
+package profile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tajtiattila/hid/ds4"
+	"github.com/tajtiattila/hid/ds4/ds4util"
+	"github.com/tajtiattila/hid/ds4/ds4util/uinput"
+)
+
+type inputKind int
+
+const (
+	_ inputKind = iota
+	inButton
+	inTrigger
+	inStick
+	inStickDir
+	inTouch
+	inClick
+	inGesture
+	inGyro
+	inTouchpad
+)
+
+// input is a parsed binding input.
+type input struct {
+	kind    inputKind
+	bits    uint32              // inButton
+	index   int                 // inTrigger, inStick, inStickDir: 0 left, 1 right; region index
+	dir     ds4util.Vec         // inStickDir
+	gesture ds4util.GestureKind // inGesture
+	edge    int                 // inGesture: edge of EdgeSwipe or zero for any
+}
+
+// analog reports whether in can drive analog outputs.
+func (in input) analog() bool {
+	switch in.kind {
+	case inTrigger, inStick, inGyro, inTouchpad:
+		return true
+	}
+	return false
+}
+
+// digital reports whether in can drive digital outputs.
+func (in input) digital() bool {
+	switch in.kind {
+	case inStick, inGyro, inTouchpad:
+		return false
+	}
+	return true
+}
+
+var buttonNames = map[string]uint32{
+	"cross":    ds4.Cross,
+	"circle":   ds4.Circle,
+	"square":   ds4.Square,
+	"triangle": ds4.Triangle,
+	"l1":       ds4.L1,
+	"r1":       ds4.R1,
+	"options":  ds4.Options,
+	"share":    ds4.Share,
+	"l3":       ds4.L3,
+	"r3":       ds4.R3,
+	"ps":       ds4.PS,
+	"click":    ds4.Click,
+
+	"dpad:up":    ds4util.DpadUp,
+	"dpad:down":  ds4util.DpadDown,
+	"dpad:left":  ds4util.DpadLeft,
+	"dpad:right": ds4util.DpadRight,
+}
+
+var stickDirs = map[string]ds4util.Vec{
+	"up":    {X: 0, Y: -1},
+	"down":  {X: 0, Y: 1},
+	"left":  {X: -1, Y: 0},
+	"right": {X: 1, Y: 0},
+}
+
+var edgeNames = map[string]int{
+	"left":   ds4util.SwipeLeft,
+	"right":  ds4util.SwipeRight,
+	"top":    ds4util.SwipeUp,
+	"bottom": ds4util.SwipeDown,
+}
+
+// parseInput parses the input name s.
+func parseInput(s string, regions []ds4util.Region) (input, error) {
+	name := strings.ToLower(s)
+	if bits, ok := buttonNames[name]; ok {
+		return input{kind: inButton, bits: bits}, nil
+	}
+	group, arg := name, ""
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		group, arg = name[:i], name[i+1:]
+	}
+	switch group {
+	case "l2", "r2":
+		if arg == "" {
+			return input{kind: inTrigger, index: strings.Index("lr", name[:1])}, nil
+		}
+	case "leftstick", "rightstick":
+		idx := 0
+		if group == "rightstick" {
+			idx = 1
+		}
+		if arg == "" {
+			return input{kind: inStick, index: idx}, nil
+		}
+		if d, ok := stickDirs[arg]; ok {
+			return input{kind: inStickDir, index: idx, dir: d}, nil
+		}
+	case "touch", "click":
+		for i, r := range regions {
+			if strings.EqualFold(r.Name, arg) {
+				k := inTouch
+				if group == "click" {
+					k = inClick
+				}
+				return input{kind: k, index: i}, nil
+			}
+		}
+		return input{}, fmt.Errorf("unknown touchpad region %q", arg)
+	case "gesture":
+		kind, edge := arg, ""
+		if i := strings.IndexByte(arg, '-'); i >= 0 {
+			kind, edge = arg[:i], arg[i+1:]
+		}
+		for _, g := range []ds4util.GestureKind{ds4util.Tap, ds4util.DoubleTap, ds4util.LongPress, ds4util.EdgeSwipe} {
+			if kind != g.String() {
+				continue
+			}
+			in := input{kind: inGesture, gesture: g}
+			if edge == "" {
+				return in, nil
+			}
+			if e, ok := edgeNames[edge]; ok && g == ds4util.EdgeSwipe {
+				in.edge = e
+				return in, nil
+			}
+		}
+	case "gyro":
+		if arg == "" {
+			return input{kind: inGyro}, nil
+		}
+	case "touchpad":
+		if arg == "" {
+			return input{kind: inTouchpad}, nil
+		}
+	}
+	return input{}, fmt.Errorf("unknown input %q", s)
+}
+
+type outputKind int
+
+const (
+	_ outputKind = iota
+	outKey
+	outWheel
+	outHat
+	outLayer
+	outToggle
+	outLED
+	outRumble
+	outMove
+	outStick
+	outTrigger
+)
+
+// Output devices.
+const (
+	gamepadDev = iota
+	keyboardDev
+	mouseDev
+	numDev
+)
+
+// output is a parsed binding output.
+type output struct {
+	kind  outputKind
+	dev   int    // outKey
+	code  uint16 // outKey
+	value int    // outWheel: direction; outHat: direction bit; outStick, outTrigger: index
+	name  string // outLayer, outToggle
+	color ds4.Color
+	light byte
+	heavy byte
+}
+
+func (o output) analog() bool {
+	return o.kind == outMove || o.kind == outStick || o.kind == outTrigger
+}
+
+// accepts reports whether the analog output o accepts input in.
+func (o output) accepts(in input) bool {
+	switch o.kind {
+	case outMove:
+		return in.kind == inStick || in.kind == inGyro || in.kind == inTouchpad
+	case outStick:
+		return in.kind == inStick
+	case outTrigger:
+		return in.kind == inTrigger
+	}
+	return false
+}
+
+var mouseOutputs = map[string]output{
+	"left":      {kind: outKey, dev: mouseDev, code: uinput.BTN_LEFT},
+	"right":     {kind: outKey, dev: mouseDev, code: uinput.BTN_RIGHT},
+	"middle":    {kind: outKey, dev: mouseDev, code: uinput.BTN_MIDDLE},
+	"wheelup":   {kind: outWheel, value: 1},
+	"wheeldown": {kind: outWheel, value: -1},
+	"move":      {kind: outMove},
+}
+
+var gamepadOutputs = map[string]output{
+	"a":          {kind: outKey, code: uinput.BTN_A},
+	"b":          {kind: outKey, code: uinput.BTN_B},
+	"x":          {kind: outKey, code: uinput.BTN_X},
+	"y":          {kind: outKey, code: uinput.BTN_Y},
+	"lb":         {kind: outKey, code: uinput.BTN_TL},
+	"rb":         {kind: outKey, code: uinput.BTN_TR},
+	"back":       {kind: outKey, code: uinput.BTN_SELECT},
+	"start":      {kind: outKey, code: uinput.BTN_START},
+	"guide":      {kind: outKey, code: uinput.BTN_MODE},
+	"ls":         {kind: outKey, code: uinput.BTN_THUMBL},
+	"rs":         {kind: outKey, code: uinput.BTN_THUMBR},
+	"dpadup":     {kind: outHat, value: uinput.DpadUp},
+	"dpaddown":   {kind: outHat, value: uinput.DpadDown},
+	"dpadleft":   {kind: outHat, value: uinput.DpadLeft},
+	"dpadright":  {kind: outHat, value: uinput.DpadRight},
+	"leftstick":  {kind: outStick, value: 0},
+	"rightstick": {kind: outStick, value: 1},
+	"lt":         {kind: outTrigger, value: 0},
+	"rt":         {kind: outTrigger, value: 1},
+}
+
+// parseOutput parses the output name s.
+func parseOutput(s string) (output, error) {
+	i := strings.IndexByte(s, ':')
+	if i < 0 {
+		return output{}, fmt.Errorf("invalid output %q", s)
+	}
+	group, arg := strings.ToLower(s[:i]), s[i+1:]
+	switch group {
+	case "key":
+		if code, ok := uinput.KeyCode(arg); ok {
+			return output{kind: outKey, dev: keyboardDev, code: code}, nil
+		}
+		return output{}, fmt.Errorf("unknown key %q", arg)
+	case "mouse":
+		if o, ok := mouseOutputs[strings.ToLower(arg)]; ok {
+			return o, nil
+		}
+	case "gamepad":
+		if o, ok := gamepadOutputs[strings.ToLower(arg)]; ok {
+			return o, nil
+		}
+	case "layer":
+		return output{kind: outLayer, name: arg}, nil
+	case "toggle":
+		return output{kind: outToggle, name: arg}, nil
+	case "led":
+		var c ds4.Color
+		if err := c.UnmarshalText([]byte(arg)); err != nil {
+			return output{}, fmt.Errorf("invalid colour %q", arg)
+		}
+		return output{kind: outLED, color: c}, nil
+	case "rumble":
+		v := strings.Split(arg, ",")
+		if len(v) == 2 {
+			l, err1 := strconv.ParseUint(strings.TrimSpace(v[0]), 10, 8)
+			h, err2 := strconv.ParseUint(strings.TrimSpace(v[1]), 10, 8)
+			if err1 == nil && err2 == nil {
+				return output{kind: outRumble, light: byte(l), heavy: byte(h)}, nil
+			}
+		}
+		return output{}, fmt.Errorf("invalid rumble %q, expected LIGHT,HEAVY", arg)
+	}
+	return output{}, fmt.Errorf("unknown output %q", s)
+}
@@ -0,0 +1,595 @@
+// Package profile implements declarative remapping profiles
+// binding DS4 inputs to virtual devices and controller feedback.
+//
+// Profiles are JSON documents like this:
+//
+//	{
+//		"name": "fps",
+//		"deadzone": {"left": 0.08, "right": 0.05, "l2": 0.02, "r2": 0.02},
+//		"gyro": {"space": "player", "sensitivity": 20, "ratchet": "L1"},
+//		"touchpad": {"regions": "halves"},
+//		"bindings": [
+//			{"input": "Cross", "output": "key:space"},
+//			{"input": "LeftStick:Up", "threshold": 0.4, "output": "key:w"},
+//			{"input": "R2", "threshold": 0.3, "output": "mouse:left"},
+//			{"input": "gyro", "output": "mouse:move"},
+//			{"input": "click:left", "output": "key:tab"},
+//			{"input": "PS", "output": ["led:#ff0000", "rumble:0,64"]},
+//			{"input": "L1", "output": "layer:shift"}
+//		],
+//		"layers": {
+//			"shift": [
+//				{"input": "Cross", "output": ["key:leftctrl", "key:c"]},
+//				{"input": "Circle", "macro": ["key:h", "key:i"]}
+//			]
+//		}
+//	}
+//
+// Digital inputs are the buttons Cross, Circle, Square, Triangle, L1, R1,
+// L2, R2, Options, Share, L3, R3, PS and Click; Dpad:Up, Dpad:Down,
+// Dpad:Left and Dpad:Right; stick directions such as LeftStick:Up or
+// RightStick:Left; touchpad regions touched or clicked as touch:NAME and
+// click:NAME; and the gestures gesture:tap, gesture:doubletap,
+// gesture:longpress and gesture:edgeswipe, optionally followed by
+// the edge, eg. gesture:edgeswipe-left. Analog inputs are LeftStick,
+// RightStick, L2, R2, gyro and touchpad. Input names are case insensitive.
+//
+// Stick directions and triggers bound to digital outputs are pressed
+// above their threshold, 0.5 by default.
+//
+// Digital outputs are key:NAME (see uinput.KeyCode), mouse:left,
+// mouse:right, mouse:middle, mouse:wheelup and mouse:wheeldown;
+// gamepad buttons gamepad:a, b, x, y, lb, rb, back, start, guide, ls, rs,
+// dpadup, dpaddown, dpadleft and dpadright; layer:NAME activating
+// a layer while held, toggle:NAME toggling a layer; and feedback
+// led:#rrggbb and rumble:LIGHT,HEAVY while held. Outputs of a binding
+// are pressed together, and macro outputs are pressed and released
+// one after the other.
+//
+// Analog outputs are mouse:move for sticks, gyro and touchpad;
+// gamepad:leftstick and gamepad:rightstick for sticks;
+// and gamepad:lt and gamepad:rt for triggers. Sensitivity sets
+// the pointer speed in units per second for sticks, and multiplies
+// the gyro and touchpad movement.
+//
+// Bindings of the active layer replace the bindings of the same inputs
+// in the base profile, except the bindings activating the layer.
+// Layers held take precedence over toggled layers.
+package profile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tajtiattila/hid/ds4/ds4util"
+)
+
+// Profile is a remapping profile.
+type Profile struct {
+	Name string
+
+	// Deadzone holds the stick and trigger deadzones.
+	Deadzone ds4util.Deadzone
+
+	// Gyro holds the gyro aim settings used by the gyro input.
+	Gyro Gyro
+
+	// Regions are the touchpad regions.
+	Regions []ds4util.Region
+
+	Bindings []*Binding
+	Layers   []*Layer
+}
+
+// Layer is a set of bindings that can be activated.
+type Layer struct {
+	Name     string
+	Bindings []*Binding
+}
+
+// Gyro holds gyro aim settings, see ds4util.GyroAim.
+type Gyro struct {
+	Space       ds4util.GyroSpace
+	Sensitivity float64
+	Tightening  float64
+
+	// Smoothing is the low pass filter alpha, or zero to disable smoothing.
+	Smoothing float64
+
+	// Ratchet are the ratchet button bits.
+	Ratchet uint32
+}
+
+// Binding binds an input to outputs.
+type Binding struct {
+	Input       string
+	Output      []string
+	Macro       []string
+	Threshold   float64
+	Sensitivity float64
+
+	// Line is the line of the binding in the profile source.
+	Line int
+
+	off, inOff, outOff, macroOff int64
+
+	in    input
+	out   []output
+	macro []output
+}
+
+// Error is a profile error at a position in the source.
+type Error struct {
+	File      string
+	Line, Col int
+	Msg       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Col, e.Msg)
+}
+
+// ErrorList is a list of profile errors.
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	v := make([]string, len(l))
+	for i, e := range l {
+		v[i] = e.Error()
+	}
+	return strings.Join(v, "\n")
+}
+
+// Load loads the profile in the file path.
+func Load(path string) (*Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, data)
+}
+
+// LoadDir loads the profiles in the .json files of dir.
+// Profiles without a name are named after their file.
+func LoadDir(dir string) (map[string]*Profile, error) {
+	v, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]*Profile)
+	for _, fn := range v {
+		p, err := Load(fn)
+		if err != nil {
+			return nil, err
+		}
+		if p.Name == "" {
+			p.Name = strings.TrimSuffix(filepath.Base(fn), ".json")
+		}
+		m[p.Name] = p
+	}
+	return m, nil
+}
+
+// Parse parses the profile in data read from file.
+// The error returned for invalid profiles is an ErrorList.
+func Parse(file string, data []byte) (*Profile, error) {
+	p := &parser{
+		file: file,
+		data: data,
+		dec:  json.NewDecoder(bytes.NewReader(data)),
+	}
+	pr := &Profile{
+		Gyro: Gyro{Space: ds4util.PlayerSpace, Sensitivity: 1, Tightening: 2},
+	}
+	err := p.object(func(key string, off int64) error {
+		switch key {
+		case "name":
+			return p.value(&pr.Name)
+		case "deadzone":
+			return p.value(&pr.Deadzone)
+		case "gyro":
+			return p.gyro(&pr.Gyro)
+		case "touchpad":
+			return p.touchpad(pr)
+		case "bindings":
+			return p.bindings(&pr.Bindings)
+		case "layers":
+			return p.object(func(name string, off int64) error {
+				for _, l := range pr.Layers {
+					if l.Name == name {
+						p.errorf(off, "duplicate layer %q", name)
+					}
+				}
+				l := &Layer{Name: name}
+				pr.Layers = append(pr.Layers, l)
+				return p.bindings(&l.Bindings)
+			})
+		}
+		p.errorf(off, "unknown field %q", key)
+		return p.skip()
+	})
+	if err != nil {
+		if err != errAbort {
+			p.fatal(err)
+		}
+		return nil, p.errs
+	}
+
+	p.compile(pr)
+	if len(p.errs) != 0 {
+		return nil, p.errs
+	}
+	return pr, nil
+}
+
+// errAbort stops parsing after a structural error.
+var errAbort = errors.New("profile: parsing aborted")
+
+type parser struct {
+	file string
+	data []byte
+	dec  *json.Decoder
+	errs ErrorList
+}
+
+// errorf records an error at the value following off.
+func (p *parser) errorf(off int64, format string, args ...interface{}) {
+	p.errorAt(p.start(off), fmt.Sprintf(format, args...))
+}
+
+func (p *parser) errorAt(i int, msg string) {
+	line, col := p.pos(i)
+	p.errs = append(p.errs, &Error{File: p.file, Line: line, Col: col, Msg: msg})
+}
+
+// start returns the start of the value following off,
+// skipping white space and separators.
+func (p *parser) start(off int64) int {
+	i := int(off)
+	for i < len(p.data) && strings.IndexByte(" \t\r\n,:", p.data[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+// pos returns the line and column of the byte offset i.
+func (p *parser) pos(i int) (line, col int) {
+	if i > len(p.data) {
+		i = len(p.data)
+	}
+	line = 1 + bytes.Count(p.data[:i], []byte{'\n'})
+	col = i - bytes.LastIndexByte(p.data[:i], '\n')
+	return line, col
+}
+
+// fatal records the syntax or read error err.
+func (p *parser) fatal(err error) {
+	var se *json.SyntaxError
+	switch {
+	case errors.As(err, &se) && int(se.Offset) < len(p.data):
+		p.errorAt(int(se.Offset)-1, se.Error())
+	case se != nil || err == io.EOF || err == io.ErrUnexpectedEOF:
+		p.errorAt(len(p.data), "unexpected end of file")
+	default:
+		p.errorAt(int(p.dec.InputOffset()), err.Error())
+	}
+}
+
+// value decodes the next value into v. Type errors are recorded.
+func (p *parser) value(v interface{}) error {
+	off := p.dec.InputOffset()
+	err := p.dec.Decode(v)
+	var te *json.UnmarshalTypeError
+	if errors.As(err, &te) {
+		msg := "invalid " + te.Value
+		if te.Field != "" {
+			msg += " for " + te.Field
+		}
+		p.errorf(off, "%s, expected %v", msg, te.Type)
+		return nil
+	}
+	return err
+}
+
+// skip skips the next value.
+func (p *parser) skip() error {
+	var raw json.RawMessage
+	return p.dec.Decode(&raw)
+}
+
+// object reads an object calling f with each key and its offset.
+// The function f must consume the value of the key.
+func (p *parser) object(f func(key string, off int64) error) error {
+	if err := p.delim('{', "object"); err != nil {
+		return err
+	}
+	for p.dec.More() {
+		t, err := p.dec.Token()
+		if err != nil {
+			return err
+		}
+		if err := f(t.(string), p.dec.InputOffset()); err != nil {
+			return err
+		}
+	}
+	_, err := p.dec.Token()
+	return err
+}
+
+// array reads an array calling f with the offset of each element.
+// The function f must consume the element.
+func (p *parser) array(f func(off int64) error) error {
+	if err := p.delim('[', "array"); err != nil {
+		return err
+	}
+	for p.dec.More() {
+		if err := f(p.dec.InputOffset()); err != nil {
+			return err
+		}
+	}
+	_, err := p.dec.Token()
+	return err
+}
+
+func (p *parser) delim(d json.Delim, what string) error {
+	off := p.dec.InputOffset()
+	t, err := p.dec.Token()
+	if err != nil {
+		return err
+	}
+	if t != d {
+		p.errorf(off, "expected %s", what)
+		return errAbort
+	}
+	return nil
+}
+
+// strings decodes a string or an array of strings.
+func (p *parser) strings(off int64, v *[]string) error {
+	var raw json.RawMessage
+	if err := p.dec.Decode(&raw); err != nil {
+		return err
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		*v = []string{s}
+		return nil
+	}
+	if json.Unmarshal(raw, v) != nil {
+		p.errorf(off, "expected string or array of strings")
+	}
+	return nil
+}
+
+func (p *parser) gyro(g *Gyro) error {
+	return p.object(func(key string, off int64) error {
+		var s string
+		switch key {
+		case "space":
+			if err := p.value(&s); err != nil {
+				return err
+			}
+			for sp := ds4util.LocalSpace; sp <= ds4util.PlayerSpace; sp++ {
+				if strings.EqualFold(s, sp.String()) {
+					g.Space = sp
+					return nil
+				}
+			}
+			p.errorf(off, "unknown gyro space %q", s)
+			return nil
+		case "sensitivity":
+			return p.value(&g.Sensitivity)
+		case "tightening":
+			return p.value(&g.Tightening)
+		case "smoothing":
+			if err := p.value(&g.Smoothing); err != nil {
+				return err
+			}
+			if g.Smoothing < 0 || g.Smoothing >= 1 {
+				p.errorf(off, "smoothing must be between 0 and 1")
+			}
+			return nil
+		case "ratchet":
+			if err := p.value(&s); err != nil {
+				return err
+			}
+			bits, ok := buttonNames[strings.ToLower(s)]
+			if !ok {
+				p.errorf(off, "unknown button %q", s)
+			}
+			g.Ratchet = bits
+			return nil
+		}
+		p.errorf(off, "unknown field %q", key)
+		return p.skip()
+	})
+}
+
+// regionJSON is the JSON format of a custom touchpad region.
+type regionJSON struct {
+	Name string `json:"name"`
+	Rect [4]int `json:"rect"`
+	Mode string `json:"mode"`
+}
+
+func (p *parser) touchpad(pr *Profile) error {
+	return p.object(func(key string, off int64) error {
+		if key != "regions" {
+			p.errorf(off, "unknown field %q", key)
+			return p.skip()
+		}
+		var raw json.RawMessage
+		if err := p.dec.Decode(&raw); err != nil {
+			return err
+		}
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			var cols, rows int
+			switch {
+			case s == "halves":
+				pr.Regions = ds4util.HalfRegions(ds4util.RegionButton)
+			case scanGrid(s, &cols, &rows):
+				pr.Regions = ds4util.GridRegions(cols, rows, ds4util.RegionButton)
+			default:
+				p.errorf(off, "invalid regions %q, expected \"halves\", \"grid:CxR\" or array", s)
+			}
+			return nil
+		}
+		var v []regionJSON
+		if err := json.Unmarshal(raw, &v); err != nil {
+			p.errorf(off, "invalid regions: %v", err)
+			return nil
+		}
+		for _, r := range v {
+			var mode ds4util.RegionMode
+			switch r.Mode {
+			case "", "button":
+			case "trackpad":
+				mode = ds4util.RegionTrackpad
+			default:
+				p.errorf(off, "invalid mode %q of region %q", r.Mode, r.Name)
+			}
+			if r.Name == "" {
+				p.errorf(off, "region without name")
+			}
+			pr.Regions = append(pr.Regions, ds4util.Region{
+				Name: r.Name,
+				Rect: ds4util.Rect{X0: r.Rect[0], Y0: r.Rect[1], X1: r.Rect[2], Y1: r.Rect[3]},
+				Mode: mode,
+			})
+		}
+		return nil
+	})
+}
+
+// scanGrid parses "grid:CxR".
+func scanGrid(s string, cols, rows *int) bool {
+	v := strings.Split(strings.TrimPrefix(s, "grid:"), "x")
+	if !strings.HasPrefix(s, "grid:") || len(v) != 2 {
+		return false
+	}
+	var err1, err2 error
+	*cols, err1 = strconv.Atoi(v[0])
+	*rows, err2 = strconv.Atoi(v[1])
+	return err1 == nil && err2 == nil && *cols > 0 && *rows > 0
+}
+
+func (p *parser) bindings(v *[]*Binding) error {
+	return p.array(func(off int64) error {
+		b := &Binding{off: off}
+		b.Line, _ = p.pos(p.start(off))
+		*v = append(*v, b)
+		return p.object(func(key string, off int64) error {
+			switch key {
+			case "input":
+				b.inOff = off
+				return p.value(&b.Input)
+			case "output":
+				b.outOff = off
+				return p.strings(off, &b.Output)
+			case "macro":
+				b.macroOff = off
+				return p.strings(off, &b.Macro)
+			case "threshold":
+				if err := p.value(&b.Threshold); err != nil {
+					return err
+				}
+				if b.Threshold < 0 || b.Threshold > 1 {
+					p.errorf(off, "threshold must be between 0 and 1")
+				}
+				return nil
+			case "sensitivity":
+				return p.value(&b.Sensitivity)
+			}
+			p.errorf(off, "unknown field %q", key)
+			return p.skip()
+		})
+	})
+}
+
+// compile parses the inputs and outputs of the bindings in pr.
+func (p *parser) compile(pr *Profile) {
+	layers := make(map[string]bool)
+	for _, l := range pr.Layers {
+		layers[l.Name] = true
+	}
+	all := [][]*Binding{pr.Bindings}
+	for _, l := range pr.Layers {
+		all = append(all, l.Bindings)
+	}
+	for _, v := range all {
+		seen := make(map[input]bool)
+		for _, b := range v {
+			p.compileBinding(pr, b, layers)
+			if b.in.kind != 0 && seen[b.in] {
+				p.errorf(b.inOff, "input %q bound twice", b.Input)
+			}
+			seen[b.in] = true
+		}
+	}
+}
+
+func (p *parser) compileBinding(pr *Profile, b *Binding, layers map[string]bool) {
+	if b.Input == "" {
+		p.errorf(b.off, "binding without input")
+		return
+	}
+	in, err := parseInput(b.Input, pr.Regions)
+	if err != nil {
+		p.errorf(b.inOff, "%v", err)
+		return
+	}
+	b.in = in
+
+	if len(b.Output) == 0 && len(b.Macro) == 0 {
+		p.errorf(b.off, "binding without output or macro")
+		return
+	}
+	var nanalog int
+	for _, s := range b.Output {
+		o, err := parseOutput(s)
+		if err == nil && (o.kind == outLayer || o.kind == outToggle) && !layers[o.name] {
+			err = fmt.Errorf("unknown layer %q", o.name)
+		}
+		if err != nil {
+			p.errorf(b.outOff, "%v", err)
+			continue
+		}
+		if o.analog() {
+			nanalog++
+			if !o.accepts(in) {
+				p.errorf(b.outOff, "output %q does not accept input %q", s, b.Input)
+			}
+		}
+		b.out = append(b.out, o)
+	}
+	for _, s := range b.Macro {
+		o, err := parseOutput(s)
+		if err == nil && o.kind != outKey && o.kind != outWheel {
+			err = fmt.Errorf("output %q can't be used in a macro", s)
+		}
+		if err != nil {
+			p.errorf(b.macroOff, "%v", err)
+			continue
+		}
+		b.macro = append(b.macro, o)
+	}
+
+	switch {
+	case nanalog != 0 && (nanalog != len(b.Output) || len(b.Macro) != 0):
+		p.errorf(b.outOff, "analog and digital outputs mixed")
+	case nanalog != 0:
+		if !in.analog() {
+			p.errorf(b.inOff, "input %q is not analog", b.Input)
+		}
+	case !in.digital():
+		p.errorf(b.inOff, "input %q needs an analog output", b.Input)
+	}
+}
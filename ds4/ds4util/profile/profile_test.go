@@ -0,0 +1,169 @@
+package profile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tajtiattila/hid/ds4"
+	"github.com/tajtiattila/hid/ds4/ds4util"
+)
+
+const testProfile = `{
+	"name": "fps",
+	"deadzone": {"left": 0.1, "right": 0.05},
+	"gyro": {"space": "world", "sensitivity": 20, "ratchet": "L1", "smoothing": 0.5},
+	"touchpad": {"regions": "halves"},
+	"bindings": [
+		{"input": "Cross", "output": "key:space"},
+		{"input": "LeftStick:Up", "threshold": 0.4, "output": "key:w"},
+		{"input": "R2", "output": "mouse:left"},
+		{"input": "L2", "output": "gamepad:lt"},
+		{"input": "RightStick", "output": "gamepad:rightstick"},
+		{"input": "gyro", "output": "mouse:move"},
+		{"input": "click:right", "output": "key:tab"},
+		{"input": "gesture:edgeswipe-left", "output": "key:esc"},
+		{"input": "PS", "output": ["led:#ff0000", "rumble:0,64"]},
+		{"input": "R1", "output": "layer:shift"},
+		{"input": "Share", "output": "toggle:menu"}
+	],
+	"layers": {
+		"shift": [
+			{"input": "Cross", "output": ["key:leftctrl", "key:c"]},
+			{"input": "Circle", "macro": ["key:h", "key:i"]}
+		],
+		"menu": [
+			{"input": "Dpad:Up", "output": "key:up"}
+		]
+	}
+}
+`
+
+func TestParse(t *testing.T) {
+	p, err := Parse("fps.json", []byte(testProfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "fps" || p.Deadzone.LeftStick != 0.1 || len(p.Regions) != 2 {
+		t.Errorf("got %+v", p)
+	}
+	if p.Gyro.Space != ds4util.WorldSpace || p.Gyro.Ratchet != ds4.L1 || p.Gyro.Sensitivity != 20 {
+		t.Errorf("got gyro %+v", p.Gyro)
+	}
+	if len(p.Bindings) != 11 || len(p.Layers) != 2 || p.Layers[1].Name != "menu" {
+		t.Fatalf("got %d bindings, %d layers", len(p.Bindings), len(p.Layers))
+	}
+	b := p.Bindings[1]
+	if b.Line != 8 || b.Threshold != 0.4 || b.Output[0] != "key:w" {
+		t.Errorf("got binding %+v", b)
+	}
+	if b := p.Layers[0].Bindings[1]; b.Line != 22 || len(b.Macro) != 2 {
+		t.Errorf("got layer binding %+v", b)
+	}
+	gamepad, keyboard, mouse := p.Uses()
+	if !gamepad || !keyboard || !mouse {
+		t.Errorf("Uses() = %v %v %v", gamepad, keyboard, mouse)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{
+			"syntax",
+			"{\n\t\"name\": \"x\",\n\t\"bindings\": [,]\n}",
+			[]string{"p.json:3:15: invalid character ',' looking for beginning of value"},
+		},
+		{
+			"eof",
+			"{\n\t\"name\": \"x\",\n",
+			[]string{"p.json:3:1: unexpected end of file"},
+		},
+		{
+			"not an object",
+			"[]",
+			[]string{"p.json:1:1: expected object"},
+		},
+		{
+			"type",
+			"{\n\t\"name\": 5\n}",
+			[]string{"p.json:2:10: invalid number, expected string"},
+		},
+		{
+			"unknown field",
+			"{\n\t\"nmae\": \"x\"\n}",
+			[]string{`p.json:2:10: unknown field "nmae"`},
+		},
+		{
+			"bindings",
+			`{
+	"bindings": [
+		{"input": "Crosss", "output": "key:a"},
+		{"input": "Circle", "output": "key:nokey"},
+		{"input": "gyro", "output": "key:a"},
+		{"input": "L2", "output": "gamepad:leftstick"},
+		{"input": "Square", "output": "layer:nolayer"},
+		{"input": "touch:left", "output": "key:a"},
+		{"input": "Triangle", "threshold": 2, "output": "key:a"},
+		{"input": "L1", "macro": ["layer:x"]},
+		{"input": "R1"},
+		{"output": "key:a"},
+		{"input": "Cross", "output": "key:b"},
+		{"input": "Cross", "output": "key:c"},
+		{"input": "LeftStick", "output": ["mouse:move", "key:a"]}
+	]
+}`,
+			[]string{
+				`p.json:9:38: threshold must be between 0 and 1`,
+				`p.json:3:13: unknown input "Crosss"`,
+				`p.json:4:33: unknown key "nokey"`,
+				`p.json:5:13: input "gyro" needs an analog output`,
+				`p.json:6:29: output "gamepad:leftstick" does not accept input "L2"`,
+				`p.json:7:33: unknown layer "nolayer"`,
+				`p.json:8:13: unknown touchpad region "left"`,
+				`p.json:10:28: output "layer:x" can't be used in a macro`,
+				`p.json:11:3: binding without output or macro`,
+				`p.json:12:3: binding without input`,
+				`p.json:14:13: input "Cross" bound twice`,
+				`p.json:15:36: analog and digital outputs mixed`,
+			},
+		},
+		{
+			"gyro",
+			"{\"gyro\": {\"space\": \"outer\", \"ratchet\": \"X\"}}",
+			[]string{
+				`p.json:1:20: unknown gyro space "outer"`,
+				`p.json:1:40: unknown button "X"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		_, err := Parse("p.json", []byte(tt.src))
+		var el ErrorList
+		if !errors.As(err, &el) {
+			t.Errorf("%s: got error %v, want ErrorList", tt.name, err)
+			continue
+		}
+		if el.Error() != strings.Join(tt.want, "\n") {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, el, strings.Join(tt.want, "\n"))
+		}
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.json"), []byte(testProfile), 0666)
+	os.WriteFile(filepath.Join(dir, "racing.json"), []byte(`{"bindings": []}`), 0666)
+	m, err := LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 2 || m["fps"] == nil || m["racing"] == nil {
+		t.Errorf("got %v", m)
+	}
+}
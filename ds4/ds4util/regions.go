@@ -0,0 +1,189 @@
+package ds4util
+
+import (
+	"fmt"
+
+	"github.com/tajtiattila/hid/ds4"
+)
+
+// Rect is a touchpad rectangle. X1 and Y1 are exclusive.
+type Rect struct {
+	X0, Y0, X1, Y1 int
+}
+
+// Contains reports whether x, y is inside r.
+func (r Rect) Contains(x, y int) bool {
+	return r.X0 <= x && x < r.X1 && r.Y0 <= y && y < r.Y1
+}
+
+// RegionMode is the behaviour of a touchpad region.
+type RegionMode int
+
+const (
+	// RegionButton is a virtual button, touched by fingers over it.
+	RegionButton RegionMode = iota
+
+	// RegionTrackpad is an independent trackpad. Fingers touching it
+	// first belong to it until they are lifted,
+	// even if they move outside of it.
+	RegionTrackpad
+)
+
+// Region is a touchpad region.
+type Region struct {
+	Name string
+	Rect Rect
+	Mode RegionMode
+}
+
+// HalfRegions returns the "left" and "right" halves of the touchpad.
+func HalfRegions(mode RegionMode) []Region {
+	w, h := TouchpadWidth/2, TouchpadHeight
+	return []Region{
+		{Name: "left", Rect: Rect{0, 0, w, h}, Mode: mode},
+		{Name: "right", Rect: Rect{w, 0, TouchpadWidth, h}, Mode: mode},
+	}
+}
+
+// GridRegions returns the touchpad split into cols×rows regions,
+// named "col,row" and ordered by rows.
+func GridRegions(cols, rows int, mode RegionMode) []Region {
+	v := make([]Region, 0, cols*rows)
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			v = append(v, Region{
+				Name: fmt.Sprintf("%d,%d", c, r),
+				Rect: Rect{
+					X0: c * TouchpadWidth / cols,
+					Y0: r * TouchpadHeight / rows,
+					X1: (c + 1) * TouchpadWidth / cols,
+					Y1: (r + 1) * TouchpadHeight / rows,
+				},
+				Mode: mode,
+			})
+		}
+	}
+	return v
+}
+
+// RegionState is the state of a touchpad region.
+type RegionState struct {
+	// Touched is set if a finger is on the region.
+	Touched bool
+
+	// X and Y is the position of the finger relative to the region.
+	X, Y int
+
+	// Clicked is set while the touchpad is clicked with a finger
+	// on the region. Regions are clicked when the click starts,
+	// and stay clicked until it ends.
+	Clicked bool
+
+	// Changed is set if Clicked has changed in this update.
+	Changed bool
+
+	// DX and DY is the finger movement in trackpad regions.
+	DX, DY int
+}
+
+// TouchRegions splits the touchpad into regions.
+// Where regions overlap, the first one is used.
+type TouchRegions struct {
+	Regions []Region
+
+	fingers [2]regionFinger
+	nf      int
+
+	click bool
+	st    []RegionState
+}
+
+type regionFinger struct {
+	id     byte
+	region int // -1 if none
+	x, y   int
+}
+
+// Index returns the index of the region with name, or -1.
+func (t *TouchRegions) Index(name string) int {
+	for i, r := range t.Regions {
+		if r.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Update processes the touches and click in s.
+// It returns the states of the regions.
+// The returned slice is reused by the next Update.
+func (t *TouchRegions) Update(s *ds4.State) []RegionState {
+	if len(t.st) != len(t.Regions) {
+		t.st = make([]RegionState, len(t.Regions))
+	}
+	for i := range t.st {
+		t.st[i] = RegionState{Clicked: t.st[i].Clicked}
+	}
+
+	var next [2]regionFinger
+	n := 0
+	for _, tc := range s.Touch {
+		if !tc.Active() {
+			continue
+		}
+		x, y := int(tc.X), int(tc.Y)
+		f, ok := t.finger(tc.Id)
+		if !ok {
+			f = regionFinger{id: tc.Id, region: t.regionAt(x, y), x: x, y: y}
+		} else if f.region < 0 || t.Regions[f.region].Mode != RegionTrackpad {
+			f.region = t.regionAt(x, y)
+		}
+		if f.region >= 0 {
+			r := &t.Regions[f.region]
+			st := &t.st[f.region]
+			st.Touched = true
+			st.X, st.Y = x-r.Rect.X0, y-r.Rect.Y0
+			if r.Mode == RegionTrackpad && ok {
+				st.DX += x - f.x
+				st.DY += y - f.y
+			}
+		}
+		f.x, f.y = x, y
+		next[n] = f
+		n++
+	}
+	t.fingers, t.nf = next, n
+
+	click := s.Button&ds4.Click != 0
+	for i := range t.st {
+		st := &t.st[i]
+		was := st.Clicked
+		switch {
+		case !click:
+			st.Clicked = false
+		case !t.click:
+			st.Clicked = st.Touched
+		}
+		st.Changed = st.Clicked != was
+	}
+	t.click = click
+	return t.st
+}
+
+func (t *TouchRegions) finger(id byte) (regionFinger, bool) {
+	for _, f := range t.fingers[:t.nf] {
+		if f.id == id {
+			return f, true
+		}
+	}
+	return regionFinger{}, false
+}
+
+func (t *TouchRegions) regionAt(x, y int) int {
+	for i, r := range t.Regions {
+		if r.Rect.Contains(x, y) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,93 @@
+package ds4util
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestGridRegions(t *testing.T) {
+	v := GridRegions(3, 2, RegionButton)
+	if len(v) != 6 {
+		t.Fatalf("got %d regions", len(v))
+	}
+	if v[0].Rect != (Rect{0, 0, 640, 471}) || v[5].Rect != (Rect{1280, 471, 1920, 943}) {
+		t.Errorf("got %v %v", v[0].Rect, v[5].Rect)
+	}
+	if v[4].Name != "1,1" {
+		t.Errorf("got name %q", v[4].Name)
+	}
+}
+
+// regionString formats the interesting parts of v.
+func regionString(regions []Region, v []RegionState) []string {
+	var r []string
+	for i, st := range v {
+		s := regions[i].Name
+		if st.Touched {
+			s += " touch"
+		}
+		if st.Clicked {
+			s += " click"
+		}
+		if st.Changed {
+			s += " changed"
+		}
+		if st.DX != 0 || st.DY != 0 {
+			s += fmt.Sprintf(" %+d%+d", st.DX, st.DY)
+		}
+		r = append(r, s)
+	}
+	return r
+}
+
+func TestTouchRegionsButtons(t *testing.T) {
+	tr := TouchRegions{Regions: HalfRegions(RegionButton)}
+	steps := []touchStep{
+		{0, []finger{{1, 300, 400}}, false},
+		{10, []finger{{1, 300, 400}}, true},
+		{20, []finger{{1, 1500, 400}}, true}, // click stays on the left
+		{30, []finger{{1, 1500, 400}}, false},
+		{40, []finger{{1, 1500, 400}, {2, 200, 400}}, true},
+		{50, nil, false},
+	}
+	want := [][]string{
+		{"left touch", "right"},
+		{"left touch click changed", "right"},
+		{"left click", "right touch"},
+		{"left changed", "right touch"},
+		{"left touch click changed", "right touch click changed"},
+		{"left changed", "right changed"},
+	}
+	for i, s := range touchStates(steps) {
+		got := regionString(tr.Regions, tr.Update(&s))
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("step %d: got %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestTouchRegionsTrackpad(t *testing.T) {
+	tr := TouchRegions{Regions: HalfRegions(RegionTrackpad)}
+	if tr.Index("right") != 1 || tr.Index("middle") != -1 {
+		t.Error("Index failed")
+	}
+	steps := []touchStep{
+		{0, []finger{{1, 800, 400}, {2, 1500, 400}}, false},
+		{10, []finger{{1, 1000, 420}, {2, 1490, 400}}, false}, // finger 1 stays on the left
+		{20, []finger{{2, 1480, 390}}, false},
+		{30, []finger{{3, 1000, 390}}, false},
+	}
+	want := [][]string{
+		{"left touch", "right touch"},
+		{"left touch +200+20", "right touch -10+0"},
+		{"left", "right touch -10-10"},
+		{"left", "right touch"},
+	}
+	for i, s := range touchStates(steps) {
+		got := regionString(tr.Regions, tr.Update(&s))
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("step %d: got %q, want %q", i, got, want[i])
+		}
+	}
+}
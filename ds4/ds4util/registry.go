@@ -0,0 +1,183 @@
+package ds4util
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tajtiattila/hid/ds4"
+)
+
+// Settings holds the user settings of a controller.
+type Settings struct {
+	// Name is the user given name of the controller.
+	Name string `json:"name,omitempty"`
+
+	// Color is the preferred lightbar colour.
+	Color *ds4.Color `json:"color,omitempty"`
+
+	// Calibration is applied to the states read from the controller.
+	Calibration ds4.Calibration `json:"calibration"`
+
+	// Deadzone holds the stick and trigger deadzones.
+	Deadzone Deadzone `json:"deadzone"`
+
+	// Profile is the name of the profile to use with the controller.
+	Profile string `json:"profile,omitempty"`
+}
+
+// clone returns a copy of s not sharing Color with s.
+func (s Settings) clone() Settings {
+	if s.Color != nil {
+		c := *s.Color
+		s.Color = &c
+	}
+	return s
+}
+
+// Deadzone holds deadzones as a fraction of the full range.
+type Deadzone struct {
+	LeftStick  float64 `json:"left,omitempty"`
+	RightStick float64 `json:"right,omitempty"`
+	L2         float64 `json:"l2,omitempty"`
+	R2         float64 `json:"r2,omitempty"`
+}
+
+// Registry maps controller serial numbers to user settings
+// stored in a JSON file.
+//
+// The file holds a JSON object with serial numbers as keys
+// and Settings as values.
+type Registry struct {
+	path string
+
+	mu    sync.Mutex
+	m     map[string]Settings
+	mtime time.Time
+	dirty bool // changes not saved
+}
+
+// OpenRegistry opens the registry in the file path.
+// The registry is empty if the file does not exist.
+func OpenRegistry(path string) (*Registry, error) {
+	r := &Registry{path: path, m: make(map[string]Settings)}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Lookup returns the settings of the controller with serial.
+//
+// The file is reloaded if it was modified since it was last read,
+// unless there are unsaved changes.
+func (r *Registry) Lookup(serial string) (Settings, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.dirty {
+		if fi, err := os.Stat(r.path); err == nil && !fi.ModTime().Equal(r.mtime) {
+			// keep old settings if reading fails
+			r.loadLocked()
+		}
+	}
+	s, ok := r.m[serial]
+	return s.clone(), ok
+}
+
+// Set sets the settings of the controller with serial.
+// Save must be called to store the changes.
+func (r *Registry) Set(serial string, s Settings) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.m[serial] = s.clone()
+	r.dirty = true
+}
+
+// Delete removes the settings of the controller with serial.
+// Save must be called to store the changes.
+func (r *Registry) Delete(serial string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.m, serial)
+	r.dirty = true
+}
+
+// Serials returns the sorted serial numbers of the controllers in r.
+func (r *Registry) Serials() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v := make([]string, 0, len(r.m))
+	for k := range r.m {
+		v = append(v, k)
+	}
+	sort.Strings(v)
+	return v
+}
+
+// Save writes r to its file.
+func (r *Registry) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, err := json.MarshalIndent(r.m, "", "\t")
+	if err != nil {
+		return err
+	}
+	p = append(p, '\n')
+
+	// replace file atomically
+	f, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(p)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), r.path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if fi, err := os.Stat(r.path); err == nil {
+		r.mtime = fi.ModTime()
+	}
+	r.dirty = false
+	return nil
+}
+
+func (r *Registry) load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.loadLocked()
+}
+
+func (r *Registry) loadLocked() error {
+	f, err := os.Open(r.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	m := make(map[string]Settings)
+	if err := json.NewDecoder(f).Decode(&m); err != nil && err != io.EOF {
+		return &os.PathError{Op: "ds4util: decode registry", Path: r.path, Err: err}
+	}
+	r.m, r.mtime = m, fi.ModTime()
+	return nil
+}
@@ -0,0 +1,82 @@
+package ds4util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tajtiattila/hid/ds4"
+)
+
+func TestRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ds4.json")
+	r, err := OpenRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.Lookup("a"); ok {
+		t.Fatal("found serial in empty registry")
+	}
+
+	want := Settings{
+		Name:        "red",
+		Color:       &ds4.Color{R: 0xff, G: 0x10},
+		Calibration: ds4.Calibration{LX: 3, ZGyro: -12},
+		Deadzone:    Deadzone{LeftStick: 0.1},
+		Profile:     "fps",
+	}
+	r.Set("a", want)
+	if err := r.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err = OpenRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := r.Lookup("a")
+	if !ok || got.Name != want.Name || *got.Color != *want.Color ||
+		got.Calibration != want.Calibration || got.Deadzone != want.Deadzone {
+		t.Fatalf("got %+v", got)
+	}
+
+	// modified by another program
+	p := []byte(`{"b": {"name": "blue", "color": "#0000ff"}}`)
+	if err := os.WriteFile(path, p, 0666); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(path, future, future)
+	if _, ok := r.Lookup("a"); ok {
+		t.Error("registry not reloaded")
+	}
+	if got, ok := r.Lookup("b"); !ok || *got.Color != (ds4.Color{B: 0xff}) {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestRegistryInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ds4.json")
+	os.WriteFile(path, []byte(`{"a": {"color": "red"}}`), 0666)
+	if _, err := OpenRegistry(path); err == nil {
+		t.Fatal("invalid colour accepted")
+	}
+}
+
+func TestRegistryCopy(t *testing.T) {
+	r, err := OpenRegistry(filepath.Join(t.TempDir(), "ds4.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	red := ds4.Color{R: 0xff}
+	st := Settings{Color: &red}
+	r.Set("a", st)
+	st.Color.G = 0xff
+
+	got, _ := r.Lookup("a")
+	got.Color.B = 0xff
+	if got, _ := r.Lookup("a"); *got.Color != (ds4.Color{R: 0xff}) {
+		t.Errorf("colour changed to %v through a copy", *got.Color)
+	}
+}
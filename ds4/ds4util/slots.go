@@ -0,0 +1,131 @@
+package ds4util
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/tajtiattila/hid/ds4"
+)
+
+// PlayerColors are the default lightbar colours of player slots.
+var PlayerColors = []ds4.Color{
+	{R: 0x00, G: 0x00, B: 0x40}, // blue
+	{R: 0x40, G: 0x00, B: 0x00}, // red
+	{R: 0x00, G: 0x40, B: 0x00}, // green
+	{R: 0x20, G: 0x00, B: 0x20}, // pink
+}
+
+// Slots assigns controllers to player slots numbered from 1.
+//
+// A controller gets the slot it had before when it reconnects,
+// unless the slot has been taken by another controller meanwhile.
+// Otherwise it gets the lowest free slot.
+type Slots struct {
+	// Colors are the lightbar colours of the slots.
+	// Slots after the last colour reuse them from the start.
+	// It is set to PlayerColors by NewSlots.
+	Colors []ds4.Color
+
+	mu     sync.Mutex
+	serial []string       // serials by slot-1, empty if free
+	last   map[string]int // last slot of serials
+}
+
+// NewSlots returns player slots for n controllers.
+func NewSlots(n int) *Slots {
+	return &Slots{
+		Colors: PlayerColors,
+		serial: make([]string, n),
+		last:   make(map[string]int),
+	}
+}
+
+// Len returns the number of slots.
+func (s *Slots) Len() int {
+	return len(s.serial)
+}
+
+// Slot returns the slot of the controller with serial,
+// or zero if it has no slot.
+func (s *Slots) Slot(serial string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.slot(serial)
+}
+
+// Serial returns the serial of the controller in slot,
+// or the empty string if the slot is free.
+func (s *Slots) Serial(slot int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if slot < 1 || slot > len(s.serial) {
+		return ""
+	}
+	return s.serial[slot-1]
+}
+
+// Color returns the lightbar colour of slot.
+func (s *Slots) Color(slot int) ds4.Color {
+	if slot < 1 || len(s.Colors) == 0 {
+		return ds4.Color{}
+	}
+	return s.Colors[(slot-1)%len(s.Colors)]
+}
+
+func (s *Slots) slot(serial string) int {
+	for i, x := range s.serial {
+		if x == serial {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// assign assigns a slot to serial. It returns zero if all slots are taken.
+func (s *Slots) assign(serial string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n := s.slot(serial); n != 0 {
+		return n
+	}
+	if n, ok := s.last[serial]; ok && n <= len(s.serial) && s.serial[n-1] == "" {
+		s.serial[n-1] = serial
+		return n
+	}
+	for i, x := range s.serial {
+		if x == "" {
+			s.serial[i] = serial
+			s.last[serial] = i + 1
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// release frees the slot of serial, but remembers it for reconnects.
+func (s *Slots) release(serial string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n := s.slot(serial); n != 0 {
+		s.serial[n-1] = ""
+	}
+}
+
+// swap swaps the controllers in slots a and b.
+// It returns the serials now in a and b.
+func (s *Slots) swap(a, b int) (sa, sb string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a < 1 || a > len(s.serial) || b < 1 || b > len(s.serial) {
+		return "", "", fmt.Errorf("ds4util: invalid slots %d and %d", a, b)
+	}
+	s.serial[a-1], s.serial[b-1] = s.serial[b-1], s.serial[a-1]
+	sa, sb = s.serial[a-1], s.serial[b-1]
+	if sa != "" {
+		s.last[sa] = a
+	}
+	if sb != "" {
+		s.last[sb] = b
+	}
+	return sa, sb, nil
+}
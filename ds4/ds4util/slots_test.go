@@ -0,0 +1,47 @@
+package ds4util
+
+import "testing"
+
+func TestSlots(t *testing.T) {
+	s := NewSlots(2)
+	if n := s.assign("a"); n != 1 {
+		t.Fatalf("a got slot %d", n)
+	}
+	if n := s.assign("b"); n != 2 {
+		t.Fatalf("b got slot %d", n)
+	}
+	if n := s.assign("c"); n != 0 {
+		t.Fatalf("c got slot %d with all slots taken", n)
+	}
+
+	// a reconnects to its slot
+	s.release("a")
+	if n := s.assign("a"); n != 1 {
+		t.Fatalf("a got slot %d after reconnect", n)
+	}
+
+	// slot of a taken while a is away
+	s.release("a")
+	if n := s.assign("c"); n != 1 {
+		t.Fatalf("c got slot %d", n)
+	}
+	s.release("b")
+	if n := s.assign("a"); n != 2 {
+		t.Fatalf("a got slot %d, want free slot", n)
+	}
+
+	if _, _, err := s.swap(1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if s.Serial(1) != "a" || s.Slot("c") != 2 {
+		t.Fatalf("swap failed: %q %q", s.Serial(1), s.Serial(2))
+	}
+	s.release("a")
+	s.release("c")
+	if n := s.assign("c"); n != 2 {
+		t.Fatalf("c got slot %d after swap", n)
+	}
+	if _, _, err := s.swap(0, 3); err == nil {
+		t.Fatal("invalid swap succeeded")
+	}
+}
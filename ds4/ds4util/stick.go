@@ -0,0 +1,176 @@
+package ds4util
+
+import (
+	"math"
+
+	"github.com/tajtiattila/hid/ds4"
+)
+
+// Vec is a normalized stick position or direction.
+// X points right and Y points down like the raw values in ds4.State,
+// and both are between -1 and 1.
+type Vec struct {
+	X, Y float64
+}
+
+// Len returns the length of v.
+func (v Vec) Len() float64 {
+	return math.Hypot(v.X, v.Y)
+}
+
+// StickVec returns the normalized vector of the raw stick position x, y.
+func StickVec(x, y byte) Vec {
+	return Vec{normAxis(x), normAxis(y)}
+}
+
+func normAxis(v byte) float64 {
+	x := (float64(v) - 128) / 127
+	if x < -1 {
+		return -1
+	}
+	return x
+}
+
+// DeadzoneMode is the shape of a stick deadzone.
+type DeadzoneMode int
+
+const (
+	// ScaledRadial ignores positions within a circle, and scales
+	// positions outside so that the output starts from zero at its edge.
+	ScaledRadial DeadzoneMode = iota
+
+	// Radial ignores positions within a circle,
+	// but passes positions outside unchanged.
+	Radial
+
+	// Axial ignores each axis separately within the deadzone,
+	// and scales the rest of the axis. It helps hitting exact
+	// horizontal and vertical directions, but distorts diagonals.
+	Axial
+)
+
+// Curve maps the stick magnitude between 0 and 1
+// after the deadzones to the output between 0 and 1.
+type Curve func(x float64) float64
+
+// Linear is the identity response curve.
+func Linear(x float64) float64 { return x }
+
+// Exponential returns a curve raising the input to exp.
+// Values above 1 give finer control near the centre.
+func Exponential(exp float64) Curve {
+	return func(x float64) float64 {
+		return math.Pow(x, exp)
+	}
+}
+
+// LookupTable returns a curve interpolating linearly
+// between the values in t placed evenly between 0 and 1.
+// It panics if t has less than two elements.
+func LookupTable(t []float64) Curve {
+	if len(t) < 2 {
+		panic("ds4util: lookup table too short")
+	}
+	t = append([]float64(nil), t...)
+	return func(x float64) float64 {
+		f := x * float64(len(t)-1)
+		i := int(f)
+		if i >= len(t)-1 {
+			return t[len(t)-1]
+		}
+		if i < 0 {
+			return t[0]
+		}
+		return t[i] + (f-float64(i))*(t[i+1]-t[i])
+	}
+}
+
+// Stick processes stick positions.
+//
+// Positions are processed in this order:
+// the inner and outer deadzones are applied according to Mode,
+// the magnitude is mapped using Curve, and
+// the output is raised to start from AntiDeadzone.
+type Stick struct {
+	Mode DeadzoneMode
+
+	// Deadzone is the inner deadzone as a fraction of the full range.
+	Deadzone float64
+
+	// Outer is the outer deadzone as a fraction of the full range.
+	// Positions within Outer of the edge give full output.
+	Outer float64
+
+	// AntiDeadzone is the output for positions just outside
+	// the deadzone, used to counter the deadzone of a game.
+	AntiDeadzone float64
+
+	// Curve is the response curve. Linear is used if Curve is nil.
+	Curve Curve
+}
+
+// Left processes the left stick position in s.
+func (k *Stick) Left(s *ds4.State) Vec {
+	return k.Process(StickVec(s.LX, s.LY))
+}
+
+// Right processes the right stick position in s.
+func (k *Stick) Right(s *ds4.State) Vec {
+	return k.Process(StickVec(s.RX, s.RY))
+}
+
+// Process processes the normalized stick position v.
+func (k *Stick) Process(v Vec) Vec {
+	if k.Mode == Axial {
+		return Vec{k.axis(v.X), k.axis(v.Y)}
+	}
+
+	m := v.Len()
+	if m <= k.Deadzone || m == 0 {
+		return Vec{}
+	}
+	var r float64
+	if k.Mode == Radial {
+		r = k.scale(m, 0)
+	} else {
+		r = k.scale(m, k.Deadzone)
+	}
+	r = k.respond(r)
+	return Vec{v.X / m * r, v.Y / m * r}
+}
+
+// axis processes a single axis value for Axial.
+func (k *Stick) axis(x float64) float64 {
+	a := math.Abs(x)
+	if a <= k.Deadzone {
+		return 0
+	}
+	return math.Copysign(k.respond(k.scale(a, k.Deadzone)), x)
+}
+
+// scale maps m between dz and the outer deadzone to 0..1.
+func (k *Stick) scale(m, dz float64) float64 {
+	w := 1 - dz - k.Outer
+	if w <= 0 {
+		return 1
+	}
+	return clamp01((m - dz) / w)
+}
+
+// respond applies the response curve and anti-deadzone to r.
+func (k *Stick) respond(r float64) float64 {
+	if k.Curve != nil {
+		r = clamp01(k.Curve(r))
+	}
+	return k.AntiDeadzone + (1-k.AntiDeadzone)*r
+}
+
+func clamp01(x float64) float64 {
+	switch {
+	case x < 0:
+		return 0
+	case x > 1:
+		return 1
+	}
+	return x
+}
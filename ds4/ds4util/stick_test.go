@@ -0,0 +1,44 @@
+package ds4util
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStick(t *testing.T) {
+	tests := []struct {
+		name string
+		k    Stick
+		in   Vec
+		want Vec
+	}{
+		{"centre", Stick{Deadzone: 0.1}, Vec{0.05, -0.05}, Vec{}},
+		{"scaled", Stick{Deadzone: 0.2}, Vec{0.6, 0}, Vec{0.5, 0}},
+		{"scaled full", Stick{Deadzone: 0.2}, Vec{0, -1}, Vec{0, -1}},
+		{"scaled outer", Stick{Deadzone: 0.2, Outer: 0.1}, Vec{0.95, 0}, Vec{1, 0}},
+		{"radial", Stick{Mode: Radial, Deadzone: 0.2}, Vec{0.3, 0.4}, Vec{0.3, 0.4}},
+		{"radial inside", Stick{Mode: Radial, Deadzone: 0.2}, Vec{0.1, 0.1}, Vec{}},
+		{"axial", Stick{Mode: Axial, Deadzone: 0.2}, Vec{0.1, 0.6}, Vec{0, 0.5}},
+		{"axial negative", Stick{Mode: Axial, Deadzone: 0.2}, Vec{-0.6, -0.15}, Vec{-0.5, 0}},
+		{"anti", Stick{Deadzone: 0.2, AntiDeadzone: 0.2}, Vec{0.6, 0}, Vec{0.6, 0}},
+		{"anti edge", Stick{Deadzone: 0.2, AntiDeadzone: 0.2}, Vec{0.2001, 0}, Vec{0.2, 0}},
+		{"exp", Stick{Curve: Exponential(2)}, Vec{0, 0.5}, Vec{0, 0.25}},
+		{"table", Stick{Curve: LookupTable([]float64{0, 0.2, 1})}, Vec{0.75, 0}, Vec{0.6, 0}},
+		{"diagonal clamped", Stick{}, Vec{1, 1}, Vec{math.Sqrt2 / 2, math.Sqrt2 / 2}},
+	}
+	for _, tt := range tests {
+		got := tt.k.Process(tt.in)
+		if math.Abs(got.X-tt.want.X) > 1e-3 || math.Abs(got.Y-tt.want.Y) > 1e-3 {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStickVec(t *testing.T) {
+	if v := StickVec(128, 128); v != (Vec{}) {
+		t.Errorf("centre: %v", v)
+	}
+	if v := StickVec(0, 255); v != (Vec{-1, 1}) {
+		t.Errorf("corner: %v", v)
+	}
+}
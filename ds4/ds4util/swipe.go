@@ -21,14 +21,49 @@ type SwipeHandler interface {
 	Click(x, y int)
 }
 
+// SwipeConfig holds the swipe recognition parameters.
+// Distances are in touchpad units.
+type SwipeConfig struct {
+	// A swipe is started when the first finger moved at
+	// least StartDist within BeginTime.
+	StartDist int
+	BeginTime time.Duration
+
+	// Dist is the travel distance needed for a swipe.
+	Dist int
+
+	// MaxTime is the longest time to travel Dist.
+	MaxTime time.Duration
+
+	// Diagonals enables diagonal swipes reported
+	// using two direction bits, such as SwipeUp|SwipeRight.
+	Diagonals bool
+}
+
+// DefaultSwipeConfig is the default swipe configuration.
+var DefaultSwipeConfig = SwipeConfig{
+	StartDist: 50,
+	BeginTime: 100 * time.Millisecond,
+	Dist:      300,
+	MaxTime:   300 * time.Millisecond,
+}
+
 // SwipeLogic provides swipe, touch and click input.
+// It uses a ReportClock.
 type SwipeLogic struct {
 	Handler SwipeHandler
 
+	// Config holds the swipe parameters.
+	// Zero fields use the values in DefaultSwipeConfig.
+	Config SwipeConfig
+
 	pkt byte
 
 	state swipeFunc
 
+	clock ReportClock
+	now   time.Time // current report time
+
 	// swipeStart data
 	t0     time.Time // current touch start time
 	x0, y0 int
@@ -46,27 +81,73 @@ func NewSwipeLogic(h SwipeHandler) *SwipeLogic {
 }
 
 func (l *SwipeLogic) HandleState(s *ds4.State) {
+	l.now = l.clock.Time(s)
 	if l.pkt == s.Packet {
 		return
 	}
 	l.pkt = s.Packet
+	if l.state == nil {
+		l.state = swipeStart
+	}
 	l.state = l.state(l, s)
 }
 
-type swipeFunc func(l *SwipeLogic, s *ds4.State) swipeFunc
+// Reset resets the swipe state and the report clock.
+func (l *SwipeLogic) Reset() {
+	l.state = swipeStart
+	l.clock.Reset()
+}
 
-const (
-	// a swipe is started when the first finger moved at
-	// least swipeStartDist within swipeBeginTime
-	swipeStartDist = 50
-	swipeBeginTime = 100 * time.Millisecond
+type swipeFunc func(l *SwipeLogic, s *ds4.State) swipeFunc
 
-	// swipeDist is the travel distance needed for a swipe
-	swipeDist = 300
+// config returns the configuration with defaults for zero fields.
+func (l *SwipeLogic) config() SwipeConfig {
+	c, d := l.Config, DefaultSwipeConfig
+	if c.StartDist == 0 {
+		c.StartDist = d.StartDist
+	}
+	if c.BeginTime == 0 {
+		c.BeginTime = d.BeginTime
+	}
+	if c.Dist == 0 {
+		c.Dist = d.Dist
+	}
+	if c.MaxTime == 0 {
+		c.MaxTime = d.MaxTime
+	}
+	return c
+}
 
-	// swipeMaxTime is the longest time to travel swipeDist
-	swipeMaxTime = 300 * time.Millisecond
-)
+// swipeDir returns the swipe direction of the movement dx, dy.
+func swipeDir(dx, dy int64, diag bool) int {
+	var h, v int
+	if dx > 0 {
+		h = SwipeRight
+	} else {
+		h = SwipeLeft
+	}
+	if dy > 0 {
+		v = SwipeDown
+	} else {
+		v = SwipeUp
+	}
+	ax, ay := float64(iabs(int(dx))), float64(iabs(int(dy)))
+	if diag {
+		// tan(22.5°)
+		const t = 0.41421356
+		switch {
+		case ay < ax*t:
+			return h
+		case ax < ay*t:
+			return v
+		}
+		return h | v
+	}
+	if ax > ay {
+		return h
+	}
+	return v
+}
 
 // swipeStart is the start state when no touch or click is active
 func swipeStart(l *SwipeLogic, s *ds4.State) swipeFunc {
@@ -77,7 +158,7 @@ func swipeStart(l *SwipeLogic, s *ds4.State) swipeFunc {
 			l.Handler.Click(x, y)
 			return swipeClick
 		}
-		l.t0, l.x0, l.y0, l.id = time.Now(), x, y, int(s.Touch[0].Id)
+		l.t0, l.x0, l.y0, l.id = l.now, x, y, int(s.Touch[0].Id)
 		return swipeBegin
 	}
 	return swipeStart
@@ -98,14 +179,14 @@ func swipeBegin(l *SwipeLogic, s *ds4.State) swipeFunc {
 	if int(s.Touch[0].Id) != l.id {
 		return swipeClear
 	}
+	c := l.config()
 	dx, dy := int64(x-l.x0), int64(y-l.y0)
-	if dx*dx+dy*dy > swipeStartDist*swipeStartDist {
+	if d := int64(c.StartDist); dx*dx+dy*dy > d*d {
 		// swipe started
 		l.ntouch = 1
 		return swipeSwipe
 	}
-	t := time.Now()
-	if t.Sub(l.t0) > swipeBeginTime {
+	if l.now.Sub(l.t0) > c.BeginTime {
 		// touching still near start pos
 		l.Handler.Touch(x, y)
 		return swipeTouch
@@ -127,26 +208,15 @@ func swipeSwipe(l *SwipeLogic, s *ds4.State) swipeFunc {
 		return swipeClear
 	}
 
+	c := l.config()
 	x, y := int(s.Touch[0].X), int(s.Touch[0].Y)
 	dx, dy := int64(x-l.x0), int64(y-l.y0)
-	if dx*dx+dy*dy > swipeDist*swipeDist {
-		if iabs(int(dx)) > iabs(int(dy)) {
-			if dx > 0 {
-				l.Handler.Swipe(SwipeRight, l.ntouch)
-			} else {
-				l.Handler.Swipe(SwipeLeft, l.ntouch)
-			}
-		} else {
-			if dy > 0 {
-				l.Handler.Swipe(SwipeDown, l.ntouch)
-			} else {
-				l.Handler.Swipe(SwipeUp, l.ntouch)
-			}
-		}
+	if d := int64(c.Dist); dx*dx+dy*dy > d*d {
+		l.Handler.Swipe(swipeDir(dx, dy, c.Diagonals), l.ntouch)
 		return swipeClear
 	}
 
-	if time.Now().Sub(l.t0) > swipeMaxTime {
+	if l.now.Sub(l.t0) > c.MaxTime {
 		return swipeClear
 	}
 
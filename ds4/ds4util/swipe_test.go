@@ -0,0 +1,127 @@
+package ds4util
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSwipeLogic(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   SwipeConfig
+		steps []touchStep
+		want  []string
+	}{
+		{
+			name: "swipe right",
+			steps: []touchStep{
+				{0, []finger{{1, 100, 400}}, false},
+				{10, []finger{{1, 200, 400}}, false},
+				{20, []finger{{1, 450, 410}}, false},
+				{30, nil, false},
+			},
+			want: []string{"swipe right 1"},
+		},
+		{
+			name: "two finger swipe up",
+			steps: []touchStep{
+				{0, []finger{{1, 500, 800}}, false},
+				{10, []finger{{1, 500, 700}, {2, 700, 800}}, false},
+				{20, []finger{{1, 510, 400}, {2, 700, 500}}, false},
+				{30, nil, false},
+			},
+			want: []string{"swipe up 2"},
+		},
+		{
+			name: "too slow",
+			steps: []touchStep{
+				{0, []finger{{1, 100, 400}}, false},
+				{50, []finger{{1, 200, 400}}, false},
+				{350, []finger{{1, 300, 400}}, false},
+				{400, []finger{{1, 500, 400}}, false},
+				{410, nil, false},
+			},
+			want: nil,
+		},
+		{
+			name: "tap",
+			steps: []touchStep{
+				{0, []finger{{1, 100, 200}}, false},
+				{50, []finger{{1, 102, 201}}, false},
+				{60, nil, false},
+			},
+			want: []string{"touch 102 201"},
+		},
+		{
+			name: "hold",
+			steps: []touchStep{
+				{0, []finger{{1, 100, 200}}, false},
+				{150, []finger{{1, 102, 201}}, false},
+				{160, []finger{{1, 103, 201}}, false},
+				{170, nil, false},
+			},
+			want: []string{"touch 102 201", "touch 103 201"},
+		},
+		{
+			name: "click",
+			steps: []touchStep{
+				{0, []finger{{1, 300, 300}}, true},
+				{50, []finger{{1, 300, 300}}, false},
+				{60, nil, false},
+			},
+			want: []string{"click 300 300"},
+		},
+		{
+			name: "custom distance",
+			cfg:  SwipeConfig{StartDist: 10, Dist: 100},
+			steps: []touchStep{
+				{0, []finger{{1, 500, 400}}, false},
+				{10, []finger{{1, 480, 400}}, false},
+				{20, []finger{{1, 380, 400}}, false},
+			},
+			want: []string{"swipe left 1"},
+		},
+		{
+			name: "custom max time",
+			cfg:  SwipeConfig{MaxTime: time.Second},
+			steps: []touchStep{
+				{0, []finger{{1, 100, 400}}, false},
+				{50, []finger{{1, 200, 400}}, false},
+				{350, []finger{{1, 300, 400}}, false},
+				{600, []finger{{1, 500, 400}}, false},
+			},
+			want: []string{"swipe right 1"},
+		},
+		{
+			name: "diagonal",
+			cfg:  SwipeConfig{Diagonals: true},
+			steps: []touchStep{
+				{0, []finger{{1, 100, 800}}, false},
+				{10, []finger{{1, 200, 700}}, false},
+				{20, []finger{{1, 400, 500}}, false},
+			},
+			want: []string{"swipe up+right 1"},
+		},
+		{
+			name: "diagonal disabled",
+			steps: []touchStep{
+				{0, []finger{{1, 100, 800}}, false},
+				{10, []finger{{1, 200, 700}}, false},
+				{20, []finger{{1, 410, 500}}, false},
+			},
+			want: []string{"swipe right 1"},
+		},
+	}
+	for _, tt := range tests {
+		r := new(recorder)
+		l := NewSwipeLogic(r)
+		l.Config = tt.cfg
+		for _, s := range touchStates(tt.steps) {
+			l.HandleState(&s)
+		}
+		if !reflect.DeepEqual(r.calls, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, r.calls, tt.want)
+		}
+	}
+}
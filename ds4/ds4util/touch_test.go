@@ -0,0 +1,39 @@
+package ds4util
+
+import "github.com/tajtiattila/hid/ds4"
+
+// finger is a synthetic touch point.
+type finger struct {
+	id   byte
+	x, y int16
+}
+
+// touchStep is a synthetic report at time ms with active fingers.
+type touchStep struct {
+	ms    int
+	f     []finger
+	click bool
+}
+
+// touchStates returns the states for steps. Packet changes in every state,
+// and the time between steps must be less than 349 ms.
+// Like the controller, released touches keep their last position.
+func touchStates(steps []touchStep) []ds4.State {
+	v := make([]ds4.State, len(steps))
+	for i, st := range steps {
+		v[i] = testState(i, st.ms)
+		s := &v[i]
+		if i > 0 {
+			s.Touch = v[i-1].Touch
+		}
+		s.Touch[0].Id |= ds4.TouchInactive
+		s.Touch[1].Id |= ds4.TouchInactive
+		for j, f := range st.f {
+			s.Touch[j] = ds4.Touch{Id: f.id, X: f.x, Y: f.y}
+		}
+		if st.click {
+			s.Button |= ds4.Click
+		}
+	}
+	return v
+}
@@ -0,0 +1,134 @@
+package ds4util
+
+import "github.com/tajtiattila/hid/ds4"
+
+// defaultHairSensitivity is the travel used
+// for hair triggers if Sensitivity is not set.
+const defaultHairSensitivity = 0.05
+
+// Trigger processes analog trigger values.
+//
+// The raw value is normalized, and the inner and outer deadzones
+// are applied to get the trigger travel between 0 and 1.
+// The digital state is derived from the travel, and the analog value
+// is the travel mapped using Curve.
+type Trigger struct {
+	// Deadzone is the inner deadzone as a fraction of the full range.
+	Deadzone float64
+
+	// Outer is the outer deadzone as a fraction of the full range.
+	Outer float64
+
+	// Curve is the analog response curve.
+	// Linear is used if Curve is nil.
+	Curve Curve
+
+	// Threshold is the travel at which the trigger becomes pressed.
+	// The trigger is pressed when it leaves the deadzone if Threshold is zero.
+	Threshold float64
+
+	// Hysteresis is the travel below Threshold the trigger
+	// must be released to before it is released.
+	Hysteresis float64
+
+	// HairTrigger, if set, ignores Threshold and Hysteresis.
+	// The trigger is pressed whenever it is pressed further by Sensitivity,
+	// and released when it is released by Sensitivity, regardless
+	// of its position. This allows rapid repeated presses.
+	HairTrigger bool
+
+	// Sensitivity is the travel for hair triggers.
+	Sensitivity float64
+
+	pressed bool
+	anchor  float64 // hair trigger extreme travel since last change
+}
+
+// TriggerState is a processed trigger state.
+type TriggerState struct {
+	// Value is the analog value between 0 and 1.
+	Value float64
+
+	// Pressed is the digital state.
+	Pressed bool
+
+	// Changed is set if Pressed has changed in this update.
+	Changed bool
+}
+
+// Update processes the raw trigger value.
+func (t *Trigger) Update(raw byte) TriggerState {
+	x := float64(raw) / 255
+	var v float64
+	if w := 1 - t.Deadzone - t.Outer; w <= 0 {
+		if x > t.Deadzone {
+			v = 1
+		}
+	} else {
+		v = clamp01((x - t.Deadzone) / w)
+	}
+
+	was := t.pressed
+	if t.HairTrigger {
+		t.hair(v)
+	} else {
+		switch {
+		case v <= 0:
+			t.pressed = false
+		case t.pressed:
+			t.pressed = v >= t.Threshold-t.Hysteresis
+		default:
+			t.pressed = v >= t.Threshold
+		}
+	}
+
+	a := v
+	if t.Curve != nil {
+		a = clamp01(t.Curve(v))
+	}
+	return TriggerState{Value: a, Pressed: t.pressed, Changed: t.pressed != was}
+}
+
+func (t *Trigger) hair(v float64) {
+	sens := t.Sensitivity
+	if sens <= 0 {
+		sens = defaultHairSensitivity
+	}
+	switch {
+	case v <= 0:
+		t.pressed, t.anchor = false, 0
+	case t.pressed:
+		if v > t.anchor {
+			t.anchor = v
+		} else if t.anchor-v >= sens {
+			t.pressed, t.anchor = false, v
+		}
+	default:
+		if v < t.anchor {
+			t.anchor = v
+		} else if v-t.anchor >= sens {
+			t.pressed, t.anchor = true, v
+		}
+	}
+}
+
+// Triggers processes both triggers of a controller.
+type Triggers struct {
+	L2, R2 Trigger
+}
+
+// Update processes the triggers in s. It returns the processed states,
+// and the buttons of s with the L2 and R2 bits replaced
+// by the digital states of the triggers.
+func (t *Triggers) Update(s *ds4.State) (l2, r2 TriggerState, buttons uint32) {
+	l2 = t.L2.Update(s.L2)
+	r2 = t.R2.Update(s.R2)
+	buttons = s.Button &^ (ds4.L2 | ds4.R2)
+	if l2.Pressed {
+		buttons |= ds4.L2
+	}
+	if r2.Pressed {
+		buttons |= ds4.R2
+	}
+	return l2, r2, buttons
+}
@@ -0,0 +1,82 @@
+package ds4util
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tajtiattila/hid/ds4"
+)
+
+func TestTrigger(t *testing.T) {
+	type step struct {
+		raw     byte
+		pressed bool
+		changed bool
+	}
+	tests := []struct {
+		name  string
+		t     Trigger
+		steps []step
+	}{
+		{
+			"threshold",
+			Trigger{Threshold: 0.5},
+			[]step{{0, false, false}, {100, false, false}, {140, true, true}, {120, false, true}},
+		},
+		{
+			"hysteresis",
+			Trigger{Threshold: 0.5, Hysteresis: 0.2},
+			[]step{{140, true, true}, {90, true, false}, {60, false, true}, {110, false, false}},
+		},
+		{
+			"deadzone",
+			Trigger{Deadzone: 0.1},
+			[]step{{20, false, false}, {30, true, true}, {0, false, true}},
+		},
+		{
+			"hair",
+			Trigger{HairTrigger: true, Sensitivity: 0.1},
+			[]step{
+				{100, true, true},   // pressed from rest
+				{200, true, false},  // further
+				{170, false, true},  // released by sensitivity
+				{160, false, false}, // further released
+				{190, true, true},   // pressed again without full release
+				{0, false, true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tr := tt.t
+		for i, s := range tt.steps {
+			got := tr.Update(s.raw)
+			if got.Pressed != s.pressed || got.Changed != s.changed {
+				t.Errorf("%s step %d: got %+v, want pressed=%v changed=%v",
+					tt.name, i, got, s.pressed, s.changed)
+			}
+		}
+	}
+}
+
+func TestTriggerValue(t *testing.T) {
+	tr := Trigger{Deadzone: 0.2, Outer: 0.2, Curve: Exponential(2)}
+	for _, tt := range []struct {
+		raw  byte
+		want float64
+	}{
+		{0, 0}, {51, 0}, {128, 0.25}, {204, 1}, {255, 1},
+	} {
+		if got := tr.Update(tt.raw).Value; math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("Update(%d) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestTriggersButtons(t *testing.T) {
+	tr := Triggers{L2: Trigger{Threshold: 0.5}, R2: Trigger{Threshold: 0.5}}
+	s := ds4.State{L2: 200, R2: 10, Button: ds4.R2 | ds4.Cross}
+	_, _, b := tr.Update(&s)
+	if b != ds4.L2|ds4.Cross {
+		t.Errorf("got buttons %#x", b)
+	}
+}
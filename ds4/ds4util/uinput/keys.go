@@ -0,0 +1,52 @@
+package uinput
+
+import (
+	"strconv"
+	"strings"
+)
+
+// keyNames maps key names without the KEY_ prefix to key codes.
+var keyNames = map[string]uint16{
+	"ESC": 1, "MINUS": 12, "EQUAL": 13, "BACKSPACE": 14, "TAB": 15,
+	"LEFTBRACE": 26, "RIGHTBRACE": 27, "ENTER": 28, "LEFTCTRL": 29,
+	"SEMICOLON": 39, "APOSTROPHE": 40, "GRAVE": 41, "LEFTSHIFT": 42,
+	"BACKSLASH": 43, "COMMA": 51, "DOT": 52, "SLASH": 53, "RIGHTSHIFT": 54,
+	"LEFTALT": 56, "SPACE": 57, "CAPSLOCK": 58,
+	"F11": 87, "F12": 88,
+	"RIGHTCTRL": 97, "RIGHTALT": 100,
+	"HOME": 102, "UP": 103, "PAGEUP": 104, "LEFT": 105, "RIGHT": 106,
+	"END": 107, "DOWN": 108, "PAGEDOWN": 109, "INSERT": 110, "DELETE": 111,
+	"MUTE": 113, "VOLUMEDOWN": 114, "VOLUMEUP": 115, "PAUSE": 119,
+	"LEFTMETA": 125, "RIGHTMETA": 126,
+	"NEXTSONG": 163, "PLAYPAUSE": 164, "PREVIOUSSONG": 165,
+	"ZOOMIN": KEY_ZOOMIN, "ZOOMOUT": KEY_ZOOMOUT,
+}
+
+func init() {
+	rows := []struct {
+		keys  string
+		first uint16
+	}{
+		{"1234567890", 2},
+		{"QWERTYUIOP", 16},
+		{"ASDFGHJKL", 30},
+		{"ZXCVBNM", 44},
+	}
+	for _, r := range rows {
+		for i, c := range r.keys {
+			keyNames[string(c)] = r.first + uint16(i)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		keyNames["F"+strconv.Itoa(i+1)] = KEY_F1 + uint16(i)
+	}
+}
+
+// KeyCode returns the key code for the key name as in
+// linux/input-event-codes.h, with or without the KEY_ prefix, eg. "KEY_A",
+// "a" or "leftctrl". Names are case insensitive.
+func KeyCode(name string) (uint16, bool) {
+	name = strings.TrimPrefix(strings.ToUpper(name), "KEY_")
+	c, ok := keyNames[name]
+	return c, ok
+}
@@ -0,0 +1,198 @@
+package uinput
+
+import (
+	"math"
+
+	"github.com/tajtiattila/hid/ds4"
+	"github.com/tajtiattila/hid/ds4/ds4util"
+)
+
+// Button bits used by Mapper for D-pad directions,
+// in addition to the button bits of ds4.State.
+const (
+	DpadUp    = ds4util.DpadUp
+	DpadRight = ds4util.DpadRight
+	DpadDown  = ds4util.DpadDown
+	DpadLeft  = ds4util.DpadLeft
+)
+
+// XboxButtons maps DS4 buttons to the buttons of GamepadConfig
+// in the Xbox layout.
+var XboxButtons = map[uint32]uint16{
+	ds4.Cross:    BTN_A,
+	ds4.Circle:   BTN_B,
+	ds4.Square:   BTN_X,
+	ds4.Triangle: BTN_Y,
+	ds4.L1:       BTN_TL,
+	ds4.R1:       BTN_TR,
+	ds4.Share:    BTN_SELECT,
+	ds4.Options:  BTN_START,
+	ds4.PS:       BTN_MODE,
+	ds4.L3:       BTN_THUMBL,
+	ds4.R3:       BTN_THUMBR,
+}
+
+// Mapper maps DS4 input to virtual devices.
+//
+// Mapper implements ds4util.StateHandler,
+// so it can be returned by a ds4util.ConnectHandler.
+type Mapper struct {
+	// Output holds the Gamepad, Keyboard and Mouse
+	// receiving the events.
+	Output
+
+	// Buttons maps DS4 button bits to gamepad buttons.
+	Buttons map[uint32]uint16
+
+	// Keys maps DS4 button bits to keys sent to Keyboard,
+	// mouse buttons sent to Mouse, or gamepad buttons
+	// sent to Gamepad. A key is pressed
+	// when all bits are pressed. L2 and R2 use the digital states
+	// of Triggers, and D-pad directions use the Dpad bits.
+	Keys map[uint32]uint16
+
+	// GestureKeys maps touchpad gestures to keys or mouse buttons
+	// pressed and released when the gesture is recognised.
+	GestureKeys map[ds4util.GestureKind]uint16
+
+	// Stick and Triggers process the gamepad sticks and triggers.
+	Stick    ds4util.Stick
+	Triggers ds4util.Triggers
+
+	// Touchpad moves the mouse pointer if set.
+	// Its handler is set to the mapper by NewMapper.
+	Touchpad *ds4util.Mouse
+
+	// Gestures recognises touchpad gestures if set. Scrolling moves
+	// the mouse wheel, and other gestures are mapped using GestureKeys.
+	// Its handler is set to the mapper by NewMapper.
+	Gestures *ds4util.Gestures
+
+	// ScrollScale is the touchpad units per mouse wheel step.
+	ScrollScale float64
+
+	// Aim moves the mouse pointer using the gyroscope if set.
+	Aim *ds4util.GyroAim
+
+	prev  uint32
+	wheel float64 // wheel remainder
+}
+
+// NewMapper returns a new Mapper using XboxButtons, with the touchpad
+// moving the mouse and scrolling using two fingers.
+func NewMapper(gamepad, keyboard, mouse Emitter) *Mapper {
+	m := &Mapper{
+		Output:      Output{Gamepad: gamepad, Keyboard: keyboard, Mouse: mouse},
+		Buttons:     XboxButtons,
+		Stick:       ds4util.Stick{Deadzone: 0.05},
+		ScrollScale: 40,
+	}
+	m.Touchpad = ds4util.NewMouse((*mapperEvents)(m))
+	m.Gestures = ds4util.NewGestures((*mapperEvents)(m))
+	return m
+}
+
+// State maps s to events.
+func (m *Mapper) State(s *ds4.State) error {
+	l2, r2, b := m.Triggers.Update(s)
+	b = ds4util.Buttons(b)
+
+	if m.Gamepad != nil {
+		m.gamepad(s, b, l2, r2)
+	}
+	for bits, code := range m.Keys {
+		was, now := m.prev&bits == bits, b&bits == bits
+		if was != now {
+			m.Key(code, now)
+		}
+	}
+	m.prev = b
+
+	if m.Touchpad != nil {
+		m.Touchpad.HandleState(s)
+	}
+	if m.Gestures != nil {
+		m.Gestures.HandleState(s)
+	}
+	if m.Aim != nil {
+		dx, dy := m.Aim.Update(s)
+		m.Move(dx, dy)
+	}
+	return m.Sync()
+}
+
+func (m *Mapper) gamepad(s *ds4.State, b uint32, l2, r2 ds4util.TriggerState) {
+	left, right := m.Stick.Left(s), m.Stick.Right(s)
+	m.Abs(ABS_X, left.X*StickMax)
+	m.Abs(ABS_Y, left.Y*StickMax)
+	m.Abs(ABS_RX, right.X*StickMax)
+	m.Abs(ABS_RY, right.Y*StickMax)
+	m.Abs(ABS_Z, l2.Value*TriggerMax)
+	m.Abs(ABS_RZ, r2.Value*TriggerMax)
+
+	var hx, hy float64
+	switch {
+	case b&DpadLeft != 0:
+		hx = -1
+	case b&DpadRight != 0:
+		hx = 1
+	}
+	switch {
+	case b&DpadUp != 0:
+		hy = -1
+	case b&DpadDown != 0:
+		hy = 1
+	}
+	m.Abs(ABS_HAT0X, hx)
+	m.Abs(ABS_HAT0Y, hy)
+
+	for bits, code := range m.Buttons {
+		was, now := m.prev&bits == bits, b&bits == bits
+		if was != now {
+			m.Key(code, now)
+		}
+	}
+}
+
+// mapperEvents receives pointer and gesture events for Mapper.
+type mapperEvents Mapper
+
+func (x *mapperEvents) MouseEvent(e *ds4util.MouseEvent) {
+	m := (*Mapper)(x)
+	if e.Button == 0 {
+		m.Move(float64(e.DX), float64(e.DY))
+		return
+	}
+	var code uint16
+	switch e.Button {
+	case ds4util.MouseLeft:
+		code = BTN_LEFT
+	case ds4util.MouseRight:
+		code = BTN_RIGHT
+	case ds4util.MouseMiddle:
+		code = BTN_MIDDLE
+	default:
+		return
+	}
+	m.Key(code, e.Pressed)
+}
+
+func (x *mapperEvents) Gesture(g *ds4util.Gesture) {
+	m := (*Mapper)(x)
+	if g.Kind == ds4util.Scroll {
+		if m.ScrollScale <= 0 {
+			return
+		}
+		// fingers moving up scroll up
+		m.wheel -= g.DY / m.ScrollScale
+		n := math.Trunc(m.wheel)
+		m.wheel -= n
+		m.Wheel(int32(n))
+		return
+	}
+	if code, ok := m.GestureKeys[g.Kind]; ok {
+		// Key syncs the press before the release
+		m.Key(code, true)
+		m.Key(code, false)
+	}
+}
@@ -0,0 +1,227 @@
+package uinput
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/tajtiattila/hid/ds4"
+	"github.com/tajtiattila/hid/ds4/ds4util"
+)
+
+// recorder records events, sorted within each report.
+type recorder struct {
+	ev      []string
+	reports [][]string
+}
+
+func (r *recorder) Emit(typ, code uint16, value int32) error {
+	r.ev = append(r.ev, fmt.Sprintf("%d:%#x=%d", typ, code, value))
+	return nil
+}
+
+func (r *recorder) Sync() error {
+	sort.Strings(r.ev)
+	r.reports = append(r.reports, r.ev)
+	r.ev = nil
+	return nil
+}
+
+func (r *recorder) take() [][]string {
+	v := r.reports
+	r.reports = nil
+	return v
+}
+
+func centred() ds4.State {
+	return ds4.State{LX: 128, LY: 128, RX: 128, RY: 128, Button: ds4.DpadOff}
+}
+
+func TestMapperGamepad(t *testing.T) {
+	var pad recorder
+	m := NewMapper(&pad, nil, nil)
+
+	s := centred()
+	m.State(&s)
+	want := [][]string{{
+		"3:0x0=0", "3:0x10=0", "3:0x11=0", "3:0x1=0",
+		"3:0x2=0", "3:0x3=0", "3:0x4=0", "3:0x5=0",
+	}}
+	if got := pad.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("initial: got %v, want %v", got, want)
+	}
+
+	m.State(&s)
+	if got := pad.take(); got != nil {
+		t.Errorf("unchanged: got %v", got)
+	}
+
+	s.LX, s.R2 = 255, 255
+	s.Button = 3 | ds4.Cross // south east
+	m.State(&s)
+	want = [][]string{{"1:0x130=1", "3:0x0=32767", "3:0x10=1", "3:0x11=1", "3:0x5=255"}}
+	if got := pad.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("pressed: got %v, want %v", got, want)
+	}
+
+	s = centred()
+	m.State(&s)
+	want = [][]string{{"1:0x130=0", "3:0x0=0", "3:0x10=0", "3:0x11=0", "3:0x5=0"}}
+	if got := pad.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("released: got %v, want %v", got, want)
+	}
+}
+
+func TestMapperKeys(t *testing.T) {
+	var pad, kbd, mouse recorder
+	m := NewMapper(&pad, &kbd, &mouse)
+	m.Buttons = nil
+	m.Keys = map[uint32]uint16{
+		DpadUp:             KEY_W,
+		ds4.L1 | ds4.Cross: KEY_SPACE,
+		ds4.R2:             BTN_LEFT,
+		ds4.Options:        BTN_A,
+		ds4.Share:          KEY_ZOOMIN,
+	}
+	m.Triggers.R2.Threshold = 0.5
+
+	states := []ds4.State{centred(), centred(), centred(), centred()}
+	states[0].Button = 0 // D-pad up
+	states[1].Button |= ds4.L1
+	states[2].Button |= ds4.L1 | ds4.Cross
+	states[2].R2 = 200
+	states[2].Button |= ds4.Options | ds4.Share
+	for i := range states {
+		m.State(&states[i])
+	}
+	want := [][]string{
+		{"1:0x11=1"},
+		{"1:0x11=0"},
+		{"1:0x1a2=1", "1:0x39=1"},
+		{"1:0x1a2=0", "1:0x39=0"},
+	}
+	if got := kbd.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("keyboard: got %v, want %v", got, want)
+	}
+	want = [][]string{{"1:0x110=1"}, {"1:0x110=0"}}
+	if got := mouse.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("mouse: got %v, want %v", got, want)
+	}
+	var btn []string
+	for _, r := range pad.take() {
+		for _, e := range r {
+			if strings.HasPrefix(e, "1:") {
+				btn = append(btn, e)
+			}
+		}
+	}
+	if want := []string{"1:0x130=1", "1:0x130=0"}; !reflect.DeepEqual(btn, want) {
+		t.Errorf("gamepad: got %v, want %v", btn, want)
+	}
+}
+
+func TestKeyboardConfig(t *testing.T) {
+	keys := make(map[uint16]bool)
+	for _, k := range KeyboardConfig("test").Keys {
+		keys[k] = true
+	}
+	for name, k := range keyNames {
+		if !keys[k] {
+			t.Errorf("KEY_%s not enabled", name)
+		}
+	}
+	if !keys[KEY_ESC] || !keys[KEY_VOLUMEDOWN] || keys[BTN_LEFT] {
+		t.Errorf("got keys %v", keys)
+	}
+}
+
+func touchState(ts uint16, pkt byte, x, y int16, fingers int) ds4.State {
+	s := centred()
+	s.Timestamp, s.Packet = ts, pkt
+	s.Touch[0].Id, s.Touch[1].Id = ds4.TouchInactive|1, ds4.TouchInactive|2
+	for i := 0; i < fingers; i++ {
+		s.Touch[i] = ds4.Touch{Id: byte(i + 1), X: x + int16(i)*200, Y: y}
+	}
+	return s
+}
+
+func TestMapperTouchpad(t *testing.T) {
+	var kbd, mouse recorder
+	m := NewMapper(nil, &kbd, &mouse)
+	m.GestureKeys = map[ds4util.GestureKind]uint16{ds4util.DoubleTap: KEY_ENTER}
+
+	// move pointer
+	for _, st := range []ds4.State{
+		touchState(0, 1, 500, 400, 1),
+		touchState(1000, 2, 560, 395, 1),
+		touchState(2000, 3, 560, 395, 0),
+	} {
+		m.State(&st)
+	}
+	want := [][]string{{"2:0x0=60", "2:0x1=-5"}}
+	if got := mouse.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("move: got %v, want %v", got, want)
+	}
+
+	// double tap
+	for _, st := range []ds4.State{
+		touchState(10000, 4, 500, 400, 1),
+		touchState(11000, 5, 500, 400, 0),
+		touchState(20000, 6, 500, 400, 1),
+		touchState(21000, 7, 500, 400, 0),
+	} {
+		m.State(&st)
+	}
+	want = [][]string{{"1:0x110=1"}, {"1:0x110=0"}, {"1:0x110=1"}, {"1:0x110=0"}}
+	if got := mouse.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("tap: got %v, want %v", got, want)
+	}
+	want = [][]string{{"1:0x1c=1"}, {"1:0x1c=0"}}
+	if got := kbd.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("double tap: got %v, want %v", got, want)
+	}
+
+	// two-finger scroll down
+	ts := uint16(30000)
+	for i := 0; i < 5; i++ {
+		st := touchState(ts, byte(8+i), 500, 400+int16(i)*50, 2)
+		m.State(&st)
+		ts += 1000
+	}
+	var wheel int
+	for _, r := range mouse.take() {
+		for _, e := range r {
+			var v int
+			if _, err := fmt.Sscanf(e, "2:0x8=%d", &v); err == nil {
+				wheel += v
+			}
+		}
+	}
+	if wheel != -5 {
+		t.Errorf("got wheel %d, want -5", wheel)
+	}
+}
+
+func TestKeyCode(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		code uint16
+		ok   bool
+	}{
+		{"a", KEY_A, true},
+		{"KEY_W", KEY_W, true},
+		{"leftctrl", KEY_LEFTCTRL, true},
+		{"F1", KEY_F1, true},
+		{"f10", 68, true},
+		{"0", 11, true},
+		{"M", 50, true},
+		{"nokey", 0, false},
+	} {
+		code, ok := KeyCode(tt.name)
+		if code != tt.code || ok != tt.ok {
+			t.Errorf("KeyCode(%q) = %d, %v; want %d, %v", tt.name, code, ok, tt.code, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package uinput
+
+import (
+	"io"
+	"math"
+)
+
+// Output sends events to a gamepad, keyboard and mouse,
+// and delivers them when Sync is called.
+// It is used by Mapper, and can be used by other mappings.
+//
+// Events for nil emitters are dropped.
+type Output struct {
+	Gamepad, Keyboard, Mouse Emitter
+
+	abs     map[uint16]int32
+	rx, ry  float64         // pointer remainder
+	pending [3]bool         // gamepad, keyboard, mouse have events
+	keys    map[uint16]bool // keys changed since the last sync
+	err     error
+}
+
+const (
+	gamepadDev = iota
+	keyboardDev
+	mouseDev
+)
+
+// Key sends a key, mouse button or gamepad button,
+// see BTN_MOUSE and BTN_JOYSTICK.
+//
+// If the key already changed since the last sync,
+// the pending events are delivered first,
+// so that a press and release are not lost in the same report.
+func (o *Output) Key(code uint16, pressed bool) {
+	dev := keyboardDev
+	switch {
+	case isMouseButton(code):
+		dev = mouseDev
+	case isGamepadButton(code):
+		dev = gamepadDev
+	}
+	if o.keys[code] {
+		o.sync()
+	}
+	if o.keys == nil {
+		o.keys = make(map[uint16]bool)
+	}
+	o.keys[code] = true
+	o.emit(dev, EV_KEY, code, boolValue(pressed))
+}
+
+// Move moves the mouse pointer, keeping fractions for the next move.
+func (o *Output) Move(dx, dy float64) {
+	o.rx += dx
+	o.ry += dy
+	ix, iy := math.Trunc(o.rx), math.Trunc(o.ry)
+	o.rx -= ix
+	o.ry -= iy
+	if ix != 0 {
+		o.emit(mouseDev, EV_REL, REL_X, int32(ix))
+	}
+	if iy != 0 {
+		o.emit(mouseDev, EV_REL, REL_Y, int32(iy))
+	}
+}
+
+// Wheel turns the mouse wheel by n steps.
+func (o *Output) Wheel(n int32) {
+	if n != 0 {
+		o.emit(mouseDev, EV_REL, REL_WHEEL, n)
+	}
+}
+
+// Abs sends the rounded gamepad axis value v if it has changed.
+func (o *Output) Abs(code uint16, v float64) {
+	iv := int32(math.Round(v))
+	if o.abs == nil {
+		o.abs = make(map[uint16]int32)
+	}
+	if old, ok := o.abs[code]; ok && old == iv {
+		return
+	}
+	o.abs[code] = iv
+	o.emit(gamepadDev, EV_ABS, code, iv)
+}
+
+// Sync delivers the pending events. It returns the first error
+// of the emitters since the previous call to Sync.
+func (o *Output) Sync() error {
+	o.sync()
+	err := o.err
+	o.err = nil
+	return err
+}
+
+// Close closes the emitters implementing io.Closer.
+func (o *Output) Close() error {
+	var err error
+	for _, e := range []Emitter{o.Gamepad, o.Keyboard, o.Mouse} {
+		if c, ok := e.(io.Closer); ok {
+			if cerr := c.Close(); err == nil {
+				err = cerr
+			}
+		}
+	}
+	return err
+}
+
+func (o *Output) sync() {
+	for i, e := range []Emitter{o.Gamepad, o.Keyboard, o.Mouse} {
+		if o.pending[i] {
+			o.pending[i] = false
+			o.check(e.Sync())
+		}
+	}
+	for code := range o.keys {
+		delete(o.keys, code)
+	}
+}
+
+func (o *Output) emit(dev int, typ, code uint16, value int32) {
+	e := [3]Emitter{o.Gamepad, o.Keyboard, o.Mouse}[dev]
+	if e == nil {
+		return
+	}
+	o.pending[dev] = true
+	o.check(e.Emit(typ, code, value))
+}
+
+func (o *Output) check(err error) {
+	if o.err == nil {
+		o.err = err
+	}
+}
+
+// isMouseButton reports whether code is a mouse button.
+func isMouseButton(code uint16) bool {
+	return BTN_MOUSE <= code && code <= BTN_TASK
+}
+
+// isGamepadButton reports whether code is a joystick or gamepad button.
+func isGamepadButton(code uint16) bool {
+	return BTN_JOYSTICK <= code && code < BTN_DIGI
+}
+
+func boolValue(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,190 @@
+// Package uinput creates virtual Linux input devices using /dev/uinput,
+// and maps DS4 input to virtual keyboards, mice and gamepads.
+//
+// Virtual devices are supported only on Linux,
+// but Mapper can be used with any Emitter.
+package uinput
+
+import "sort"
+
+// Event types, see linux/input-event-codes.h.
+const (
+	EV_SYN = 0x00
+	EV_KEY = 0x01
+	EV_REL = 0x02
+	EV_ABS = 0x03
+
+	SYN_REPORT = 0
+)
+
+// Relative axes.
+const (
+	REL_X      = 0x00
+	REL_Y      = 0x01
+	REL_HWHEEL = 0x06
+	REL_WHEEL  = 0x08
+)
+
+// Absolute axes.
+const (
+	ABS_X     = 0x00
+	ABS_Y     = 0x01
+	ABS_Z     = 0x02
+	ABS_RX    = 0x03
+	ABS_RY    = 0x04
+	ABS_RZ    = 0x05
+	ABS_HAT0X = 0x10
+	ABS_HAT0Y = 0x11
+)
+
+// Mouse and gamepad buttons.
+// Output sends codes from BTN_MOUSE to BTN_TASK to the mouse,
+// and codes from BTN_JOYSTICK up to BTN_DIGI to the gamepad.
+const (
+	BTN_MOUSE  = 0x110
+	BTN_TASK   = 0x117
+	BTN_LEFT   = 0x110
+	BTN_RIGHT  = 0x111
+	BTN_MIDDLE = 0x112
+
+	BTN_JOYSTICK = 0x120
+	BTN_DIGI     = 0x140
+
+	BTN_A      = 0x130
+	BTN_B      = 0x131
+	BTN_X      = 0x133
+	BTN_Y      = 0x134
+	BTN_TL     = 0x136
+	BTN_TR     = 0x137
+	BTN_SELECT = 0x13a
+	BTN_START  = 0x13b
+	BTN_MODE   = 0x13c
+	BTN_THUMBL = 0x13d
+	BTN_THUMBR = 0x13e
+)
+
+// Commonly used keys.
+const (
+	KEY_ESC        = 1
+	KEY_1          = 2
+	KEY_2          = 3
+	KEY_3          = 4
+	KEY_4          = 5
+	KEY_BACKSPACE  = 14
+	KEY_TAB        = 15
+	KEY_Q          = 16
+	KEY_W          = 17
+	KEY_E          = 18
+	KEY_R          = 19
+	KEY_ENTER      = 28
+	KEY_LEFTCTRL   = 29
+	KEY_A          = 30
+	KEY_S          = 31
+	KEY_D          = 32
+	KEY_F          = 33
+	KEY_LEFTSHIFT  = 42
+	KEY_C          = 46
+	KEY_SPACE      = 57
+	KEY_F1         = 59
+	KEY_UP         = 103
+	KEY_PAGEUP     = 104
+	KEY_LEFT       = 105
+	KEY_RIGHT      = 106
+	KEY_DOWN       = 108
+	KEY_PAGEDOWN   = 109
+	KEY_VOLUMEUP   = 115
+	KEY_VOLUMEDOWN = 114
+	KEY_ZOOMIN     = 0x1a2
+	KEY_ZOOMOUT    = 0x1a3
+)
+
+// lastKey is the largest code of the range of keys
+// enabled by KeyboardConfig by default.
+const lastKey = 0xff
+
+// BUS_USB is the bus type of virtual devices.
+const BUS_USB = 0x03
+
+// AbsInfo is the range of an absolute axis.
+type AbsInfo struct {
+	Min, Max   int32
+	Fuzz, Flat int32
+}
+
+// Config describes a virtual device.
+type Config struct {
+	Name string
+
+	Vendor, Product, Version uint16
+
+	// Keys, Rel and Abs are the keys and buttons,
+	// relative axes and absolute axes of the device.
+	Keys []uint16
+	Rel  []uint16
+	Abs  map[uint16]AbsInfo
+}
+
+// Emitter sends input events.
+// Events are delivered when Sync is called.
+type Emitter interface {
+	Emit(typ, code uint16, value int32) error
+	Sync() error
+}
+
+// KeyboardConfig returns the configuration of a virtual keyboard
+// with keys. If keys is empty, the keys up to 0xff
+// and all keys known to KeyCode are enabled.
+func KeyboardConfig(name string, keys ...uint16) *Config {
+	if len(keys) == 0 {
+		for k := uint16(1); k <= lastKey; k++ {
+			keys = append(keys, k)
+		}
+		for _, k := range keyNames {
+			if k > lastKey {
+				keys = append(keys, k)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	}
+	return &Config{Name: name, Keys: keys}
+}
+
+// MouseConfig returns the configuration of a virtual mouse.
+func MouseConfig(name string) *Config {
+	return &Config{
+		Name: name,
+		Keys: []uint16{BTN_LEFT, BTN_RIGHT, BTN_MIDDLE},
+		Rel:  []uint16{REL_X, REL_Y, REL_WHEEL, REL_HWHEEL},
+	}
+}
+
+// Ranges of the gamepad axes.
+const (
+	StickMax   = 32767
+	TriggerMax = 255
+)
+
+// GamepadConfig returns the configuration of a virtual gamepad
+// identifying itself as an Xbox 360 controller,
+// which is understood by most software.
+func GamepadConfig(name string) *Config {
+	stick := AbsInfo{Min: -StickMax - 1, Max: StickMax, Fuzz: 16, Flat: 128}
+	trigger := AbsInfo{Max: TriggerMax}
+	hat := AbsInfo{Min: -1, Max: 1}
+	return &Config{
+		Name:    name,
+		Vendor:  0x045e,
+		Product: 0x028e,
+		Version: 0x0110,
+		Keys: []uint16{
+			BTN_A, BTN_B, BTN_X, BTN_Y,
+			BTN_TL, BTN_TR, BTN_SELECT, BTN_START,
+			BTN_MODE, BTN_THUMBL, BTN_THUMBR,
+		},
+		Abs: map[uint16]AbsInfo{
+			ABS_X: stick, ABS_Y: stick, ABS_RX: stick, ABS_RY: stick,
+			ABS_Z: trigger, ABS_RZ: trigger,
+			ABS_HAT0X: hat, ABS_HAT0Y: hat,
+		},
+	}
+}
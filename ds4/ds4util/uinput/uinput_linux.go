@@ -0,0 +1,140 @@
+package uinput
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// ioctl requests, see linux/uinput.h
+const (
+	uiDevCreate  = 0x5501     // _IO('U', 1)
+	uiDevDestroy = 0x5502     // _IO('U', 2)
+	uiSetEvBit   = 0x40045564 // _IOW('U', 100, int)
+	uiSetKeyBit  = 0x40045565
+	uiSetRelBit  = 0x40045566
+	uiSetAbsBit  = 0x40045567
+
+	absCnt = 0x40
+)
+
+// userDev is struct uinput_user_dev
+type userDev struct {
+	Name         [80]byte
+	Bustype      uint16
+	Vendor       uint16
+	Product      uint16
+	Version      uint16
+	FFEffectsMax uint32
+	AbsMax       [absCnt]int32
+	AbsMin       [absCnt]int32
+	AbsFuzz      [absCnt]int32
+	AbsFlat      [absCnt]int32
+}
+
+// inputEvent is struct input_event
+type inputEvent struct {
+	Time  syscall.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+// Device is a virtual input device.
+type Device struct {
+	f   *os.File
+	buf []byte
+}
+
+// Create creates a virtual device.
+func Create(c *Config) (*Device, error) {
+	f, err := os.OpenFile("/dev/uinput", os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := setup(f, c); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("uinput: creating %q: %w", c.Name, err)
+	}
+	return &Device{f: f}, nil
+}
+
+func setup(f *os.File, c *Config) error {
+	fd := f.Fd()
+	var dev userDev
+	copy(dev.Name[:len(dev.Name)-1], c.Name)
+	dev.Bustype = BUS_USB
+	dev.Vendor, dev.Product, dev.Version = c.Vendor, c.Product, c.Version
+
+	if len(c.Keys) != 0 {
+		if err := ioctl(fd, uiSetEvBit, EV_KEY); err != nil {
+			return err
+		}
+		for _, k := range c.Keys {
+			if err := ioctl(fd, uiSetKeyBit, uintptr(k)); err != nil {
+				return err
+			}
+		}
+	}
+	if len(c.Rel) != 0 {
+		if err := ioctl(fd, uiSetEvBit, EV_REL); err != nil {
+			return err
+		}
+		for _, r := range c.Rel {
+			if err := ioctl(fd, uiSetRelBit, uintptr(r)); err != nil {
+				return err
+			}
+		}
+	}
+	if len(c.Abs) != 0 {
+		if err := ioctl(fd, uiSetEvBit, EV_ABS); err != nil {
+			return err
+		}
+		for a, ai := range c.Abs {
+			if a >= absCnt {
+				return fmt.Errorf("invalid axis %#x", a)
+			}
+			if err := ioctl(fd, uiSetAbsBit, uintptr(a)); err != nil {
+				return err
+			}
+			dev.AbsMin[a], dev.AbsMax[a] = ai.Min, ai.Max
+			dev.AbsFuzz[a], dev.AbsFlat[a] = ai.Fuzz, ai.Flat
+		}
+	}
+
+	p := (*[unsafe.Sizeof(dev)]byte)(unsafe.Pointer(&dev))[:]
+	if _, err := f.Write(p); err != nil {
+		return err
+	}
+	return ioctl(fd, uiDevCreate, 0)
+}
+
+func ioctl(fd, req, arg uintptr) error {
+	_, _, e := syscall.Syscall(syscall.SYS_IOCTL, fd, req, arg)
+	if e != 0 {
+		return &os.SyscallError{Syscall: "ioctl", Err: e}
+	}
+	return nil
+}
+
+// Emit queues an input event. It is sent by Sync.
+func (d *Device) Emit(typ, code uint16, value int32) error {
+	ev := inputEvent{Type: typ, Code: code, Value: value}
+	d.buf = append(d.buf, (*[unsafe.Sizeof(ev)]byte)(unsafe.Pointer(&ev))[:]...)
+	return nil
+}
+
+// Sync sends the queued events followed by a SYN_REPORT event.
+func (d *Device) Sync() error {
+	d.Emit(EV_SYN, SYN_REPORT, 0)
+	_, err := d.f.Write(d.buf)
+	d.buf = d.buf[:0]
+	return err
+}
+
+// Close destroys the virtual device.
+func (d *Device) Close() error {
+	ioctl(d.f.Fd(), uiDevDestroy, 0)
+	return d.f.Close()
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package uinput
+
+import "github.com/tajtiattila/hid"
+
+// Device is a virtual input device.
+type Device struct{}
+
+// Create creates a virtual device.
+// It returns hid.ErrNotSupported on this platform.
+func Create(c *Config) (*Device, error) {
+	return nil, hid.ErrNotSupported
+}
+
+func (d *Device) Emit(typ, code uint16, value int32) error { return hid.ErrNotSupported }
+func (d *Device) Sync() error                              { return hid.ErrNotSupported }
+func (d *Device) Close() error                             { return hid.ErrNotSupported }
@@ -0,0 +1,67 @@
+package ds4
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// firmware and hardware info feature report
+const (
+	featureInfo    = 0xa3
+	featureInfoLen = 49
+)
+
+// Info holds controller firmware and hardware information.
+type Info struct {
+	// Build is the firmware build date and time as reported by the controller.
+	Build string
+
+	// BuildDate is Build parsed, or the zero time if Build could not be parsed.
+	BuildDate time.Time
+
+	HWVersion     uint16
+	FWVersion     uint16
+	BoardRevision uint16
+}
+
+func (i *Info) String() string {
+	return fmt.Sprintf("hw %04x fw %04x board %04x build %s",
+		i.HWVersion, i.FWVersion, i.BoardRevision, i.Build)
+}
+
+// Info reads firmware and hardware information from the controller.
+func (d *Device) Info() (*Info, error) {
+	buf := make([]byte, featureInfoLen)
+	buf[0] = featureInfo
+	if err := d.GetFeatureReport(buf); err != nil {
+		return nil, &Error{"ds4.Info", err}
+	}
+	return decodeInfo(buf), nil
+}
+
+// decodeInfo decodes the info report with hw version at 35 and fw at 41.
+func decodeInfo(p []byte) *Info {
+	date, tod := cstring(p[1:17]), cstring(p[17:33])
+	i := &Info{
+		Build:         date + " " + tod,
+		BoardRevision: le16(p[33:]),
+		HWVersion:     le16(p[35:]),
+		FWVersion:     le16(p[41:]),
+	}
+	if t, err := time.Parse("Jan _2 2006 15:04:05", i.Build); err == nil {
+		i.BuildDate = t
+	}
+	return i
+}
+
+func cstring(p []byte) string {
+	if i := strings.IndexByte(string(p), 0); i >= 0 {
+		p = p[:i]
+	}
+	return strings.TrimSpace(string(p))
+}
+
+func le16(p []byte) uint16 {
+	return uint16(p[0]) | uint16(p[1])<<8
+}
@@ -0,0 +1,60 @@
+package ds4
+
+import (
+	"testing"
+	"time"
+)
+
+// infoReport returns a firmware info report laid out as in decodeInfo.
+func infoReport(date, tod string, board, hw, fw uint16) []byte {
+	p := make([]byte, featureInfoLen)
+	p[0] = featureInfo
+	copy(p[1:17], date)
+	copy(p[17:33], tod)
+	put := func(i int, v uint16) {
+		p[i], p[i+1] = byte(v), byte(v>>8)
+	}
+	put(33, board)
+	put(35, hw)
+	put(41, fw)
+	// unknown bytes must be ignored
+	p[37], p[39], p[43] = 0x01, 0x02, 0x03
+	return p
+}
+
+func TestDecodeInfo(t *testing.T) {
+	tests := []struct {
+		p    []byte
+		want Info
+	}{
+		{
+			infoReport("Sep 21 2018", "04:50:51", 0x0001, 0xb400, 0x0a01),
+			Info{
+				Build:         "Sep 21 2018 04:50:51",
+				BuildDate:     time.Date(2018, 9, 21, 4, 50, 51, 0, time.UTC),
+				BoardRevision: 0x0001,
+				HWVersion:     0xb400,
+				FWVersion:     0x0a01,
+			},
+		},
+		{
+			infoReport("Jan  5 2014", "17:00:02", 0, 0x3100, 0x0070),
+			Info{
+				Build:     "Jan  5 2014 17:00:02",
+				BuildDate: time.Date(2014, 1, 5, 17, 0, 2, 0, time.UTC),
+				HWVersion: 0x3100,
+				FWVersion: 0x0070,
+			},
+		},
+		{
+			infoReport("Sep 21 2018", "25:00:00", 1, 2, 3),
+			Info{Build: "Sep 21 2018 25:00:00", BoardRevision: 1, HWVersion: 2, FWVersion: 3},
+		},
+	}
+	for _, tt := range tests {
+		got := decodeInfo(tt.p)
+		if *got != tt.want {
+			t.Errorf("got %+v, want %+v", *got, tt.want)
+		}
+	}
+}
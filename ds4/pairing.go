@@ -0,0 +1,81 @@
+package ds4
+
+import (
+	"fmt"
+
+	"github.com/tajtiattila/hid"
+)
+
+// Feature reports used for pairing. They are only available
+// when the controller is connected using USB.
+const (
+	// pairing info: device and current host addresses
+	featurePairingInfo    = 0x12
+	featurePairingInfoLen = 16
+
+	// set host address and link key
+	featureSetPairing    = 0x13
+	featureSetPairingLen = 23
+)
+
+// ErrPairingUSB is returned by the pairing methods
+// when the controller is connected using bluetooth.
+// It matches hid.ErrNotSupported.
+var ErrPairingUSB = fmt.Errorf("ds4: pairing requires USB connection: %w", hid.ErrNotSupported)
+
+// Pairing holds the bluetooth addresses of a controller
+// and the host it is paired with.
+type Pairing struct {
+	// Device is the address of the controller itself.
+	Device hid.BDAddr
+
+	// Host is the address of the host the controller is paired with.
+	Host hid.BDAddr
+}
+
+func (p *Pairing) String() string {
+	return "device " + p.Device.String() + " host " + p.Host.String()
+}
+
+// Pairing reads the controller and paired host addresses.
+func (d *Device) Pairing() (*Pairing, error) {
+	if d.bt {
+		return nil, &Error{"ds4.Pairing", ErrPairingUSB}
+	}
+	buf := make([]byte, featurePairingInfoLen)
+	buf[0] = featurePairingInfo
+	if err := d.GetFeatureReport(buf); err != nil {
+		return nil, &Error{"ds4.Pairing", err}
+	}
+	p := new(Pairing)
+	getMAC(p.Device[:], buf[1:7])
+	getMAC(p.Host[:], buf[10:16])
+	return p, nil
+}
+
+// SetPairing pairs the controller with the host having the
+// bluetooth address host using the specified link key.
+//
+// The controller will try to connect to host
+// the next time its PS button is pressed.
+func (d *Device) SetPairing(host hid.BDAddr, linkKey [16]byte) error {
+	if d.bt {
+		return &Error{"ds4.SetPairing", ErrPairingUSB}
+	}
+	buf := make([]byte, featureSetPairingLen)
+	buf[0] = featureSetPairing
+	getMAC(buf[1:7], host[:])
+	copy(buf[7:], linkKey[:])
+	if err := d.SetFeatureReport(buf); err != nil {
+		return &Error{"ds4.SetPairing", err}
+	}
+	return nil
+}
+
+// getMAC copies a 6 byte address from src to dst reversing byte order.
+// Feature reports have the least significant byte first.
+func getMAC(dst, src []byte) {
+	for i := 0; i < 6; i++ {
+		dst[i] = src[5-i]
+	}
+}
@@ -0,0 +1,67 @@
+package ds4
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/tajtiattila/hid"
+)
+
+func TestPairing(t *testing.T) {
+	d, c := newTestDevice(t)
+	c.feature = map[byte][]byte{
+		featurePairingInfo: {
+			featurePairingInfo,
+			0x06, 0x05, 0x04, 0x03, 0x02, 0x01, // device
+			0x08, 0x25, 0x00, // unknown
+			0x6b, 0x5a, 0x49, 0x38, 0x27, 0x16, // host
+		},
+	}
+	p, err := d.Pairing()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Pairing{
+		Device: hid.BDAddr{0x01, 0x02, 0x03, 0x04, 0x05, 0x06},
+		Host:   hid.BDAddr{0x16, 0x27, 0x38, 0x49, 0x5a, 0x6b},
+	}
+	if *p != want {
+		t.Errorf("got %v, want %v", p, &want)
+	}
+}
+
+func TestSetPairing(t *testing.T) {
+	d, c := newTestDevice(t)
+	host := hid.BDAddr{0x16, 0x27, 0x38, 0x49, 0x5a, 0x6b}
+	var key [16]byte
+	for i := range key {
+		key[i] = byte(0xa0 + i)
+	}
+	if err := d.SetPairing(host, key); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		featureSetPairing,
+		0x6b, 0x5a, 0x49, 0x38, 0x27, 0x16, // host
+		0xa0, 0xa1, 0xa2, 0xa3, 0xa4, 0xa5, 0xa6, 0xa7, // link key
+		0xa8, 0xa9, 0xaa, 0xab, 0xac, 0xad, 0xae, 0xaf,
+	}
+	if len(c.set) != 1 || !bytes.Equal(c.set[0], want) {
+		t.Errorf("got feature reports % x, want % x", c.set, want)
+	}
+}
+
+func TestPairingBluetooth(t *testing.T) {
+	d, c := newTestDevice(t)
+	d.bt = true
+	if _, err := d.Pairing(); !errors.Is(err, hid.ErrNotSupported) {
+		t.Errorf("Pairing: got error %v", err)
+	}
+	if err := d.SetPairing(hid.BDAddr{}, [16]byte{}); !errors.Is(err, ErrPairingUSB) {
+		t.Errorf("SetPairing: got error %v", err)
+	}
+	if len(c.set) != 0 {
+		t.Errorf("feature reports set: % x", c.set)
+	}
+}
@@ -5,6 +5,8 @@ package ds4
 import (
 	"bytes"
 	"fmt"
+
+	"github.com/tajtiattila/hid"
 )
 
 // constants for the Button field of D4State
@@ -53,11 +55,18 @@ type State struct {
 	XGyro, YGyro, ZGyro int16
 
 	// battery
-	Battery byte // bit 5: charging, bits: 0-4 battery level percentage/10
+	Battery Battery
 
 	// Packet is a counter incremented whenever there is touch input
 	Packet byte
 
+	// Counter is the report counter,
+	// incremented by one (modulo 64) for each report
+	Counter byte
+
+	// Timestamp is the report time in units of 16/3 µs, wrapping around
+	Timestamp uint16
+
 	// Touch holds recognised touch events
 	Touch [2]Touch
 }
@@ -99,11 +108,11 @@ func (s *State) String() string {
 			buf.WriteByte('.')
 		}
 	}
-	x := int(int16(s.XGyro)) / 64
-	y := int(int16(s.YGyro)) / 64
-	z := int(int16(s.ZGyro)) / 64
+	x := int(s.XAcc) / 64
+	y := int(s.YAcc) / 64
+	z := int(s.ZAcc) / 64
 	fmt.Fprintf(&buf, " G(%+4d %+4d %+4d)", x, y, z)
-	fmt.Fprintf(&buf, " %02x", s.Battery)
+	fmt.Fprintf(&buf, " %02x", byte(s.Battery))
 	fmt.Fprintf(&buf, " %02x", s.Packet)
 	for i := 0; i < 2; i++ {
 		t := s.Touch[i]
@@ -114,32 +123,45 @@ func (s *State) String() string {
 	return buf.String()
 }
 
+// Decode decodes the input report in p into s.
+//
+// The report layout follows dualshock4_input_report_common
+// in Linux drivers/hid/hid-playstation.c. The motion sensor values
+// are little endian, with the gyroscope preceding the accelerometer.
+//
+// It returns an error matching hid.ErrShortReport or
+// hid.ErrUnknownReport if p is not a valid input report.
 func (s *State) Decode(p []byte) error {
 	if len(p) == 0 {
-		return fmt.Errorf("short packet")
+		return hid.ErrShortReport
 	}
 	switch p[0] {
 	case 0x01:
 		// pass
 	case 0x11:
+		if len(p) < 2 {
+			return hid.ErrShortReport
+		}
 		p = p[2:]
 	default:
-		return fmt.Errorf("unrecognised packet")
+		return hid.ErrUnknownReport
 	}
 
 	if len(p) < 43 {
-		return fmt.Errorf("short packet")
+		return hid.ErrShortReport
 	}
 
 	s.LX, s.LY = p[1], p[2]
 	s.RX, s.RY = p[3], p[4]
 	s.Button = uint32(p[5]) | uint32(p[6])<<8 | uint32(p[7])<<16
+	s.Counter = p[7] >> 2
 	s.L2, s.R2 = p[8], p[9]
+	s.Timestamp = uint16(p[10]) | uint16(p[11])<<8
 
-	s.XAcc, s.YAcc, s.ZAcc = u16triplet(p[14:20])
-	s.XGyro, s.YGyro, s.ZGyro = u16triplet(p[20:26])
+	s.XGyro, s.YGyro, s.ZGyro = le16triplet(p[13:19])
+	s.XAcc, s.YAcc, s.ZAcc = le16triplet(p[19:25])
 
-	s.Battery = p[30]
+	s.Battery = Battery(p[30])
 
 	s.Packet = p[34]
 	decodeTouch(p[35:39], &s.Touch[0])
@@ -149,19 +171,21 @@ func (s *State) Decode(p []byte) error {
 }
 
 func (s *State) GyroRoll() float64 {
-	return GyroRoll(gyroVec(s.XGyro, s.YGyro, s.ZGyro))
+	return GyroRoll(gyroVec(s.XAcc, s.YAcc, s.ZAcc))
 }
 
 func (s *State) GyroPitch() float64 {
-	return GyroPitch(gyroVec(s.XGyro, s.YGyro, s.ZGyro))
+	return GyroPitch(gyroVec(s.XAcc, s.YAcc, s.ZAcc))
 }
 
 func (s *State) GyroRollPitch() (roll, pitch float64) {
-	return GyroRollPitch(gyroVec(s.XGyro, s.YGyro, s.ZGyro))
+	return GyroRollPitch(gyroVec(s.XAcc, s.YAcc, s.ZAcc))
 }
 
+// GyroVec returns the accelerometer vector
+// used by GyroRoll, GyroPitch and GyroRollPitch.
 func (s *State) GyroVec() (x, y, z float64) {
-	return gyroVec(s.XGyro, s.YGyro, s.ZGyro)
+	return gyroVec(s.XAcc, s.YAcc, s.ZAcc)
 }
 
 func (s *State) Finger(fid byte) *Touch {
@@ -174,10 +198,10 @@ func (s *State) Finger(fid byte) *Touch {
 	return nil
 }
 
-func u16triplet(p []byte) (x, y, z int16) {
-	x = int16(p[0])<<8 | int16(p[1])
-	y = int16(p[2])<<8 | int16(p[3])
-	z = int16(p[4])<<8 | int16(p[5])
+func le16triplet(p []byte) (x, y, z int16) {
+	x = int16(p[1])<<8 | int16(p[0])
+	y = int16(p[3])<<8 | int16(p[2])
+	z = int16(p[5])<<8 | int16(p[4])
 	return
 }
 
@@ -0,0 +1,84 @@
+package ds4
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tajtiattila/hid"
+)
+
+// usbReport is a USB input report laid out as
+// dualshock4_input_report_usb in Linux hid-playstation.c,
+// with the controller lying still with one finger on the touchpad.
+var usbReport = []byte{
+	0x01,                   // report id
+	0x80, 0x7f, 0x82, 0x7d, // sticks
+	0x28, 0x02, 0x55, // Cross, dpad off, R1, counter 0x15, PS
+	0x00, 0xff, // triggers
+	0x34, 0x12, // timestamp
+	0x1a,                               // temperature
+	0xfd, 0xff, 0x05, 0x00, 0x02, 0x00, // gyroscope
+	0x78, 0x00, 0xfe, 0x1f, 0xa2, 0xfe, // accelerometer
+	0x00, 0x00, 0x00, 0x00, 0x00, // reserved
+	0x1b, 0x00, // status
+	0x00,                   // reserved
+	0x01,                   // touch reports
+	0x2a,                   // touch packet
+	0x05, 0xe8, 0x43, 0x1f, // finger 5 at 1000, 500
+	0x80, 0x00, 0x00, 0x00, // inactive
+}
+
+var usbState = State{
+	LX: 0x80, LY: 0x7f, RX: 0x82, RY: 0x7d,
+	R2:        0xff,
+	Button:    DpadOff | Cross | R1 | PS | 0x15<<18,
+	XGyro:     -3,
+	YGyro:     5,
+	ZGyro:     2,
+	XAcc:      120,
+	YAcc:      8190,
+	ZAcc:      -350,
+	Battery:   0x1b,
+	Packet:    0x2a,
+	Counter:   0x15,
+	Timestamp: 0x1234,
+	Touch: [2]Touch{
+		{Id: 5, X: 1000, Y: 500},
+		{Id: TouchInactive},
+	},
+}
+
+func TestDecode(t *testing.T) {
+	usb := make([]byte, 64)
+	copy(usb, usbReport)
+
+	// bluetooth reports have two more header bytes
+	bt := make([]byte, 78)
+	bt[0], bt[1] = 0x11, 0xc0
+	copy(bt[3:], usbReport[1:])
+
+	for _, p := range [][]byte{usb, bt} {
+		var s State
+		if err := s.Decode(p); err != nil {
+			t.Fatalf("report %#x: %v", p[0], err)
+		}
+		if s != usbState {
+			t.Errorf("report %#x:\ngot  %+v\nwant %+v", p[0], s, usbState)
+		}
+	}
+
+	var s State
+	for _, tt := range []struct {
+		p   []byte
+		err error
+	}{
+		{nil, hid.ErrShortReport},
+		{usb[:42], hid.ErrShortReport},
+		{[]byte{0x11}, hid.ErrShortReport},
+		{[]byte{0x02, 0x00}, hid.ErrUnknownReport},
+	} {
+		if err := s.Decode(tt.p); !errors.Is(err, tt.err) {
+			t.Errorf("% x: got %v, want %v", tt.p, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package ds4
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"time"
+)
+
+// LatencyBuckets are the upper bounds of the
+// report delay histogram buckets in Stats.
+var LatencyBuckets = [NumLatencyBuckets]time.Duration{
+	1 * time.Millisecond,
+	2 * time.Millisecond,
+	4 * time.Millisecond,
+	8 * time.Millisecond,
+	16 * time.Millisecond,
+	32 * time.Millisecond,
+	64 * time.Millisecond,
+	128 * time.Millisecond,
+}
+
+// NumLatencyBuckets is the number of latency histogram buckets
+// with an upper bound.
+const NumLatencyBuckets = 8
+
+// batteryHistoryLen is the number of battery samples kept.
+const batteryHistoryLen = 64
+
+// Stats holds input report statistics of a Device.
+type Stats struct {
+	// Reports is the number of reports read, including invalid ones.
+	Reports uint64
+
+	// Dropped is the number of reports missed
+	// according to the report counter.
+	Dropped uint64
+
+	// DecodeErrors is the number of reports that could not be decoded.
+	DecodeErrors uint64
+
+	// CRCErrors is the number of bluetooth reports with a bad checksum.
+	CRCErrors uint64
+
+	// Rate is the number of reports per second
+	// measured over the last second.
+	Rate float64
+
+	// Latency is the histogram of report delays.
+	//
+	// The delay of a report is estimated from its timestamp:
+	// it is the time the report arrived later than the earliest
+	// arriving reports of the last 10-20 seconds. Reports arriving
+	// after a gap in the input are not measured.
+	Latency Histogram
+
+	// BatteryHistory holds recent battery state changes, oldest first.
+	BatteryHistory []BatterySample
+}
+
+// Histogram is a latency histogram.
+type Histogram struct {
+	// Counts[i] is the number of samples not longer than LatencyBuckets[i],
+	// and longer than the previous bucket. The last element counts the
+	// samples longer than all buckets.
+	Counts [NumLatencyBuckets + 1]uint64
+
+	// Sum is the sum of all samples.
+	Sum time.Duration
+}
+
+// Count returns the number of samples in h.
+func (h *Histogram) Count() uint64 {
+	var n uint64
+	for _, c := range h.Counts {
+		n += c
+	}
+	return n
+}
+
+func (h *Histogram) add(d time.Duration) {
+	i := 0
+	for i < NumLatencyBuckets && d > LatencyBuckets[i] {
+		i++
+	}
+	h.Counts[i]++
+	h.Sum += d
+}
+
+// BatterySample is a battery state recorded at Time.
+type BatterySample struct {
+	Time    time.Time
+	Battery Battery
+}
+
+// Stats returns the input report statistics of d.
+func (d *Device) Stats() Stats {
+	d.stmu.Lock()
+	defer d.stmu.Unlock()
+	st := d.st.Stats
+	st.BatteryHistory = append([]BatterySample(nil), st.BatteryHistory...)
+	return st
+}
+
+// stats collects Stats.
+type stats struct {
+	Stats
+
+	// last report counter, or -1 before the first report
+	counter int
+
+	// rate measurement
+	winStart time.Time
+	winCount uint64
+
+	delay delay
+}
+
+func newStats() stats {
+	return stats{counter: -1}
+}
+
+// record updates st with the report decoded into s arriving at now.
+// Err is the decoding error, and crcok is false
+// if the report checksum was bad.
+func (st *stats) record(now time.Time, s *State, err error, crcok bool) {
+	st.Reports++
+
+	if st.winStart.IsZero() {
+		st.winStart = now
+	}
+	st.winCount++
+	if el := now.Sub(st.winStart); el >= time.Second {
+		st.Rate = float64(st.winCount) / el.Seconds()
+		st.winStart, st.winCount = now, 0
+	}
+
+	if !crcok {
+		st.CRCErrors++
+	}
+	if err != nil {
+		st.DecodeErrors++
+		return
+	}
+
+	if d, ok := st.delay.update(now, s.Timestamp); ok {
+		st.Latency.add(d)
+	}
+
+	c := int(s.Counter)
+	if st.counter >= 0 {
+		st.Dropped += uint64((c - st.counter - 1) & 0x3f)
+	}
+	st.counter = c
+
+	h := st.BatteryHistory
+	if len(h) == 0 || h[len(h)-1].Battery != s.Battery {
+		if len(h) == batteryHistoryLen {
+			copy(h, h[1:])
+			h = h[:len(h)-1]
+		}
+		st.BatteryHistory = append(h, BatterySample{now, s.Battery})
+	}
+}
+
+const (
+	// delayWindow is the time after which the smallest
+	// report offset is renewed to follow clock drift.
+	delayWindow = 10 * time.Second
+
+	// delayMaxGap is the longest time between reports
+	// that can be measured using the wrapping report timestamps.
+	delayMaxGap = 300 * time.Millisecond
+)
+
+// delay estimates report delays from the report timestamps.
+//
+// The offset between the arrival time of a report and its
+// timestamp grows with the time the report was delayed,
+// so the delay is the offset relative to the smallest offset
+// seen in the current and previous window.
+type delay struct {
+	last  time.Time     // arrival of the previous report
+	stamp uint16        // timestamp of the previous report
+	dev   time.Duration // controller time since base
+	base  time.Time     // arrival of the first report
+
+	win          time.Time     // start of the current window
+	min, prevMin time.Duration // smallest offsets
+}
+
+// update records a report with timestamp arriving at now,
+// and returns its delay. It reports false if the delay
+// could not be measured.
+func (d *delay) update(now time.Time, stamp uint16) (time.Duration, bool) {
+	if d.last.IsZero() || now.Sub(d.last) > delayMaxGap {
+		// first report, or timestamp may have wrapped
+		*d = delay{last: now, stamp: stamp, base: now, win: now}
+		return 0, false
+	}
+	d.dev += time.Duration(stamp-d.stamp) * 16 * time.Microsecond / 3
+	d.last, d.stamp = now, stamp
+
+	off := now.Sub(d.base) - d.dev
+	if now.Sub(d.win) >= delayWindow {
+		d.win = now
+		d.prevMin, d.min = d.min, off
+	}
+	if off < d.min {
+		d.min = off
+	}
+	min := d.min
+	if d.prevMin < min {
+		min = d.prevMin
+	}
+	return off - min, true
+}
+
+// bluetooth input report checksum
+const (
+	btCRCSeed = 0xa1 // HID input report header
+	btCRCPos  = 74
+)
+
+// checkCRC reports if the checksum of the bluetooth input report p is valid.
+// Reports that have no checksum are reported valid.
+func checkCRC(p []byte) bool {
+	if len(p) < btCRCPos+4 || p[0] != 0x11 {
+		return true
+	}
+	crc := crc32.Update(0, crc32.IEEETable, []byte{btCRCSeed})
+	crc = crc32.Update(crc, crc32.IEEETable, p[:btCRCPos])
+	return crc == binary.LittleEndian.Uint32(p[btCRCPos:])
+}
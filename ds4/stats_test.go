@@ -0,0 +1,145 @@
+package ds4
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"testing"
+	"time"
+)
+
+func TestStatsRecord(t *testing.T) {
+	st := newStats()
+	now := time.Unix(1000, 0)
+	var s State
+	for _, c := range []byte{10, 11, 12, 15, 63, 1} {
+		s.Counter = c
+		st.record(now, &s, nil, true)
+		now = now.Add(4 * time.Millisecond)
+		s.Timestamp += 750
+	}
+	// 13, 14 and 16..62, 0 missed
+	if want := uint64(2 + 47 + 1); st.Dropped != want {
+		t.Errorf("dropped %d, want %d", st.Dropped, want)
+	}
+
+	// report delayed by 3ms
+	s.Counter = 2
+	st.record(now.Add(3*time.Millisecond), &s, nil, true)
+
+	st.record(now, &s, errors.New("bad"), false)
+	if st.Reports != 8 || st.DecodeErrors != 1 || st.CRCErrors != 1 {
+		t.Errorf("got %+v", st.Stats)
+	}
+	if st.Latency.Counts[0] != 5 || st.Latency.Counts[2] != 1 {
+		t.Errorf("latency histogram %v", st.Latency.Counts)
+	}
+	if st.Latency.Count() != 6 {
+		t.Errorf("latency count %d", st.Latency.Count())
+	}
+}
+
+func TestStatsDelay(t *testing.T) {
+	base := time.Unix(1000, 0)
+	tests := []struct {
+		ms    int    // arrival time
+		stamp uint16 // report timestamp
+		delay int    // delay in ms, or -1 if not measured
+	}{
+		{0, 0, -1},
+		{4, 750, 0},
+		{10, 1500, 2},
+		{11, 2250, 0}, // earlier than the first report
+		{16, 3000, 1},
+		{500, 3750, -1}, // gap
+		{504, 4500, 0},
+		{509, 5250, 1},
+		{1000, 65000, -1},
+		{1004, 214, 0}, // wraps around
+	}
+	var d delay
+	for i, tt := range tests {
+		got, ok := d.update(base.Add(time.Duration(tt.ms)*time.Millisecond), tt.stamp)
+		if tt.delay < 0 {
+			if ok {
+				t.Errorf("%d: measured delay %v", i, got)
+			}
+			continue
+		}
+		if want := time.Duration(tt.delay) * time.Millisecond; !ok || got != want {
+			t.Errorf("%d: got %v, %v, want %v", i, got, ok, want)
+		}
+	}
+}
+
+func TestStatsDelayDrift(t *testing.T) {
+	// host clock 100 ppm faster than the controller
+	now := time.Unix(1000, 0)
+	var d delay
+	var stamp uint16
+	var got time.Duration
+	for i := 0; i < 15000; i++ {
+		got, _ = d.update(now, stamp)
+		now = now.Add(4*time.Millisecond + 400*time.Nanosecond)
+		stamp += 750
+	}
+	// 6ms drift in a minute, at most two windows are measured
+	if max := 2*delayWindow/10000 + time.Millisecond/10; got > max {
+		t.Errorf("delay %v after a minute, want at most %v", got, max)
+	}
+}
+
+func TestStatsRate(t *testing.T) {
+	st := newStats()
+	now := time.Unix(1000, 0)
+	var s State
+	for i := 0; i <= 250; i++ {
+		s.Counter = byte(i) & 0x3f
+		st.record(now, &s, nil, true)
+		now = now.Add(4 * time.Millisecond)
+	}
+	if st.Rate < 249 || st.Rate > 252 {
+		t.Errorf("rate %v, want 250", st.Rate)
+	}
+	if st.Dropped != 0 {
+		t.Errorf("dropped %d", st.Dropped)
+	}
+}
+
+func TestStatsBatteryHistory(t *testing.T) {
+	st := newStats()
+	now := time.Unix(1000, 0)
+	var s State
+	for i := 0; i < 2*batteryHistoryLen; i++ {
+		s.Counter = byte(i) & 0x3f
+		s.Battery = Battery(i)
+		st.record(now, &s, nil, true)
+	}
+	h := st.BatteryHistory
+	if len(h) != batteryHistoryLen {
+		t.Fatalf("history length %d", len(h))
+	}
+	if h[0].Battery != batteryHistoryLen || h[len(h)-1].Battery != 2*batteryHistoryLen-1 {
+		t.Errorf("history %v..%v", h[0].Battery, h[len(h)-1].Battery)
+	}
+}
+
+func TestCheckCRC(t *testing.T) {
+	p := make([]byte, 78)
+	p[0] = 0x11
+	for i := 1; i < btCRCPos; i++ {
+		p[i] = byte(i)
+	}
+	crc := crc32.ChecksumIEEE(append([]byte{btCRCSeed}, p[:btCRCPos]...))
+	binary.LittleEndian.PutUint32(p[btCRCPos:], crc)
+	if !checkCRC(p) {
+		t.Error("valid checksum rejected")
+	}
+	p[5]++
+	if checkCRC(p) {
+		t.Error("bad checksum accepted")
+	}
+	if !checkCRC(make([]byte, 64)) {
+		t.Error("USB report rejected")
+	}
+}
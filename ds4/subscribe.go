@@ -0,0 +1,204 @@
+package ds4
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/tajtiattila/hid"
+)
+
+// DropPolicy specifies what happens when a Subscription
+// is not received from fast enough and its buffer is full.
+type DropPolicy int
+
+const (
+	// DropOldest discards the oldest buffered state
+	// to make room for the new one.
+	DropOldest DropPolicy = iota
+
+	// LatestOnly buffers only the most recent state,
+	// regardless of the buffer size.
+	LatestOnly
+
+	// Block makes the reader wait until there is room in the buffer.
+	// A slow subscriber using Block delays all other subscribers.
+	Block
+)
+
+// Subscription receives input states read from a Device.
+type Subscription struct {
+	// C delivers states. It is closed after
+	// Close is called or when reading fails.
+	C <-chan State
+
+	c      chan State
+	d      *Device
+	policy DropPolicy
+
+	done     chan struct{}
+	doneOnce sync.Once
+	once     sync.Once
+
+	mu     sync.Mutex
+	closed bool
+	err    error
+}
+
+// Subscribe returns a new subscription receiving the states read from d.
+//
+// A single reader goroutine is shared by all subscriptions of d.
+// It is started with the first subscription, and stopped when all
+// subscriptions are closed, or when reading fails with an error
+// other than hid.ErrTimeout. In the latter case the subscriptions
+// are stopped with the error, and a later Subscribe starts reading
+// again. ReadState should not be used
+// while there are active subscriptions.
+//
+// Buffer is the number of states that can be buffered in the subscription,
+// policy determines what happens when the buffer is full.
+func (d *Device) Subscribe(buffer int, policy DropPolicy) *Subscription {
+	if buffer < 1 || policy == LatestOnly {
+		buffer = 1
+	}
+	c := make(chan State, buffer)
+	sub := &Subscription{
+		C:      c,
+		c:      c,
+		d:      d,
+		policy: policy,
+		done:   make(chan struct{}),
+	}
+
+	d.submu.Lock()
+	defer d.submu.Unlock()
+	for d.subcancel == nil && d.subdone != nil {
+		// previous reader is stopping
+		done := d.subdone
+		d.submu.Unlock()
+		<-done
+		d.submu.Lock()
+		if d.subdone == done {
+			d.subdone = nil
+		}
+	}
+	d.subs = append(d.subs, sub)
+	if d.subcancel == nil {
+		var ctx context.Context
+		ctx, d.subcancel = context.WithCancel(context.Background())
+		d.subdone = make(chan struct{})
+		go d.readSubs(ctx, d.subdone)
+	}
+	return sub
+}
+
+// Close stops the subscription and closes C.
+func (s *Subscription) Close() error {
+	s.once.Do(func() {
+		d := s.d
+		d.submu.Lock()
+		for i, x := range d.subs {
+			if x == s {
+				d.subs = append(d.subs[:i], d.subs[i+1:]...)
+				break
+			}
+		}
+		if len(d.subs) == 0 && d.subcancel != nil {
+			d.subcancel()
+			d.subcancel = nil
+		}
+		d.submu.Unlock()
+		s.stop(nil)
+	})
+	return nil
+}
+
+// Err returns the error that stopped the subscription,
+// or nil if it was stopped using Close.
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// stop closes C and records err.
+func (s *Subscription) stop(err error) {
+	// unblock send before taking s.mu
+	s.doneOnce.Do(func() { close(s.done) })
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		s.err = err
+		close(s.c)
+	}
+}
+
+func (s *Subscription) send(st *State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	if s.policy == Block {
+		select {
+		case s.c <- *st:
+		case <-s.done:
+		}
+		return
+	}
+	for {
+		select {
+		case s.c <- *st:
+			return
+		default:
+		}
+		// buffer full, drop oldest
+		select {
+		case <-s.c:
+		default:
+		}
+	}
+}
+
+// readSubs reads states and sends them to subscribers until ctx is done
+// or reading fails.
+func (d *Device) readSubs(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	var s State
+	var subs []*Subscription
+	for {
+		err := d.ReadStateContext(ctx, &s)
+		if errors.Is(err, hid.ErrShortReport) || errors.Is(err, hid.ErrUnknownReport) {
+			// skip corrupt report
+			continue
+		}
+		if errors.Is(err, hid.ErrTimeout) && ctx.Err() == nil {
+			// no input yet, keep waiting
+			continue
+		}
+		if err != nil {
+			d.submu.Lock()
+			if ctx.Err() != nil {
+				// all subscriptions closed
+				d.submu.Unlock()
+				return
+			}
+			subs = d.subs
+			d.subcancel()
+			d.subs, d.subcancel = nil, nil
+			d.submu.Unlock()
+			for _, sub := range subs {
+				sub.stop(err)
+			}
+			return
+		}
+
+		d.submu.Lock()
+		subs = append(subs[:0], d.subs...)
+		d.submu.Unlock()
+		for _, sub := range subs {
+			sub.send(&s)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package ds4
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tajtiattila/hid"
+)
+
+// testRead is the result of a read from testConn.
+// A zero testRead is a barrier: it is not returned to the reader,
+// so sending it waits until the previous read was handled.
+type testRead struct {
+	lx  byte
+	err error
+}
+
+// testConn is a USB connection returning the reads sent on in.
+// It returns the feature reports in feature,
+// and records the feature reports set.
+type testConn struct {
+	in chan testRead
+
+	feature map[byte][]byte
+	set     [][]byte
+}
+
+func newTestDevice(t *testing.T) (*Device, *testConn) {
+	t.Helper()
+	c := &testConn{in: make(chan testRead)}
+	d, err := NewDevice(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d, c
+}
+
+// feed sends reports with the LX values lx,
+// and waits until they are handled.
+func (c *testConn) feed(lx ...byte) {
+	for _, x := range lx {
+		c.in <- testRead{lx: x}
+	}
+	c.in <- testRead{}
+}
+
+// fail makes the next read fail with err.
+func (c *testConn) fail(err error) {
+	c.in <- testRead{err: err}
+}
+
+func (c *testConn) Read(p []byte) (int, error) {
+	return c.ReadContext(context.Background(), p)
+}
+
+func (c *testConn) ReadContext(ctx context.Context, p []byte) (int, error) {
+	for {
+		select {
+		case r := <-c.in:
+			if r.err != nil {
+				return 0, r.err
+			}
+			if r.lx == 0 {
+				continue
+			}
+			for i := range p {
+				p[i] = 0
+			}
+			p[0], p[1] = 0x01, r.lx
+			return len(p), nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
+
+func (c *testConn) Write(p []byte) (int, error) { return len(p), nil }
+func (c *testConn) Close() error                { return nil }
+
+func (c *testConn) WriteContext(ctx context.Context, p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (c *testConn) Name() string { return "test" }
+
+func (c *testConn) DeviceInfo() (*hid.DeviceInfo, error) {
+	return &hid.DeviceInfo{
+		Name: "test",
+		Attr: &hid.Attr{VendorId: 0x54C, ProductId: 0x5C4},
+		Caps: &hid.Caps{InputLen: 64, OutputLen: 32},
+	}, nil
+}
+
+func (c *testConn) SetTimeout(time.Duration)           {}
+func (c *testConn) SetDeadline(t time.Time) error      { return nil }
+func (c *testConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *testConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func (c *testConn) SetOutputReport(p []byte) error { return nil }
+func (c *testConn) DisconnectRadio() error         { return hid.ErrNotBluetooth }
+
+func (c *testConn) GetFeatureReport(p []byte) error {
+	r, ok := c.feature[p[0]]
+	if !ok {
+		return hid.ErrNotSupported
+	}
+	copy(p, r)
+	return nil
+}
+
+func (c *testConn) SetFeatureReport(p []byte) error {
+	c.set = append(c.set, append([]byte(nil), p...))
+	return nil
+}
+
+var (
+	_ Conn = (*testConn)(nil)
+	_ Conn = (*Device)(nil) // methods work without a *hid.Device
+)
+
+// recv receives the LX values buffered in sub.
+func recv(sub *Subscription) []byte {
+	var v []byte
+	for {
+		select {
+		case s, ok := <-sub.C:
+			if !ok {
+				return v
+			}
+			v = append(v, s.LX)
+		default:
+			return v
+		}
+	}
+}
+
+// waitClosed waits until C of sub is closed,
+// and returns the LX values received.
+func waitClosed(t *testing.T, sub *Subscription) []byte {
+	t.Helper()
+	var v []byte
+	for {
+		select {
+		case s, ok := <-sub.C:
+			if !ok {
+				return v
+			}
+			v = append(v, s.LX)
+		case <-time.After(5 * time.Second):
+			t.Fatal("subscription not closed")
+		}
+	}
+}
+
+func TestSubscribePolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		buffer int
+		policy DropPolicy
+		want   []byte
+	}{
+		{"drop oldest", 2, DropOldest, []byte{3, 4}},
+		{"latest only", 5, LatestOnly, []byte{4}},
+		{"zero buffer", 0, DropOldest, []byte{4}},
+	}
+	for _, tt := range tests {
+		d, c := newTestDevice(t)
+		sub := d.Subscribe(tt.buffer, tt.policy)
+		c.feed(1, 2, 3, 4)
+		if got := recv(sub); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+		sub.Close()
+	}
+}
+
+func TestSubscribeBlock(t *testing.T) {
+	d, c := newTestDevice(t)
+	sub := d.Subscribe(1, Block)
+	defer sub.Close()
+
+	go c.feed(1, 2, 3, 4)
+	var got []byte
+	for len(got) < 4 {
+		select {
+		case s := <-sub.C:
+			got = append(got, s.LX)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %v, want more", got)
+		}
+	}
+	if want := []byte{1, 2, 3, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSubscribeFanOut(t *testing.T) {
+	d, c := newTestDevice(t)
+	a := d.Subscribe(4, DropOldest)
+	b := d.Subscribe(4, Block)
+	c.feed(1, 2)
+	a.Close()
+	c.feed(3)
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := waitClosed(t, a), []byte{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("first got %v, want %v", got, want)
+	}
+	if got, want := waitClosed(t, b), []byte{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("second got %v, want %v", got, want)
+	}
+	if a.Err() != nil || b.Err() != nil {
+		t.Errorf("closed subscriptions have errors %v, %v", a.Err(), b.Err())
+	}
+}
+
+func TestSubscribeError(t *testing.T) {
+	d, c := newTestDevice(t)
+	a := d.Subscribe(4, DropOldest)
+	b := d.Subscribe(4, Block)
+
+	// timeouts and corrupt reports are not fatal
+	c.fail(&hid.Error{Func: "hid.Read", Path: "test", Err: hid.ErrTimeout})
+	c.fail(hid.ErrShortReport)
+	c.feed(1)
+
+	c.fail(hid.ErrDeviceGone)
+	for _, sub := range []*Subscription{a, b} {
+		if got, want := waitClosed(t, sub), []byte{1}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if err := sub.Err(); !errors.Is(err, hid.ErrDeviceGone) {
+			t.Errorf("got error %v, want %v", err, hid.ErrDeviceGone)
+		}
+	}
+
+	// the error is not permanent
+	sub := d.Subscribe(4, DropOldest)
+	defer sub.Close()
+	c.feed(2)
+	if got, want := recv(sub), []byte{2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after error got %v, want %v", got, want)
+	}
+	if err := sub.Err(); err != nil {
+		t.Errorf("new subscription has error %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package hid
+
+import (
+	"errors"
+
+	"github.com/tajtiattila/hid/asyncio"
+)
+
+// Sentinel errors to be used with errors.Is.
+//
+// Errors returned by Device methods wrap the
+// original platform error in an *Error.
+var (
+	// ErrDeviceGone means the device was unplugged or disconnected.
+	ErrDeviceGone = errors.New("hid: device disconnected")
+
+	// ErrShortReport means a report was shorter than expected.
+	ErrShortReport = errors.New("hid: short report")
+
+	// ErrUnknownReport means a report had an unrecognised report ID.
+	ErrUnknownReport = errors.New("hid: unknown report")
+
+	// ErrTimeout means a read or write timed out.
+	ErrTimeout = errors.New("hid: timeout")
+
+	// ErrAccess means the device is unavailable because of
+	// system permissions or because it is opened with exclusive access.
+	ErrAccess = errors.New("hid: access denied")
+
+	// ErrNotSupported means the device or platform
+	// does not support the operation.
+	ErrNotSupported = errors.New("hid: operation not supported")
+)
+
+// IsAccess checks if the err is an access error, meaning
+// the device is currently unavailable because of system
+// permissions or the device was opened with exclusive access.
+//
+// It is equivalent to errors.Is(err, ErrAccess),
+// but works also with unwrapped platform errors.
+func IsAccess(err error) bool {
+	var e *Error
+	if errors.As(err, &e) {
+		err = e.Err
+	}
+	return errKind(err) == ErrAccess
+}
+
+// errKind returns the sentinel error for err,
+// or nil if it has none.
+func errKind(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case err == asyncio.ErrTimeout:
+		return ErrTimeout
+	}
+	return platformErrKind(err)
+}
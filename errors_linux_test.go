@@ -0,0 +1,92 @@
+package hid
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tajtiattila/hid/asyncio"
+)
+
+func TestErrorIs(t *testing.T) {
+	tests := []struct {
+		err  error
+		want error
+	}{
+		{syscall.ENODEV, ErrDeviceGone},
+		{syscall.EIO, ErrDeviceGone},
+		{&os.PathError{Op: "open", Path: "/dev/hidraw0", Err: syscall.ENOENT}, ErrDeviceGone},
+		{&os.PathError{Op: "open", Path: "/dev/hidraw0", Err: syscall.EACCES}, ErrAccess},
+		{syscall.EBUSY, ErrAccess},
+		{asyncio.ErrTimeout, ErrTimeout},
+		{syscall.EPIPE, ErrNotSupported},
+		{ErrShortReport, ErrShortReport},
+	}
+	all := []error{ErrDeviceGone, ErrShortReport, ErrUnknownReport, ErrTimeout, ErrAccess, ErrNotSupported}
+	for _, tt := range tests {
+		err := newErr("hid.Read", "/dev/hidraw0", tt.err)
+		for _, k := range all {
+			if got := errors.Is(err, k); got != (k == tt.want) {
+				t.Errorf("errors.Is(%v, %v) = %v", err, k, got)
+			}
+		}
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%v does not wrap %v", err, tt.err)
+		}
+	}
+
+	if !IsAccess(syscall.EACCES) {
+		t.Error("IsAccess(EACCES) = false")
+	}
+	if s := (&Error{Func: "hid.Open", Path: "x"}).Error(); s != "hid.Open x" {
+		t.Errorf("nil cause: got %q", s)
+	}
+}
+
+func TestDeviceErrors(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	rd := &Device{&asyncio.File{File: r}}
+	wd := &Device{&asyncio.File{File: w}}
+
+	check := func(what string, err, want error) {
+		t.Helper()
+		var he *Error
+		if !errors.As(err, &he) {
+			t.Errorf("%s: %v (%T) is not a *hid.Error", what, err, err)
+		}
+		if !errors.Is(err, want) {
+			t.Errorf("%s: got %v, want %v", what, err, want)
+		}
+	}
+
+	buf := make([]byte, 4)
+	rd.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	_, err = rd.Read(buf)
+	check("Read", err, ErrTimeout)
+	_, err = rd.ReadContext(context.Background(), buf)
+	check("ReadContext", err, ErrTimeout)
+
+	// fill the pipe so that writes block
+	wd.SetWriteDeadline(time.Now().Add(50 * time.Millisecond))
+	big := make([]byte, 1<<20)
+	_, err = wd.Write(big)
+	check("Write", err, ErrTimeout)
+	wd.SetWriteDeadline(time.Now().Add(10 * time.Millisecond))
+	_, err = wd.WriteContext(context.Background(), buf)
+	check("WriteContext", err, ErrTimeout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = rd.ReadContext(ctx, buf)
+	check("ReadContext cancelled", err, context.Canceled)
+	_, err = wd.WriteContext(ctx, buf)
+	check("WriteContext cancelled", err, context.Canceled)
+}
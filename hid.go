@@ -1,6 +1,9 @@
 package hid
 
 import (
+	"context"
+	"errors"
+
 	"github.com/tajtiattila/hid/asyncio"
 	"github.com/tajtiattila/hid/platform"
 )
@@ -20,7 +23,7 @@ func VendorDevices(vendor uint16, products ...uint16) ([]*DeviceInfo, error) {
 	for _, n := range v {
 		i, err := Stat(n)
 		if err != nil {
-			if IsAccess(err) {
+			if IsAccess(err) || errors.Is(err, ErrDeviceGone) {
 				continue
 			}
 			return nil, err
@@ -48,7 +51,7 @@ func SerialNo(sno string) ([]*DeviceInfo, error) {
 	for _, n := range v {
 		i, err := Stat(n)
 		if err != nil {
-			if IsAccess(err) {
+			if IsAccess(err) || errors.Is(err, ErrDeviceGone) {
 				continue
 			}
 			return nil, err
@@ -74,7 +77,7 @@ func Stat(name string) (*DeviceInfo, error) {
 func Open(name string) (*Device, error) {
 	f, err := asyncio.Open(name)
 	if err != nil {
-		return nil, newErr("ds4.Open", name, err)
+		return nil, newErr("hid.Open", name, err)
 	}
 	return &Device{f}, nil
 }
@@ -84,6 +87,46 @@ type Device struct {
 	*asyncio.File
 }
 
+// Read reads an input report into p.
+func (d *Device) Read(p []byte) (n int, err error) {
+	n, err = d.File.Read(p)
+	if err != nil {
+		err = newErr("hid.Read", d.Name(), err)
+	}
+	return n, err
+}
+
+// ReadContext reads an input report into p.
+// If ctx is done before a report arrives, the read is cancelled
+// and the returned error wraps ctx.Err().
+func (d *Device) ReadContext(ctx context.Context, p []byte) (n int, err error) {
+	n, err = d.File.ReadContext(ctx, p)
+	if err != nil {
+		err = newErr("hid.Read", d.Name(), err)
+	}
+	return n, err
+}
+
+// Write writes the output report in p.
+func (d *Device) Write(p []byte) (n int, err error) {
+	n, err = d.File.Write(p)
+	if err != nil {
+		err = newErr("hid.Write", d.Name(), err)
+	}
+	return n, err
+}
+
+// WriteContext writes the output report in p.
+// If ctx is done before the report is written, the write is cancelled
+// and the returned error wraps ctx.Err().
+func (d *Device) WriteContext(ctx context.Context, p []byte) (n int, err error) {
+	n, err = d.File.WriteContext(ctx, p)
+	if err != nil {
+		err = newErr("hid.Write", d.Name(), err)
+	}
+	return n, err
+}
+
 type DeviceInfo struct {
 	Name string
 
@@ -96,6 +139,9 @@ type Attr struct {
 	ProductId uint16
 	Version   uint16
 	SerialNo  string
+
+	// Bluetooth is set if the device is connected using bluetooth.
+	Bluetooth bool
 }
 
 type Caps struct {
@@ -119,6 +165,43 @@ type Caps struct {
 	NumFeatureDataIndices  int
 }
 
+// ErrNotBluetooth is returned by DisconnectRadio
+// if the device is not connected using bluetooth.
+var ErrNotBluetooth = errors.New("hid: device not connected using bluetooth")
+
+// BDAddr is a bluetooth device address.
+type BDAddr = platform.BDAddr
+
+// ParseBDAddr parses a bluetooth address in the form "01:23:45:67:89:ab".
+func ParseBDAddr(s string) (BDAddr, error) {
+	return platform.ParseBDAddr(s)
+}
+
+// DisconnectRadio disconnects the device radio.
+// It returns ErrNotBluetooth if the device is not using bluetooth.
+func (d *Device) DisconnectRadio() error {
+	di, err := d.DeviceInfo()
+	if err != nil {
+		return err
+	}
+	if !di.Attr.Bluetooth {
+		return ErrNotBluetooth
+	}
+	a, err := ParseBDAddr(di.Attr.SerialNo)
+	if err != nil {
+		return newErr("hid.DisconnectRadio", d.Name(), err)
+	}
+	if err := platform.DisconnectBluetooth(a); err != nil {
+		return newErr("hid.DisconnectRadio", d.Name(), err)
+	}
+	return nil
+}
+
+// Error records a failed device operation.
+//
+// Error matches one of the sentinel errors such as
+// ErrDeviceGone or ErrTimeout with errors.Is
+// depending on its platform specific cause Err.
 type Error struct {
 	Func string
 	Path string
@@ -126,9 +209,20 @@ type Error struct {
 }
 
 func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Func + " " + e.Path
+	}
 	return e.Func + " " + e.Path + ": " + e.Err.Error()
 }
 
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is reports whether e is caused by target,
+// where target is one of the sentinel errors of this package.
+func (e *Error) Is(target error) bool {
+	return target != nil && errKind(e.Err) == target
+}
+
 func newErr(f, p string, err error) error {
 	return &Error{f, p, err}
 }
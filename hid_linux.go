@@ -0,0 +1,120 @@
+package hid
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/tajtiattila/hid/platform"
+)
+
+// platformErrKind returns the sentinel error for
+// the Linux error err, or nil if it has none.
+func platformErrKind(err error) error {
+	if platform.IsAccess(err) {
+		return ErrAccess
+	}
+	if pe, ok := err.(*os.PathError); ok {
+		err = pe.Err
+	}
+	errc, ok := err.(syscall.Errno)
+	if !ok {
+		return nil
+	}
+	switch errc {
+	case syscall.ENODEV, syscall.ENOENT, syscall.ENXIO,
+		syscall.EIO, syscall.ESHUTDOWN:
+		return ErrDeviceGone
+	case syscall.EINVAL, syscall.ENOTTY, syscall.EOPNOTSUPP,
+		syscall.EPIPE, syscall.ENOSYS:
+		return ErrNotSupported
+	case syscall.ETIMEDOUT:
+		return ErrTimeout
+	}
+	return nil
+}
+
+func (d *Device) DeviceInfo() (*DeviceInfo, error) {
+	hi, err := platform.HidrawStat(d.Name())
+	if err != nil {
+		return nil, newErr("hid.DeviceInfo", d.Name(), err)
+	}
+
+	var sno string
+	err = d.control(func(fd uintptr) error {
+		sno = platform.GetSerialNo(fd, hi.Uniq)
+		return nil
+	})
+	if err != nil {
+		return nil, newErr("hid.DeviceInfo", d.Name(), err)
+	}
+
+	ri := platform.ParseReportDescriptor(hi.ReportDesc)
+	return &DeviceInfo{
+		Name: d.Name(),
+		Attr: &Attr{
+			VendorId:  hi.VendorID,
+			ProductId: hi.ProductID,
+			SerialNo:  sno,
+			Bluetooth: hi.Bus == platform.BUS_BLUETOOTH,
+		},
+		Caps: &Caps{
+			Usage:     ri.Usage,
+			UsagePage: ri.UsagePage,
+
+			InputLen:   ri.InputLen,
+			OutputLen:  ri.OutputLen,
+			FeatureLen: ri.FeatureLen,
+		},
+	}, nil
+}
+
+// SetOutputReport sends the output report in p to the device.
+// The first byte of p is the report ID.
+func (d *Device) SetOutputReport(p []byte) error {
+	if _, err := d.Write(p); err != nil {
+		return newErr("hid.SetOutputReport", d.Name(), err)
+	}
+	return nil
+}
+
+// GetFeatureReport reads a feature report from the device.
+// The first byte of p must be set to the report ID before the call,
+// and p should be at least Caps.FeatureLen bytes long.
+func (d *Device) GetFeatureReport(p []byte) error {
+	err := d.control(func(fd uintptr) error {
+		return platform.HidrawGetFeature(fd, p)
+	})
+	if err != nil {
+		return newErr("hid.GetFeatureReport", d.Name(), err)
+	}
+	return nil
+}
+
+// SetFeatureReport sends the feature report in p to the device.
+// The first byte of p is the report ID.
+func (d *Device) SetFeatureReport(p []byte) error {
+	err := d.control(func(fd uintptr) error {
+		return platform.HidrawSetFeature(fd, p)
+	})
+	if err != nil {
+		return newErr("hid.SetFeatureReport", d.Name(), err)
+	}
+	return nil
+}
+
+// control calls f with the file descriptor of d.
+// Unlike Fd, it keeps the file in non-blocking mode.
+func (d *Device) control(f func(fd uintptr) error) error {
+	rc, err := d.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var ferr error
+	err = rc.Control(func(fd uintptr) {
+		ferr = f(fd)
+	})
+	if err != nil {
+		return err
+	}
+	return ferr
+}
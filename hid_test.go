@@ -2,8 +2,8 @@ package hid
 
 import "testing"
 
-func TestFindDevices(t *testing.T) {
-	v, err := FindDevices()
+func TestNames(t *testing.T) {
+	v, err := Names()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -12,8 +12,8 @@ func TestFindDevices(t *testing.T) {
 	}
 }
 
-func TestFindVendorDevices(t *testing.T) {
-	v, err := FindVendorDevices(0x54C, 0x5C4) // DualShock 4
+func TestVendorDevices(t *testing.T) {
+	v, err := VendorDevices(0x54C, 0x5C4) // DualShock 4
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -23,7 +23,7 @@ func TestFindVendorDevices(t *testing.T) {
 }
 
 func TestRead(t *testing.T) {
-	v, err := FindVendorDevices(0x54C, 0x5C4) // DualShock 4
+	v, err := VendorDevices(0x54C, 0x5C4) // DualShock 4
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -10,49 +10,88 @@ import (
 	"github.com/tajtiattila/hid/platform"
 )
 
-// IsAccess checks if the err is an access error, meaning
-// the device is currently unavailable because of system
-// permissions or the device was opened with exclusive access.
-func IsAccess(err error) bool {
-	if xerr, ok := err.(*Error); ok {
-		err = xerr.Err
+// platformErrKind returns the sentinel error for
+// the Windows error err, or nil if it has none.
+func platformErrKind(err error) error {
+	if pe, ok := err.(*os.PathError); ok {
+		err = pe.Err
 	}
 	if os.IsPermission(err) {
-		return true
+		return ErrAccess
 	}
-	if errc, _ := err.(syscall.Errno); errc == 32 {
-		// ERROR_SHARING_VIOLATION
-		return true
+	errc, ok := err.(syscall.Errno)
+	if !ok {
+		return nil
 	}
-	return false
+	switch errc {
+	case 5, // ERROR_ACCESS_DENIED
+		32: // ERROR_SHARING_VIOLATION
+		return ErrAccess
+	case 2, // ERROR_FILE_NOT_FOUND
+		3,    // ERROR_PATH_NOT_FOUND
+		6,    // ERROR_INVALID_HANDLE
+		22,   // ERROR_BAD_COMMAND
+		31,   // ERROR_GEN_FAILURE
+		995,  // ERROR_OPERATION_ABORTED
+		1167: // ERROR_DEVICE_NOT_CONNECTED
+		return ErrDeviceGone
+	case 1, // ERROR_INVALID_FUNCTION
+		50: // ERROR_NOT_SUPPORTED
+		return ErrNotSupported
+	case 1460: // ERROR_TIMEOUT
+		return ErrTimeout
+	}
+	return nil
 }
 
 func (d *Device) DeviceInfo() (*DeviceInfo, error) {
 	i := &DeviceInfo{Name: d.Name()}
-	err := statHandle(syscall.Handle(d.Fd()), i)
+	err := statHandle(syscall.Handle(d.Fd()), d.Name(), i)
 	if err != nil {
-		return nil, newErr("ds4.DeviceInfo", d.Name(), err)
+		return nil, newErr("hid.DeviceInfo", d.Name(), err)
 	}
 	return i, nil
 }
 
 func (d *Device) SetOutputReport(p []byte) error {
-	return platform.HidD_SetOutputReport(
+	err := platform.HidD_SetOutputReport(
 		syscall.Handle(d.Fd()),
 		&p[0],
 		uint32(len(p)))
+	if err != nil {
+		return newErr("hid.SetOutputReport", d.Name(), err)
+	}
+	return nil
 }
 
-// Disconnect device radio, assuming it's using bluetooth.
-func (d *Device) DisconnectRadio() error {
-	di, err := d.DeviceInfo()
+// GetFeatureReport reads a feature report from the device.
+// The first byte of p must be set to the report ID before the call,
+// and p should be at least Caps.FeatureLen bytes long.
+func (d *Device) GetFeatureReport(p []byte) error {
+	err := platform.HidD_GetFeature(
+		syscall.Handle(d.Fd()),
+		&p[0],
+		uint32(len(p)))
 	if err != nil {
-		return err
+		return newErr("hid.GetFeatureReport", d.Name(), err)
 	}
-	return platform.DisconnectBluetooth(di.Attr.SerialNo)
+	return nil
+}
+
+// SetFeatureReport sends the feature report in p to the device.
+// The first byte of p is the report ID.
+func (d *Device) SetFeatureReport(p []byte) error {
+	err := platform.HidD_SetFeature(
+		syscall.Handle(d.Fd()),
+		&p[0],
+		uint32(len(p)))
+	if err != nil {
+		return newErr("hid.SetFeatureReport", d.Name(), err)
+	}
+	return nil
 }
 
-func statHandle(h syscall.Handle, d *DeviceInfo) error {
+func statHandle(h syscall.Handle, name string, d *DeviceInfo) error {
 
 	var attr platform.HIDD_ATTRIBUTES
 	if err := platform.HidD_GetAttributes(h, &attr); err != nil {
@@ -64,6 +103,7 @@ func statHandle(h syscall.Handle, d *DeviceInfo) error {
 		ProductId: attr.ProductID,
 		Version:   attr.VersionNumber,
 		SerialNo:  platform.GetSerialNo(h),
+		Bluetooth: platform.IsBluetoothPath(name),
 	}
 
 	var prepd uintptr
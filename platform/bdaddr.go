@@ -0,0 +1,61 @@
+package platform
+
+import (
+	"errors"
+	"fmt"
+)
+
+// BDAddr is a bluetooth device address.
+//
+// Bytes are stored in their usual written order,
+// eg. the first byte is the most significant one.
+type BDAddr [6]byte
+
+var errBDAddr = errors.New("hid/platform: invalid bluetooth address")
+
+// ParseBDAddr parses a bluetooth address in the form "01:23:45:67:89:ab".
+// The separators may be colons, dashes or may be omitted altogether.
+func ParseBDAddr(s string) (BDAddr, error) {
+	var a BDAddr
+	i, p := 0, 0
+	for i < len(s) {
+		if p == len(a) {
+			return BDAddr{}, errBDAddr
+		}
+		if p != 0 && (s[i] == ':' || s[i] == '-') {
+			i++
+		}
+		if i+2 > len(s) {
+			return BDAddr{}, errBDAddr
+		}
+		hi, ok1 := unhex(s[i])
+		lo, ok2 := unhex(s[i+1])
+		if !ok1 || !ok2 {
+			return BDAddr{}, errBDAddr
+		}
+		a[p] = hi<<4 | lo
+		i, p = i+2, p+1
+	}
+	if p != len(a) {
+		return BDAddr{}, errBDAddr
+	}
+	return a, nil
+}
+
+// String returns a in the form "01:23:45:67:89:ab".
+func (a BDAddr) String() string {
+	return fmt.Sprintf("%02x:%02x:%02x:%02x:%02x:%02x",
+		a[0], a[1], a[2], a[3], a[4], a[5])
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
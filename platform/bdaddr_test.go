@@ -0,0 +1,38 @@
+package platform
+
+import "testing"
+
+func TestParseBDAddr(t *testing.T) {
+	want := BDAddr{0x1c, 0x66, 0x6d, 0x01, 0xab, 0xCD}
+	for _, s := range []string{
+		"1c:66:6d:01:ab:cd",
+		"1C:66:6D:01:AB:CD",
+		"1c-66-6d-01-ab-cd",
+		"1c666d01abcd",
+	} {
+		a, err := ParseBDAddr(s)
+		if err != nil {
+			t.Errorf("%q: %v", s, err)
+			continue
+		}
+		if a != want {
+			t.Errorf("%q: got %v, want %v", s, a, want)
+		}
+	}
+	if s := want.String(); s != "1c:66:6d:01:ab:cd" {
+		t.Errorf("got %q", s)
+	}
+
+	for _, s := range []string{
+		"",
+		"1c:66:6d:01:ab",
+		"1c:66:6d:01:ab:cd:ef",
+		"1c:66:6d:01:ab:cx",
+		"1c:66:6d:01:ab:c",
+		":1c:66:6d:01:ab:cd",
+	} {
+		if _, err := ParseBDAddr(s); err == nil {
+			t.Errorf("%q: want error", s)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package platform
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// DisconnectBluetooth disconnects the bluetooth device having the address a
+// using the BlueZ D-Bus API on the system bus.
+func DisconnectBluetooth(a BDAddr) error {
+	c, err := dialDBus(systemBusAddress())
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	dev := "dev_" + strings.ToUpper(strings.Replace(a.String(), ":", "_", -1))
+	for _, hci := range bluezAdapters() {
+		_, err := c.call("org.bluez", "/org/bluez/"+hci+"/"+dev, "org.bluez.Device1", "Disconnect")
+		if err == nil {
+			// success
+			return nil
+		}
+		if e, ok := err.(*dbusErr); ok {
+			switch e.Name {
+			case "org.freedesktop.DBus.Error.UnknownObject",
+				"org.freedesktop.DBus.Error.UnknownMethod":
+				// device not known to this adapter
+				continue
+			}
+		}
+		return err
+	}
+	return errors.New("hid/platform: device not found")
+}
+
+// bluezAdapters returns the names of the bluetooth adapters, eg. "hci0".
+var bluezAdapters = func() []string {
+	v, _ := filepath.Glob("/sys/class/bluetooth/hci*")
+	var r []string
+	for _, p := range v {
+		n := filepath.Base(p)
+		if !strings.Contains(n, ":") {
+			// not a connection
+			r = append(r, n)
+		}
+	}
+	if len(r) == 0 {
+		r = []string{"hci0"}
+	}
+	return r
+}
@@ -0,0 +1,145 @@
+package platform
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// mockBus is a minimal D-Bus service imitating BlueZ.
+type mockBus struct {
+	// devices maps object paths to the error name
+	// returned by Disconnect, or "" for success
+	devices map[string]string
+
+	mu    sync.Mutex
+	calls []string
+}
+
+func newMockBus(t *testing.T, devices map[string]string) *mockBus {
+	sock := filepath.Join(t.TempDir(), "system_bus_socket")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	t.Setenv("DBUS_SYSTEM_BUS_ADDRESS", "unix:path="+sock)
+
+	b := &mockBus{devices: devices}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(c)
+		}
+	}()
+	return b
+}
+
+func (b *mockBus) serve(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	if nul, err := r.ReadByte(); err != nil || nul != 0 {
+		return
+	}
+	line, err := r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "AUTH EXTERNAL ") {
+		c.Write([]byte("REJECTED EXTERNAL\r\n"))
+		return
+	}
+	c.Write([]byte("OK 0123456789abcdef0123456789abcdef\r\n"))
+	if line, err = r.ReadString('\n'); err != nil || line != "BEGIN\r\n" {
+		return
+	}
+
+	var serial uint32
+	send := func(m *dbusMsg) {
+		serial++
+		m.Serial = serial
+		c.Write(m.marshal())
+	}
+	for {
+		m, err := readDBusMsg(r)
+		if err != nil {
+			return
+		}
+		b.mu.Lock()
+		b.calls = append(b.calls, m.Path+" "+m.Interface+"."+m.Member)
+		b.mu.Unlock()
+
+		// unrelated signal that must be skipped by the client
+		send(&dbusMsg{
+			Type:      dbusSignal,
+			Path:      "/org/freedesktop/DBus",
+			Interface: "org.freedesktop.DBus",
+			Member:    "NameAcquired",
+		})
+
+		reply := &dbusMsg{Type: dbusMethodReturn, ReplySerial: m.Serial}
+		var ename string
+		switch {
+		case m.Member == "Hello":
+			reply.Signature, reply.Body = "s", dbusString(":1.42")
+		case m.Interface == "org.bluez.Device1" && m.Member == "Disconnect":
+			var ok bool
+			if ename, ok = b.devices[m.Path]; !ok {
+				ename = "org.freedesktop.DBus.Error.UnknownObject"
+			}
+		default:
+			ename = "org.freedesktop.DBus.Error.UnknownMethod"
+		}
+		if ename != "" {
+			reply.Type, reply.ErrorName = dbusError, ename
+			reply.Signature, reply.Body = "s", dbusString("mock error")
+		}
+		send(reply)
+	}
+}
+
+func dbusString(s string) []byte {
+	e := dbusEncoder{order: binary.LittleEndian}
+	e.str(s)
+	return e.b
+}
+
+func TestDisconnectBluetooth(t *testing.T) {
+	defer func(f func() []string) { bluezAdapters = f }(bluezAdapters)
+	bluezAdapters = func() []string { return []string{"hci0", "hci1"} }
+
+	b := newMockBus(t, map[string]string{
+		"/org/bluez/hci1/dev_1C_66_6D_01_02_03": "",
+		"/org/bluez/hci0/dev_1C_66_6D_0A_0B_0C": "org.bluez.Error.NotConnected",
+	})
+
+	a, _ := ParseBDAddr("1c:66:6d:01:02:03")
+	if err := DisconnectBluetooth(a); err != nil {
+		t.Fatal(err)
+	}
+	b.mu.Lock()
+	want := []string{
+		"/org/freedesktop/DBus org.freedesktop.DBus.Hello",
+		"/org/bluez/hci0/dev_1C_66_6D_01_02_03 org.bluez.Device1.Disconnect",
+		"/org/bluez/hci1/dev_1C_66_6D_01_02_03 org.bluez.Device1.Disconnect",
+	}
+	if strings.Join(b.calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got calls %q, want %q", b.calls, want)
+	}
+	b.mu.Unlock()
+
+	a, _ = ParseBDAddr("1c:66:6d:0a:0b:0c")
+	err := DisconnectBluetooth(a)
+	if e, ok := err.(*dbusErr); !ok || e.Name != "org.bluez.Error.NotConnected" {
+		t.Errorf("got %v, want NotConnected error", err)
+	}
+
+	a, _ = ParseBDAddr("00:00:00:00:00:01")
+	if err := DisconnectBluetooth(a); err == nil {
+		t.Error("unknown device disconnected")
+	}
+}
@@ -2,16 +2,26 @@ package platform
 
 import (
 	"errors"
+	"strings"
 	"syscall"
 	"unsafe"
 )
 
-func DisconnectBluetooth(sno string) error {
-	if sno == "" {
-		return errors.New("hid/platform: empty serial number")
-	}
+// IsBluetoothPath reports whether the device path
+// belongs to a HID device connected using bluetooth.
+func IsBluetoothPath(path string) bool {
+	// HID over bluetooth service class UUID
+	const hidp = "{00001124-0000-1000-8000-00805f9b34fb}"
+	return strings.Contains(strings.ToLower(path), hidp)
+}
 
-	addr := serialNoToBtMacAddr(sno)
+// DisconnectBluetooth disconnects the bluetooth device having the address a.
+func DisconnectBluetooth(a BDAddr) error {
+	// BLUETOOTH_ADDRESS is a little endian 64 bit value
+	addr := make([]byte, 8)
+	for i := range a {
+		addr[5-i] = a[i]
+	}
 
 	var btfrp BLUETOOTH_FIND_RADIO_PARAMS
 	btfrp.Size = uint32(unsafe.Sizeof(btfrp))
@@ -43,43 +53,6 @@ func DisconnectBluetooth(sno string) error {
 	return errors.New("hid/platform: device not found")
 }
 
-func serialNoToBtMacAddr(s string) []byte {
-	buf := make([]byte, 8)
-	i, p := 0, 6
-	var val byte
-	for _, r := range s {
-		if i == 2 {
-			i = 0
-			// skip separator, if any
-			if r == ':' {
-				continue
-			}
-		}
-
-		var digit byte
-		switch {
-		case '0' <= r && r <= '9':
-			digit = byte(r - '0')
-		case 'a' <= r && r <= 'f':
-			digit = byte(r-'a') + 10
-		case 'A' <= r && r <= 'F':
-			digit = byte(r-'A') + 10
-		}
-		val = val<<4 | digit
-		i++
-
-		if i == 2 {
-			p--
-			buf[p] = val
-			if p == 0 {
-				break
-			}
-			val = 0
-		}
-	}
-	return buf
-}
-
 type BLUETOOTH_FIND_RADIO_PARAMS struct {
 	Size uint32
 }
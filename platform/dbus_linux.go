@@ -0,0 +1,403 @@
+package platform
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Minimal D-Bus client, sufficient to call methods
+// without arguments on the system bus.
+
+// message types
+const (
+	dbusMethodCall   = 1
+	dbusMethodReturn = 2
+	dbusError        = 3
+	dbusSignal       = 4
+)
+
+// header field codes
+const (
+	dbusFieldPath        = 1
+	dbusFieldInterface   = 2
+	dbusFieldMember      = 3
+	dbusFieldErrorName   = 4
+	dbusFieldReplySerial = 5
+	dbusFieldDestination = 6
+	dbusFieldSender      = 7
+	dbusFieldSignature   = 8
+)
+
+type dbusMsg struct {
+	Type   byte
+	Serial uint32
+
+	Path, Interface, Member string
+	ErrorName               string
+	ReplySerial             uint32
+	Destination, Sender     string
+
+	// Signature and Body are the raw message body.
+	Signature string
+	Body      []byte
+
+	order binary.ByteOrder
+}
+
+// dbusErr is an error reply received from the bus.
+type dbusErr struct {
+	Name string
+	Msg  string
+}
+
+func (e *dbusErr) Error() string {
+	if e.Msg == "" {
+		return "dbus: " + e.Name
+	}
+	return "dbus: " + e.Name + ": " + e.Msg
+}
+
+type dbusConn struct {
+	c      net.Conn
+	r      *bufio.Reader
+	serial uint32
+}
+
+func systemBusAddress() string {
+	if a := os.Getenv("DBUS_SYSTEM_BUS_ADDRESS"); a != "" {
+		return a
+	}
+	return "unix:path=/var/run/dbus/system_bus_socket"
+}
+
+// dialDBus connects to the first reachable
+// unix socket of the bus address addr.
+func dialDBus(addr string) (*dbusConn, error) {
+	err := errors.New("dbus: no usable address in " + strconv.Quote(addr))
+	for _, a := range strings.Split(addr, ";") {
+		if !strings.HasPrefix(a, "unix:") {
+			continue
+		}
+		var path string
+		for _, kv := range strings.Split(a[len("unix:"):], ",") {
+			switch {
+			case strings.HasPrefix(kv, "path="):
+				path = kv[len("path="):]
+			case strings.HasPrefix(kv, "abstract="):
+				path = "@" + kv[len("abstract="):]
+			}
+		}
+		if path == "" {
+			continue
+		}
+		var c *dbusConn
+		if c, err = dbusOpen(path); err == nil {
+			return c, nil
+		}
+	}
+	return nil, err
+}
+
+func dbusOpen(path string) (*dbusConn, error) {
+	nc, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	c := &dbusConn{c: nc, r: bufio.NewReader(nc)}
+	if err := c.auth(); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	_, err = c.call("org.freedesktop.DBus", "/org/freedesktop/DBus", "org.freedesktop.DBus", "Hello")
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *dbusConn) Close() error {
+	return c.c.Close()
+}
+
+func (c *dbusConn) auth() error {
+	uid := hex.EncodeToString([]byte(strconv.Itoa(os.Getuid())))
+	if _, err := io.WriteString(c.c, "\x00AUTH EXTERNAL "+uid+"\r\n"); err != nil {
+		return err
+	}
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "OK ") {
+		return errors.New("dbus: authentication failed: " + strings.TrimSpace(line))
+	}
+	_, err = io.WriteString(c.c, "BEGIN\r\n")
+	return err
+}
+
+// call calls the method member without arguments,
+// and waits for the reply.
+func (c *dbusConn) call(dest, path, iface, member string) (*dbusMsg, error) {
+	c.serial++
+	m := &dbusMsg{
+		Type:        dbusMethodCall,
+		Serial:      c.serial,
+		Path:        path,
+		Interface:   iface,
+		Member:      member,
+		Destination: dest,
+	}
+	if _, err := c.c.Write(m.marshal()); err != nil {
+		return nil, err
+	}
+	for {
+		r, err := readDBusMsg(c.r)
+		if err != nil {
+			return nil, err
+		}
+		if r.ReplySerial != m.Serial {
+			// signal or unrelated message
+			continue
+		}
+		switch r.Type {
+		case dbusMethodReturn:
+			return r, nil
+		case dbusError:
+			e := &dbusErr{Name: r.ErrorName}
+			if strings.HasPrefix(r.Signature, "s") {
+				d := dbusDecoder{b: r.Body, order: r.order}
+				e.Msg = d.str()
+			}
+			return nil, e
+		}
+	}
+}
+
+// marshal encodes m using little endian byte order.
+func (m *dbusMsg) marshal() []byte {
+	e := dbusEncoder{order: binary.LittleEndian}
+	e.b = append(e.b, 'l', m.Type, 0, 1)
+	e.u32(uint32(len(m.Body)))
+	e.u32(m.Serial)
+
+	// header fields a(yv)
+	e.u32(0)
+	e.align(8)
+	start := len(e.b)
+	field := func(code byte, sig string, v interface{}) {
+		e.align(8)
+		e.b = append(e.b, code)
+		e.sig(sig)
+		switch x := v.(type) {
+		case string:
+			if sig == "g" {
+				e.sig(x)
+			} else {
+				e.str(x)
+			}
+		case uint32:
+			e.u32(x)
+		}
+	}
+	if m.Path != "" {
+		field(dbusFieldPath, "o", m.Path)
+	}
+	if m.Interface != "" {
+		field(dbusFieldInterface, "s", m.Interface)
+	}
+	if m.Member != "" {
+		field(dbusFieldMember, "s", m.Member)
+	}
+	if m.ErrorName != "" {
+		field(dbusFieldErrorName, "s", m.ErrorName)
+	}
+	if m.ReplySerial != 0 {
+		field(dbusFieldReplySerial, "u", m.ReplySerial)
+	}
+	if m.Destination != "" {
+		field(dbusFieldDestination, "s", m.Destination)
+	}
+	if m.Sender != "" {
+		field(dbusFieldSender, "s", m.Sender)
+	}
+	if m.Signature != "" {
+		field(dbusFieldSignature, "g", m.Signature)
+	}
+	e.order.PutUint32(e.b[12:], uint32(len(e.b)-start))
+
+	e.align(8)
+	return append(e.b, m.Body...)
+}
+
+func readDBusMsg(r io.Reader) (*dbusMsg, error) {
+	fixed := make([]byte, 16)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, err
+	}
+	var order binary.ByteOrder
+	switch fixed[0] {
+	case 'l':
+		order = binary.LittleEndian
+	case 'B':
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("dbus: invalid byte order %q", fixed[0])
+	}
+	bodyLen := order.Uint32(fixed[4:])
+	fieldsLen := order.Uint32(fixed[12:])
+	if bodyLen > 1<<27 || fieldsLen > 1<<26 {
+		return nil, errors.New("dbus: message too long")
+	}
+
+	hdrLen := (16 + int(fieldsLen) + 7) &^ 7
+	buf := make([]byte, hdrLen+int(bodyLen))
+	copy(buf, fixed)
+	if _, err := io.ReadFull(r, buf[16:]); err != nil {
+		return nil, err
+	}
+
+	m := &dbusMsg{
+		Type:   fixed[1],
+		Serial: order.Uint32(fixed[8:]),
+		Body:   buf[hdrLen:],
+		order:  order,
+	}
+	d := dbusDecoder{b: buf[:16+fieldsLen], pos: 16, order: order}
+	for d.err == nil && d.pos < len(d.b) {
+		d.align(8)
+		code := d.byte()
+		sig := d.sig()
+		var s string
+		var u uint32
+		switch sig {
+		case "s", "o":
+			s = d.str()
+		case "g":
+			s = d.sig()
+		case "u":
+			u = d.u32()
+		default:
+			return nil, fmt.Errorf("dbus: unexpected header field type %q", sig)
+		}
+		switch code {
+		case dbusFieldPath:
+			m.Path = s
+		case dbusFieldInterface:
+			m.Interface = s
+		case dbusFieldMember:
+			m.Member = s
+		case dbusFieldErrorName:
+			m.ErrorName = s
+		case dbusFieldReplySerial:
+			m.ReplySerial = u
+		case dbusFieldDestination:
+			m.Destination = s
+		case dbusFieldSender:
+			m.Sender = s
+		case dbusFieldSignature:
+			m.Signature = s
+		}
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return m, nil
+}
+
+type dbusEncoder struct {
+	b     []byte
+	order binary.ByteOrder
+}
+
+func (e *dbusEncoder) align(n int) {
+	for len(e.b)%n != 0 {
+		e.b = append(e.b, 0)
+	}
+}
+
+func (e *dbusEncoder) u32(v uint32) {
+	e.align(4)
+	var p [4]byte
+	e.order.PutUint32(p[:], v)
+	e.b = append(e.b, p[:]...)
+}
+
+func (e *dbusEncoder) str(s string) {
+	e.u32(uint32(len(s)))
+	e.b = append(e.b, s...)
+	e.b = append(e.b, 0)
+}
+
+func (e *dbusEncoder) sig(s string) {
+	e.b = append(e.b, byte(len(s)))
+	e.b = append(e.b, s...)
+	e.b = append(e.b, 0)
+}
+
+var errDBusShort = errors.New("dbus: short message")
+
+type dbusDecoder struct {
+	b     []byte
+	pos   int
+	order binary.ByteOrder
+	err   error
+}
+
+func (d *dbusDecoder) align(n int) {
+	d.pos = (d.pos + n - 1) / n * n
+}
+
+func (d *dbusDecoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || d.pos+n > len(d.b) {
+		d.err = errDBusShort
+		return nil
+	}
+	p := d.b[d.pos : d.pos+n]
+	d.pos += n
+	return p
+}
+
+func (d *dbusDecoder) byte() byte {
+	if p := d.next(1); p != nil {
+		return p[0]
+	}
+	return 0
+}
+
+func (d *dbusDecoder) u32() uint32 {
+	d.align(4)
+	if p := d.next(4); p != nil {
+		return d.order.Uint32(p)
+	}
+	return 0
+}
+
+func (d *dbusDecoder) str() string {
+	n := d.u32()
+	p := d.next(int(n) + 1)
+	if p == nil {
+		return ""
+	}
+	return string(p[:n])
+}
+
+func (d *dbusDecoder) sig() string {
+	n := d.byte()
+	p := d.next(int(n) + 1)
+	if p == nil {
+		return ""
+	}
+	return string(p[:n])
+}
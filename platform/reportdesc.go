@@ -0,0 +1,120 @@
+package platform
+
+// ReportInfo holds the top level collection usage and
+// longest report byte lengths of a HID report descriptor.
+//
+// Lengths include the report ID byte, even if the descriptor
+// does not use report IDs, similar to the values in HIDP_CAPS.
+// Lengths are zero for report types not present in the descriptor.
+type ReportInfo struct {
+	Usage     uint16
+	UsagePage uint16
+
+	InputLen   int
+	OutputLen  int
+	FeatureLen int
+}
+
+// ParseReportDescriptor parses the HID report descriptor desc.
+func ParseReportDescriptor(desc []byte) ReportInfo {
+	type global struct {
+		page, size, count, id uint32
+	}
+	var g global
+	var stack []global
+
+	var ri ReportInfo
+	var usage uint32
+	var depth int
+
+	// bit lengths by report kind and ID
+	bits := make(map[[2]uint32]uint32)
+
+	for len(desc) > 0 {
+		prefix := desc[0]
+		if prefix == 0xfe {
+			// long item
+			if len(desc) < 3 {
+				break
+			}
+			n := 3 + int(desc[1])
+			if n > len(desc) {
+				break
+			}
+			desc = desc[n:]
+			continue
+		}
+
+		n := int(prefix & 3)
+		if n == 3 {
+			n = 4
+		}
+		if 1+n > len(desc) {
+			break
+		}
+		var v uint32
+		for i := 0; i < n; i++ {
+			v |= uint32(desc[1+i]) << (8 * uint(i))
+		}
+		desc = desc[1+n:]
+
+		typ, tag := (prefix>>2)&3, prefix>>4
+		switch typ {
+		case 0: // main
+			switch tag {
+			case 0x8, 0x9, 0xb: // input, output, feature
+				bits[[2]uint32{uint32(tag), g.id}] += g.size * g.count
+			case 0xa: // collection
+				if depth == 0 && ri.UsagePage == 0 {
+					ri.UsagePage, ri.Usage = uint16(g.page), uint16(usage)
+				}
+				depth++
+			case 0xc: // end collection
+				depth--
+			}
+			usage = 0
+		case 1: // global
+			switch tag {
+			case 0x0:
+				g.page = v
+			case 0x7:
+				g.size = v
+			case 0x8:
+				g.id = v
+			case 0x9:
+				g.count = v
+			case 0xa: // push
+				stack = append(stack, g)
+			case 0xb: // pop
+				if len(stack) != 0 {
+					g = stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+				}
+			}
+		case 2: // local
+			if tag == 0x0 && usage == 0 {
+				usage = v
+			}
+		}
+	}
+
+	for k, b := range bits {
+		n := 1 + int(b+7)/8
+		switch k[0] {
+		case 0x8:
+			ri.InputLen = imax(ri.InputLen, n)
+		case 0x9:
+			ri.OutputLen = imax(ri.OutputLen, n)
+		case 0xb:
+			ri.FeatureLen = imax(ri.FeatureLen, n)
+		}
+	}
+	return ri
+}
+
+func imax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,182 @@
+package platform
+
+import "testing"
+
+// ds4USBDesc is the report descriptor of a DualShock 4 (CUH-ZCT1, 054c:05c4)
+// connected using USB, as listed in dualshock4_usb_rdesc of the Linux
+// hid-sony driver.
+var ds4USBDesc = []byte{
+	0x05, 0x01, // Usage Page (Desktop)
+	0x09, 0x05, // Usage (Gamepad)
+	0xa1, 0x01, // Collection (Application)
+	0x85, 0x01, //   Report ID (1)
+	0x09, 0x30, 0x09, 0x31, 0x09, 0x32, 0x09, 0x35, // Usage (X, Y, Z, Rz)
+	0x15, 0x00, 0x26, 0xff, 0x00, // Logical Minimum (0), Maximum (255)
+	0x75, 0x08, 0x95, 0x04, 0x81, 0x02, // 4×8 bits Input (Variable)
+	0x09, 0x39, 0x15, 0x00, 0x25, 0x07, // Usage (Hat Switch), 0-7
+	0x35, 0x00, 0x46, 0x3b, 0x01, 0x65, 0x14, // 0-315 degrees
+	0x75, 0x04, 0x95, 0x01, 0x81, 0x42, // 1×4 bits Input (Variable, Null State)
+	0x65, 0x00, // Unit
+	0x05, 0x09, 0x19, 0x01, 0x29, 0x0e, // Buttons 1-14
+	0x15, 0x00, 0x25, 0x01, 0x75, 0x01, 0x95, 0x0e, 0x81, 0x02,
+	0x06, 0x00, 0xff, 0x09, 0x20, // Vendor counter
+	0x75, 0x06, 0x95, 0x01, 0x15, 0x00, 0x25, 0x7f, 0x81, 0x02,
+	0x05, 0x01, 0x09, 0x33, 0x09, 0x34, // Usage (Rx, Ry)
+	0x15, 0x00, 0x26, 0xff, 0x00, 0x75, 0x08, 0x95, 0x02, 0x81, 0x02,
+	0x06, 0x00, 0xff, 0x09, 0x21, 0x95, 0x36, 0x81, 0x02, // 54 vendor bytes
+	0x85, 0x05, 0x09, 0x22, 0x95, 0x1f, 0x91, 0x02, // Output 5
+	0x85, 0x04, 0x09, 0x23, 0x95, 0x24, 0xb1, 0x02, // Feature 4
+	0x85, 0x02, 0x09, 0x24, 0x95, 0x24, 0xb1, 0x02,
+	0x85, 0x08, 0x09, 0x25, 0x95, 0x03, 0xb1, 0x02,
+	0x85, 0x10, 0x09, 0x26, 0x95, 0x04, 0xb1, 0x02,
+	0x85, 0x11, 0x09, 0x27, 0x95, 0x02, 0xb1, 0x02,
+	0x85, 0x12, 0x06, 0x02, 0xff, 0x09, 0x21, 0x95, 0x0f, 0xb1, 0x02,
+	0x85, 0x13, 0x09, 0x22, 0x95, 0x16, 0xb1, 0x02,
+	0x85, 0x14, 0x06, 0x05, 0xff, 0x09, 0x20, 0x95, 0x10, 0xb1, 0x02,
+	0x85, 0x15, 0x09, 0x21, 0x95, 0x2c, 0xb1, 0x02,
+	0x06, 0x80, 0xff,
+	0x85, 0x80, 0x09, 0x20, 0x95, 0x06, 0xb1, 0x02,
+	0x85, 0x81, 0x09, 0x21, 0x95, 0x06, 0xb1, 0x02,
+	0x85, 0x82, 0x09, 0x22, 0x95, 0x05, 0xb1, 0x02,
+	0x85, 0x83, 0x09, 0x23, 0x95, 0x01, 0xb1, 0x02,
+	0x85, 0x84, 0x09, 0x24, 0x95, 0x04, 0xb1, 0x02,
+	0x85, 0x85, 0x09, 0x25, 0x95, 0x06, 0xb1, 0x02,
+	0x85, 0x86, 0x09, 0x26, 0x95, 0x06, 0xb1, 0x02,
+	0x85, 0x87, 0x09, 0x27, 0x95, 0x23, 0xb1, 0x02,
+	0x85, 0x88, 0x09, 0x28, 0x95, 0x22, 0xb1, 0x02,
+	0x85, 0x89, 0x09, 0x29, 0x95, 0x02, 0xb1, 0x02,
+	0x85, 0x90, 0x09, 0x30, 0x95, 0x05, 0xb1, 0x02,
+	0x85, 0x91, 0x09, 0x31, 0x95, 0x03, 0xb1, 0x02,
+	0x85, 0x92, 0x09, 0x32, 0x95, 0x03, 0xb1, 0x02,
+	0x85, 0x93, 0x09, 0x33, 0x95, 0x0c, 0xb1, 0x02,
+	0x85, 0xa0, 0x09, 0x40, 0x95, 0x06, 0xb1, 0x02,
+	0x85, 0xa1, 0x09, 0x41, 0x95, 0x01, 0xb1, 0x02,
+	0x85, 0xa2, 0x09, 0x42, 0x95, 0x01, 0xb1, 0x02,
+	0x85, 0xa3, 0x09, 0x43, 0x95, 0x30, 0xb1, 0x02, // Feature A3, firmware info
+	0x85, 0xa4, 0x09, 0x44, 0x95, 0x0d, 0xb1, 0x02,
+	0x85, 0xa5, 0x09, 0x45, 0x95, 0x15, 0xb1, 0x02,
+	0x85, 0xa6, 0x09, 0x46, 0x95, 0x15, 0xb1, 0x02,
+	0x85, 0xf0, 0x09, 0x47, 0x95, 0x3f, 0xb1, 0x02,
+	0x85, 0xf1, 0x09, 0x48, 0x95, 0x3f, 0xb1, 0x02,
+	0x85, 0xf2, 0x09, 0x49, 0x95, 0x0f, 0xb1, 0x02,
+	0x85, 0xa7, 0x09, 0x4a, 0x95, 0x01, 0xb1, 0x02,
+	0xc0, // End Collection
+}
+
+// ds4BTDesc is the report descriptor of the same controller connected
+// using Bluetooth, as listed in dualshock4_bt_rdesc of the Linux
+// hid-sony driver.
+var ds4BTDesc = []byte{
+	0x05, 0x01, 0x09, 0x05, 0xa1, 0x01, // Desktop Gamepad, Collection (Application)
+	0x85, 0x01, // Report ID (1), the reduced report
+	0x09, 0x30, 0x09, 0x31, 0x09, 0x32, 0x09, 0x35,
+	0x15, 0x00, 0x26, 0xff, 0x00, 0x75, 0x08, 0x95, 0x04, 0x81, 0x02,
+	0x09, 0x39, 0x15, 0x00, 0x25, 0x07, 0x75, 0x04, 0x95, 0x01, 0x81, 0x42,
+	0x05, 0x09, 0x19, 0x01, 0x29, 0x0e,
+	0x15, 0x00, 0x25, 0x01, 0x75, 0x01, 0x95, 0x0e, 0x81, 0x02,
+	0x75, 0x06, 0x95, 0x01, 0x81, 0x01,
+	0x05, 0x01, 0x09, 0x33, 0x09, 0x34,
+	0x15, 0x00, 0x26, 0xff, 0x00, 0x75, 0x08, 0x95, 0x02, 0x81, 0x02,
+	0x06, 0x04, 0xff,
+	0x85, 0x02, 0x09, 0x24, 0x95, 0x24, 0xb1, 0x02,
+	0x85, 0xa3, 0x09, 0x25, 0x95, 0x30, 0xb1, 0x02,
+	0x85, 0x05, 0x09, 0x26, 0x95, 0x28, 0xb1, 0x02,
+	0x85, 0x06, 0x09, 0x27, 0x95, 0x34, 0xb1, 0x02,
+	0x85, 0x07, 0x09, 0x28, 0x95, 0x30, 0xb1, 0x02,
+	0x85, 0x08, 0x09, 0x29, 0x95, 0x2f, 0xb1, 0x02,
+	0x85, 0x09, 0x09, 0x2a, 0x95, 0x13, 0xb1, 0x02,
+	0x06, 0x03, 0xff,
+	0x85, 0x03, 0x09, 0x21, 0x95, 0x26, 0xb1, 0x02,
+	0x85, 0x04, 0x09, 0x22, 0x95, 0x2e, 0xb1, 0x02,
+	0x85, 0xf0, 0x09, 0x47, 0x95, 0x3f, 0xb1, 0x02,
+	0x85, 0xf1, 0x09, 0x48, 0x95, 0x3f, 0xb1, 0x02,
+	0x85, 0xf2, 0x09, 0x49, 0x95, 0x0f, 0xb1, 0x02,
+	0x06, 0x00, 0xff,
+	0x85, 0x11, 0x09, 0x20, 0x15, 0x00, 0x26, 0xff, 0x00, 0x75, 0x08, // full reports
+	0x95, 0x4d, 0x81, 0x02, 0x09, 0x21, 0x91, 0x02,
+	0x85, 0x12, 0x09, 0x22, 0x95, 0x8d, 0x81, 0x02, 0x09, 0x23, 0x91, 0x02,
+	0x85, 0x13, 0x09, 0x24, 0x95, 0xcd, 0x81, 0x02, 0x09, 0x25, 0x91, 0x02,
+	0x85, 0x14, 0x09, 0x26, 0x96, 0x0d, 0x01, 0x81, 0x02, 0x09, 0x27, 0x91, 0x02,
+	0x85, 0x15, 0x09, 0x28, 0x96, 0x4d, 0x01, 0x81, 0x02, 0x09, 0x29, 0x91, 0x02,
+	0x85, 0x16, 0x09, 0x2a, 0x96, 0x8d, 0x01, 0x81, 0x02, 0x09, 0x2b, 0x91, 0x02,
+	0x85, 0x17, 0x09, 0x2c, 0x96, 0xcd, 0x01, 0x81, 0x02, 0x09, 0x2d, 0x91, 0x02,
+	0x85, 0x18, 0x09, 0x2e, 0x96, 0x0d, 0x02, 0x81, 0x02, 0x09, 0x2f, 0x91, 0x02,
+	0x85, 0x19, 0x09, 0x30, 0x96, 0x22, 0x02, 0x81, 0x02, 0x09, 0x31, 0x91, 0x02,
+	0x06, 0x80, 0xff,
+	0x85, 0x82, 0x09, 0x22, 0x95, 0x3f, 0xb1, 0x02,
+	0x85, 0x83, 0x09, 0x23, 0x95, 0x01, 0xb1, 0x02,
+	0x85, 0x84, 0x09, 0x24, 0x95, 0x04, 0xb1, 0x02,
+	0x85, 0x90, 0x09, 0x30, 0x95, 0x05, 0xb1, 0x02,
+	0x85, 0x91, 0x09, 0x31, 0x95, 0x03, 0xb1, 0x02,
+	0x85, 0x92, 0x09, 0x32, 0x95, 0x03, 0xb1, 0x02,
+	0x85, 0x93, 0x09, 0x33, 0x95, 0x0c, 0xb1, 0x02,
+	0x85, 0xa0, 0x09, 0x40, 0x95, 0x06, 0xb1, 0x02,
+	0x85, 0xa1, 0x09, 0x41, 0x95, 0x01, 0xb1, 0x02,
+	0x85, 0xa2, 0x09, 0x42, 0x95, 0x01, 0xb1, 0x02,
+	0x85, 0xa4, 0x09, 0x44, 0x95, 0x0d, 0xb1, 0x02,
+	0x85, 0xa5, 0x09, 0x45, 0x95, 0x15, 0xb1, 0x02,
+	0x85, 0xa6, 0x09, 0x46, 0x95, 0x15, 0xb1, 0x02,
+	0x85, 0xa7, 0x09, 0x4a, 0x95, 0x01, 0xb1, 0x02,
+	0xc0,
+}
+
+func TestParseReportDescriptor(t *testing.T) {
+	tests := []struct {
+		name string
+		desc []byte
+		want ReportInfo
+	}{
+		{
+			"ds4 usb",
+			ds4USBDesc,
+			ReportInfo{Usage: 5, UsagePage: 1, InputLen: 64, OutputLen: 32, FeatureLen: 64},
+		},
+		{
+			"ds4 bluetooth",
+			ds4BTDesc,
+			ReportInfo{Usage: 5, UsagePage: 1, InputLen: 547, OutputLen: 547, FeatureLen: 64},
+		},
+		{
+			"no report ids",
+			[]byte{
+				0x05, 0x01, 0x09, 0x02, 0xa1, 0x01, // Desktop Mouse
+				0x09, 0x01, 0xa1, 0x00, // Pointer, Collection (Physical)
+				0x75, 0x01, 0x95, 0x03, 0x81, 0x02, // 3 buttons
+				0x75, 0x05, 0x95, 0x01, 0x81, 0x01, // padding
+				0x75, 0x08, 0x95, 0x02, 0x81, 0x06, // X, Y
+				0xc0, 0xc0,
+			},
+			ReportInfo{Usage: 2, UsagePage: 1, InputLen: 4},
+		},
+		{
+			"push pop and long item",
+			[]byte{
+				0x06, 0x00, 0xff, 0x09, 0x01, 0xa1, 0x01,
+				0x75, 0x08, 0x95, 0x10,
+				0xa4,       // Push
+				0x95, 0x02, //   Report Count (2)
+				0xfe, 0x02, 0x00, 0xaa, 0xbb, // long item, ignored
+				0x91, 0x02, //   Output, 2 bytes
+				0xb4,       // Pop
+				0x81, 0x02, // Input, 16 bytes
+				0xc0,
+			},
+			ReportInfo{Usage: 1, UsagePage: 0xff00, InputLen: 17, OutputLen: 3},
+		},
+		{
+			"truncated",
+			[]byte{0x05, 0x01, 0x09, 0x05, 0xa1, 0x01, 0x75, 0x08, 0x95, 0x04, 0x81, 0x02, 0x26, 0xff},
+			ReportInfo{Usage: 5, UsagePage: 1, InputLen: 5},
+		},
+		{
+			"empty",
+			nil,
+			ReportInfo{},
+		},
+	}
+	for _, tt := range tests {
+		if got := ParseReportDescriptor(tt.desc); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package platform
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// FindDevices lists the hidraw device nodes.
+func FindDevices() ([]string, error) {
+	v, err := filepath.Glob("/dev/hidraw*")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(v)
+	return v, nil
+}
+
+// Bus types reported in HidrawInfo.
+const (
+	BUS_USB       = 0x03
+	BUS_BLUETOOTH = 0x05
+)
+
+// HidrawInfo holds the sysfs attributes of a hidraw device.
+type HidrawInfo struct {
+	Bus       uint16
+	VendorID  uint16
+	ProductID uint16
+
+	Name string
+
+	// Uniq is the serial number of USB devices,
+	// or the address of bluetooth devices.
+	Uniq string
+
+	// ReportDesc is the raw HID report descriptor.
+	ReportDesc []byte
+}
+
+// HidrawStat reads the sysfs attributes of the hidraw device name.
+func HidrawStat(name string) (*HidrawInfo, error) {
+	dir := filepath.Join("/sys/class/hidraw", filepath.Base(name), "device")
+
+	uevent, err := os.ReadFile(filepath.Join(dir, "uevent"))
+	if err != nil {
+		return nil, err
+	}
+
+	i := new(HidrawInfo)
+	s := bufio.NewScanner(bytes.NewReader(uevent))
+	for s.Scan() {
+		kv := strings.SplitN(s.Text(), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "HID_ID":
+			// bus:vendor:product in hex, eg. 0005:0000054C:000005C4
+			v := strings.Split(kv[1], ":")
+			if len(v) == 3 {
+				i.Bus = hex16(v[0])
+				i.VendorID = hex16(v[1])
+				i.ProductID = hex16(v[2])
+			}
+		case "HID_NAME":
+			i.Name = kv[1]
+		case "HID_UNIQ":
+			i.Uniq = kv[1]
+		}
+	}
+
+	i.ReportDesc, err = os.ReadFile(filepath.Join(dir, "report_descriptor"))
+	if err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func hex16(s string) uint16 {
+	v, _ := strconv.ParseUint(s, 16, 32)
+	return uint16(v)
+}
+
+func IsAccess(err error) bool {
+	if os.IsPermission(err) {
+		return true
+	}
+	if pe, ok := err.(*os.PathError); ok {
+		err = pe.Err
+	}
+	return err == syscall.EBUSY
+}
+
+// HidrawGetFeature reads a feature report into p from the hidraw device fd.
+// The first byte of p must be set to the report ID.
+func HidrawGetFeature(fd uintptr, p []byte) error {
+	const HIDIOCGFEATURE = 0x07
+	return hidrawIoctl(fd, HIDIOCGFEATURE, p)
+}
+
+// HidrawSetFeature sends the feature report in p to the hidraw device fd.
+func HidrawSetFeature(fd uintptr, p []byte) error {
+	const HIDIOCSFEATURE = 0x06
+	return hidrawIoctl(fd, HIDIOCSFEATURE, p)
+}
+
+func hidrawIoctl(fd uintptr, nr uintptr, p []byte) error {
+	// _IOC(_IOC_WRITE|_IOC_READ, 'H', nr, len(p))
+	req := 3<<30 | uintptr(len(p))<<16 | 'H'<<8 | nr
+	_, _, e := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(unsafe.Pointer(&p[0])))
+	if e != 0 {
+		return e
+	}
+	return nil
+}
+
+// GetSerialNo returns the serial number of the hidraw device fd.
+// The uniq value from HidrawInfo is used if it looks like
+// a bluetooth address, otherwise the pairing feature report is read.
+func GetSerialNo(fd uintptr, uniq string) string {
+	if len(uniq) >= 17 {
+		return uniq
+	}
+	return serialFromFeature(fd, 0x12)
+}
+
+func serialFromFeature(fd uintptr, feat byte) string {
+	buf := make([]byte, 16)
+	buf[0] = feat
+	if err := HidrawGetFeature(fd, buf); err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%02x:%02x:%02x:%02x:%02x:%02x",
+		buf[6], buf[5], buf[4], buf[3], buf[2], buf[1])
+}
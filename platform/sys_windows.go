@@ -285,4 +285,5 @@ const (
 //sys HidP_GetCaps(preparsedData uintptr, caps *HIDP_CAPS) (errCode uint32) = hid.HidP_GetCaps
 //sys HidD_GetSerialNumberString(h syscall.Handle, buf *uint16, buflen uint32) (err error) = hid.HidD_GetSerialNumberString
 //sys HidD_GetFeature(h syscall.Handle, buf *byte, buflen uint32) (err error) = hid.HidD_GetFeature
+//sys HidD_SetFeature(h syscall.Handle, buf *byte, buflen uint32) (err error) = hid.HidD_SetFeature
 //sys HidD_SetOutputReport(h syscall.Handle, buf *byte, buflen uint32) (err error) = hid.HidD_SetOutputReport
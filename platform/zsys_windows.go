@@ -25,6 +25,7 @@ var (
 	procHidP_GetCaps                      = modhid.NewProc("HidP_GetCaps")
 	procHidD_GetSerialNumberString        = modhid.NewProc("HidD_GetSerialNumberString")
 	procHidD_GetFeature                   = modhid.NewProc("HidD_GetFeature")
+	procHidD_SetFeature                   = modhid.NewProc("HidD_SetFeature")
 	procHidD_SetOutputReport              = modhid.NewProc("HidD_SetOutputReport")
 )
 
@@ -184,6 +185,18 @@ func HidD_GetFeature(h syscall.Handle, buf *byte, buflen uint32) (err error) {
 	return
 }
 
+func HidD_SetFeature(h syscall.Handle, buf *byte, buflen uint32) (err error) {
+	r1, _, e1 := syscall.Syscall(procHidD_SetFeature.Addr(), 3, uintptr(h), uintptr(unsafe.Pointer(buf)), uintptr(buflen))
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
 func HidD_SetOutputReport(h syscall.Handle, buf *byte, buflen uint32) (err error) {
 	r1, _, e1 := syscall.Syscall(procHidD_SetOutputReport.Addr(), 3, uintptr(h), uintptr(unsafe.Pointer(buf)), uintptr(buflen))
 	if r1 == 0 {